@@ -0,0 +1,198 @@
+// Package client is a typed Go client of the HTTP API of the simulator, described by
+// the OpenAPI document served at /api/v2/openapi.json
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+)
+
+// Client calls the API of a simulator
+type Client struct {
+	BaseURL    string       // e.g. http://localhost:8000
+	HTTPClient *http.Client // http.DefaultClient if nil
+
+	Token    string // API token, sent as bearer
+	Username string // basic auth if Token is empty
+	Password string
+}
+
+// Error is the error body of the /api/v2 routes and of the authentication
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"` // code of the codes package
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v %v (code %v)", e.StatusCode, e.Message, e.Code)
+}
+
+// New returns a client of the simulator at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (c *Client) httpClient() *http.Client {
+
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+
+	return c.HTTPClient
+}
+
+// do sends the request with body encoded as JSON and decodes the response in out,
+// a status other than 2xx returns an *Error
+func (c *Client) do(ctx context.Context, method string, path string, contentType string, body interface{}, out interface{}) error {
+
+	var reader io.Reader
+
+	if body != nil {
+
+		encoded, ok := body.(json.RawMessage)
+		if !ok {
+
+			var err error
+			if encoded, err = json.Marshal(body); err != nil {
+				return err
+			}
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := c.newRequest(ctx, method, path, reader, "application/json")
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp.StatusCode, data)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// newRequest returns the request with the credentials of the client
+func (c *Client) newRequest(ctx context.Context, method string, path string, body io.Reader, accept string) (*http.Request, error) {
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", accept)
+
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	return req, nil
+}
+
+func decodeError(status int, data []byte) error {
+
+	body := struct {
+		Error *Error `json:"error"`
+	}{}
+
+	if err := json.Unmarshal(data, &body); err != nil || body.Error == nil {
+		return &Error{StatusCode: status, Message: strings.TrimSpace(string(data))}
+	}
+
+	body.Error.StatusCode = status
+
+	return body.Error
+}
+
+// statusError converts the status of the dashboard routes, <nil> if done, to an error
+func statusError(status string) error {
+
+	if status == "" || status == "<nil>" {
+		return nil
+	}
+
+	return errors.New(status)
+}
+
+// queryValues encodes the query of a listing, the ids of the selection aren't supported
+func queryValues(query fleet.Query) string {
+
+	values := url.Values{}
+
+	if query.Prefix != "" {
+		values.Set("prefix", query.Prefix)
+	}
+
+	for _, tag := range query.Tags {
+		values.Add("tag", tag)
+	}
+
+	if query.Group != "" {
+		values.Set("group", query.Group)
+	}
+
+	if query.Active != nil {
+		values.Set("active", strconv.FormatBool(*query.Active))
+	}
+
+	if query.Sort != "" {
+		values.Set("sort", query.Sort)
+	}
+
+	if query.Offset != 0 {
+		values.Set("offset", strconv.Itoa(query.Offset))
+	}
+
+	if query.Limit != 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	return "?" + values.Encode()
+}
+
+// OpenAPI returns the OpenAPI 3 document of the API
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+
+	var doc json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/api/v2/openapi.json", "", nil, &doc)
+
+	return doc, err
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	dev "github.com/arslab/lwnsimulator/simulator/components/device"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/handler"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/socket"
+	"github.com/brocaar/lorawan"
+)
+
+// DevicePage is a page of the devices
+type DevicePage struct {
+	Items  []*dev.Device `json:"items"`
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+}
+
+// ListDevices returns the page of the devices matching the query
+func (c *Client) ListDevices(ctx context.Context, query fleet.Query) (*DevicePage, error) {
+
+	var page DevicePage
+	if err := c.do(ctx, http.MethodGet, "/api/v2/devices"+queryValues(query), "", nil, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// CreateDevice adds the device and returns it with its id
+func (c *Client) CreateDevice(ctx context.Context, device *dev.Device) (*dev.Device, error) {
+	return c.device(ctx, http.MethodPost, "/api/v2/devices", "application/json", device)
+}
+
+// GetDevice returns the device
+func (c *Client) GetDevice(ctx context.Context, Id int) (*dev.Device, error) {
+	return c.device(ctx, http.MethodGet, "/api/v2/devices/"+strconv.Itoa(Id), "", nil)
+}
+
+// GetDeviceByEUI returns the device with the DevEUI
+func (c *Client) GetDeviceByEUI(ctx context.Context, DevEUI lorawan.EUI64) (*dev.Device, error) {
+	return c.device(ctx, http.MethodGet, "/api/v2/devices/eui/"+DevEUI.String(), "", nil)
+}
+
+// PatchDevice merges patch (a JSON merge patch, any value encoding to a JSON object)
+// over the stopped device and returns it updated
+func (c *Client) PatchDevice(ctx context.Context, Id int, patch interface{}) (*dev.Device, error) {
+	return c.device(ctx, http.MethodPatch, "/api/v2/devices/"+strconv.Itoa(Id), "application/merge-patch+json", patch)
+}
+
+// DeleteDevice removes the stopped device
+func (c *Client) DeleteDevice(ctx context.Context, Id int) error {
+	return c.do(ctx, http.MethodDelete, "/api/v2/devices/"+strconv.Itoa(Id), "", nil, nil)
+}
+
+func (c *Client) device(ctx context.Context, method string, path string, contentType string, body interface{}) (*dev.Device, error) {
+
+	var device dev.Device
+	if err := c.do(ctx, method, path, contentType, body, &device); err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// GetDownlinks returns the application downlinks received by the device
+func (c *Client) GetDownlinks(ctx context.Context, Id int) ([]handler.Downlink, error) {
+
+	var downlinks []handler.Downlink
+	err := c.do(ctx, http.MethodGet, "/api/downlinks/"+strconv.Itoa(Id), "", nil, &downlinks)
+
+	return downlinks, err
+}
+
+// TriggerUplink sends an uplink from the running device now, false if it isn't running
+func (c *Client) TriggerUplink(ctx context.Context, payload socket.NewPayload) (bool, error) {
+
+	status := struct {
+		Status bool `json:"status"`
+	}{}
+	err := c.do(ctx, http.MethodPost, "/api/trigger-uplink", "application/json", payload, &status)
+
+	return status.Status, err
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/arslab/lwnsimulator/simulator/events"
+)
+
+// Events subscribes to the live events matching the filter through the server-sent events
+// of /api/v2/events. The channel is closed when ctx is done or the stream ends
+func (c *Client) Events(ctx context.Context, filter events.Filter) (<-chan events.Event, error) {
+
+	values := url.Values{}
+
+	for _, Id := range filter.Devices {
+		values.Add("device", strconv.Itoa(Id))
+	}
+
+	for _, Id := range filter.Gateways {
+		values.Add("gateway", strconv.Itoa(Id))
+	}
+
+	for _, t := range filter.Types {
+		values.Add("type", t)
+	}
+
+	path := "/api/v2/events"
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+
+		defer resp.Body.Close()
+
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, decodeError(resp.StatusCode, data)
+	}
+
+	stream := make(chan events.Event)
+
+	go func() {
+
+		defer close(stream)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+		for scanner.Scan() { // only the data lines, the name of the event is its type
+
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+
+			var e events.Event
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &e); err != nil {
+				continue
+			}
+
+			select {
+			case stream <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return stream, nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+)
+
+// results is the answer of the routes acting on a selection
+type results struct {
+	Status  string         `json:"status"`
+	Results []fleet.Result `json:"results"`
+}
+
+// GetTemplates returns the device templates
+func (c *Client) GetTemplates(ctx context.Context) ([]fleet.Template, error) {
+
+	var templates []fleet.Template
+	err := c.do(ctx, http.MethodGet, "/api/templates", "", nil, &templates)
+
+	return templates, err
+}
+
+// SaveTemplate adds or replaces the device template
+func (c *Client) SaveTemplate(ctx context.Context, template fleet.Template) error {
+	return c.status(ctx, "/api/templates/save", template)
+}
+
+// DeleteTemplate removes the template, false if not found
+func (c *Client) DeleteTemplate(ctx context.Context, name string) (bool, error) {
+	return c.done(ctx, http.MethodPost, "/api/templates/del", struct {
+		Name string `json:"name"`
+	}{name})
+}
+
+// BulkAddDevices creates the devices from a template, the results are the devices created
+// and the ones failed
+func (c *Client) BulkAddDevices(ctx context.Context, req fleet.Create) ([]fleet.Result, error) {
+	return c.results(ctx, "/api/bulk/add-devices", req)
+}
+
+// BulkUpdateDevices merges the patch over the selected stopped devices
+func (c *Client) BulkUpdateDevices(ctx context.Context, req fleet.Update) ([]fleet.Result, error) {
+	return c.results(ctx, "/api/bulk/up-devices", req)
+}
+
+// BulkDeleteDevices removes the selected devices
+func (c *Client) BulkDeleteDevices(ctx context.Context, req fleet.Delete) ([]fleet.Result, error) {
+	return c.results(ctx, "/api/bulk/del-devices", req)
+}
+
+// DevicesCommand sends the command to the selected devices
+func (c *Client) DevicesCommand(ctx context.Context, cmd fleet.Command) ([]fleet.Result, error) {
+	return c.results(ctx, "/api/bulk/devices-command", cmd)
+}
+
+// GatewaysCommand sends the command to the selected gateways
+func (c *Client) GatewaysCommand(ctx context.Context, cmd fleet.Command) ([]fleet.Result, error) {
+	return c.results(ctx, "/api/bulk/gateways-command", cmd)
+}
+
+// ForceRejoin discards the session of the selected devices, the OTAA devices join again
+func (c *Client) ForceRejoin(ctx context.Context, selection fleet.Selection) ([]fleet.Result, error) {
+	return c.results(ctx, "/api/rejoin", selection)
+}
+
+func (c *Client) results(ctx context.Context, path string, body interface{}) ([]fleet.Result, error) {
+
+	var res results
+	if err := c.do(ctx, http.MethodPost, path, "application/json", body, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Results, statusError(res.Status)
+}
+
+// GetGroups returns the groups of devices and gateways
+func (c *Client) GetGroups(ctx context.Context) ([]fleet.Group, error) {
+
+	var groups []fleet.Group
+	err := c.do(ctx, http.MethodGet, "/api/groups", "", nil, &groups)
+
+	return groups, err
+}
+
+// SaveGroup adds or replaces the group
+func (c *Client) SaveGroup(ctx context.Context, group fleet.Group) error {
+	return c.status(ctx, "/api/groups/save", group)
+}
+
+// DeleteGroup removes the group, false if not found
+func (c *Client) DeleteGroup(ctx context.Context, name string) (bool, error) {
+	return c.done(ctx, http.MethodPost, "/api/groups/del", struct {
+		Name string `json:"name"`
+	}{name})
+}
+
+// DeviceAction applies the power action to the selected devices now, or schedules it with
+// At or Delay: the action returned has the id to cancel it and the results are empty
+func (c *Client) DeviceAction(ctx context.Context, action fleet.Action) (*fleet.Action, []fleet.Result, error) {
+
+	res := struct {
+		results
+		Action fleet.Action `json:"action"`
+	}{}
+
+	if err := c.do(ctx, http.MethodPost, "/api/device-action", "application/json", action, &res); err != nil {
+		return nil, nil, err
+	}
+
+	return &res.Action, res.Results, statusError(res.Status)
+}
+
+// GetDeviceActions returns the scheduled device actions
+func (c *Client) GetDeviceActions(ctx context.Context) ([]fleet.Action, error) {
+
+	var actions []fleet.Action
+	err := c.do(ctx, http.MethodGet, "/api/device-actions", "", nil, &actions)
+
+	return actions, err
+}
+
+// CancelDeviceAction cancels the scheduled action, false if not found
+func (c *Client) CancelDeviceAction(ctx context.Context, Id int) (bool, error) {
+	return c.done(ctx, http.MethodPost, "/api/device-actions/del", struct {
+		Id int `json:"id"`
+	}{Id})
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	gw "github.com/arslab/lwnsimulator/simulator/components/gateway"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/brocaar/lorawan"
+)
+
+// GatewayPage is a page of the gateways
+type GatewayPage struct {
+	Items  []*gw.Gateway `json:"items"`
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+}
+
+// ListGateways returns the page of the gateways matching the query
+func (c *Client) ListGateways(ctx context.Context, query fleet.Query) (*GatewayPage, error) {
+
+	var page GatewayPage
+	if err := c.do(ctx, http.MethodGet, "/api/v2/gateways"+queryValues(query), "", nil, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// CreateGateway adds the gateway and returns it with its id
+func (c *Client) CreateGateway(ctx context.Context, gateway *gw.Gateway) (*gw.Gateway, error) {
+	return c.gateway(ctx, http.MethodPost, "/api/v2/gateways", "application/json", gateway)
+}
+
+// GetGateway returns the gateway
+func (c *Client) GetGateway(ctx context.Context, Id int) (*gw.Gateway, error) {
+	return c.gateway(ctx, http.MethodGet, "/api/v2/gateways/"+strconv.Itoa(Id), "", nil)
+}
+
+// GetGatewayByMAC returns the gateway with the MAC address
+func (c *Client) GetGatewayByMAC(ctx context.Context, MACAddress lorawan.EUI64) (*gw.Gateway, error) {
+	return c.gateway(ctx, http.MethodGet, "/api/v2/gateways/mac/"+MACAddress.String(), "", nil)
+}
+
+// PatchGateway merges patch (a JSON merge patch, any value encoding to a JSON object)
+// over the stopped gateway and returns it updated
+func (c *Client) PatchGateway(ctx context.Context, Id int, patch interface{}) (*gw.Gateway, error) {
+	return c.gateway(ctx, http.MethodPatch, "/api/v2/gateways/"+strconv.Itoa(Id), "application/merge-patch+json", patch)
+}
+
+// DeleteGateway removes the stopped gateway
+func (c *Client) DeleteGateway(ctx context.Context, Id int) error {
+	return c.do(ctx, http.MethodDelete, "/api/v2/gateways/"+strconv.Itoa(Id), "", nil, nil)
+}
+
+func (c *Client) gateway(ctx context.Context, method string, path string, contentType string, body interface{}) (*gw.Gateway, error) {
+
+	var gateway gw.Gateway
+	if err := c.do(ctx, method, path, contentType, body, &gateway); err != nil {
+		return nil, err
+	}
+
+	return &gateway, nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/load"
+	"github.com/arslab/lwnsimulator/simulator/report"
+)
+
+// StartLoadProfile starts the profile, and the simulation if it's stopped
+func (c *Client) StartLoadProfile(ctx context.Context, profile load.Profile) error {
+	return c.status(ctx, "/api/load-profile/start", &profile) // durations in seconds
+}
+
+// StopLoadProfile stops the profile and restores its devices, false if none is running
+func (c *Client) StopLoadProfile(ctx context.Context) (bool, error) {
+	return c.done(ctx, http.MethodGet, "/api/load-profile/stop", nil)
+}
+
+// GetLoadStatus returns the running profile and its last report
+func (c *Client) GetLoadStatus(ctx context.Context) (*load.Status, error) {
+
+	var status load.Status
+	if err := c.do(ctx, http.MethodGet, "/api/load-profile", "", nil, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// GetReport returns the report of the running or last run between from and to, the zero
+// times are the bounds of the run
+func (c *Client) GetReport(ctx context.Context, from time.Time, to time.Time) (*report.Report, error) {
+	return c.report(ctx, "/api/report", from, to)
+}
+
+// GetReports returns the ids of the saved runs
+func (c *Client) GetReports(ctx context.Context) ([]string, error) {
+
+	var ids []string
+	err := c.do(ctx, http.MethodGet, "/api/reports", "", nil, &ids)
+
+	return ids, err
+}
+
+// GetRunReport returns the report of the saved run between from and to
+func (c *Client) GetRunReport(ctx context.Context, Id string, from time.Time, to time.Time) (*report.Report, error) {
+	return c.report(ctx, "/api/reports/"+url.PathEscape(Id), from, to)
+}
+
+func (c *Client) report(ctx context.Context, path string, from time.Time, to time.Time) (*report.Report, error) {
+
+	values := url.Values{}
+
+	if !from.IsZero() {
+		values.Set("from", from.Format(time.RFC3339))
+	}
+
+	if !to.IsZero() {
+		values.Set("to", to.Format(time.RFC3339))
+	}
+
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+
+	res := struct {
+		Status string `json:"status"` // error instead of the report
+		report.Report
+	}{}
+
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &res); err != nil {
+		return nil, err
+	}
+
+	if err := statusError(res.Status); err != nil {
+		return nil, err
+	}
+
+	return &res.Report, nil
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/arslab/lwnsimulator/models"
+	"github.com/arslab/lwnsimulator/simulator/clock"
+)
+
+// Start starts the simulation, false if it was already running
+func (c *Client) Start(ctx context.Context) (bool, error) {
+
+	var ok bool
+	err := c.do(ctx, http.MethodGet, "/api/start", "", nil, &ok)
+
+	return ok, err
+}
+
+// Stop stops the simulation, false if it wasn't running
+func (c *Client) Stop(ctx context.Context) (bool, error) {
+
+	var ok bool
+	err := c.do(ctx, http.MethodGet, "/api/stop", "", nil, &ok)
+
+	return ok, err
+}
+
+// Running is true if the simulation is running
+func (c *Client) Running(ctx context.Context) (bool, error) {
+
+	var running bool
+	err := c.do(ctx, http.MethodGet, "/api/status", "", nil, &running)
+
+	return running, err
+}
+
+// GetStopReport returns the report of the last stop
+func (c *Client) GetStopReport(ctx context.Context) (*models.StopReport, error) {
+
+	var report models.StopReport
+	if err := c.do(ctx, http.MethodGet, "/api/stop-report", "", nil, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// GetBridge returns the address of the gateway bridge
+func (c *Client) GetBridge(ctx context.Context) (*models.AddressIP, error) {
+
+	var bridge models.AddressIP
+	if err := c.do(ctx, http.MethodGet, "/api/bridge", "", nil, &bridge); err != nil {
+		return nil, err
+	}
+
+	return &bridge, nil
+}
+
+// SaveBridge sets the address of the gateway bridge
+func (c *Client) SaveBridge(ctx context.Context, bridge models.AddressIP) error {
+
+	status := struct {
+		Status json.RawMessage `json:"status"` // null if saved
+	}{}
+
+	if err := c.do(ctx, http.MethodPost, "/api/bridge/save", "application/json", bridge, &status); err != nil {
+		return err
+	}
+
+	if len(status.Status) > 0 && string(status.Status) != "null" {
+		return errors.New("Bridge address not saved")
+	}
+
+	return nil
+}
+
+// GetRampUp returns the ramp-up of the devices at the start of the simulation
+func (c *Client) GetRampUp(ctx context.Context) (*models.RampUp, error) {
+
+	var rampUp models.RampUp
+	if err := c.do(ctx, http.MethodGet, "/api/ramp-up", "", nil, &rampUp); err != nil {
+		return nil, err
+	}
+
+	return &rampUp, nil
+}
+
+// SaveRampUp sets the ramp-up of the devices at the start of the simulation
+func (c *Client) SaveRampUp(ctx context.Context, rampUp models.RampUp) error {
+	return c.status(ctx, "/api/ramp-up/save", rampUp)
+}
+
+// GetClock returns the clock of the simulation
+func (c *Client) GetClock(ctx context.Context) (*clock.Config, error) {
+
+	var config clock.Config
+	if err := c.do(ctx, http.MethodGet, "/api/clock", "", nil, &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// SaveClock sets the clock of the simulation, it must be stopped
+func (c *Client) SaveClock(ctx context.Context, config clock.Config) error {
+	return c.status(ctx, "/api/clock/save", config)
+}
+
+// status posts body to a dashboard route answering the status of the outcome
+func (c *Client) status(ctx context.Context, path string, body interface{}) error {
+
+	status := struct {
+		Status string `json:"status"`
+	}{}
+
+	if err := c.do(ctx, http.MethodPost, path, "application/json", body, &status); err != nil {
+		return err
+	}
+
+	return statusError(status.Status)
+}
+
+// done posts body to a dashboard route answering true if done
+func (c *Client) done(ctx context.Context, method string, path string, body interface{}) (bool, error) {
+
+	status := struct {
+		Status bool `json:"status"`
+	}{}
+
+	var contentType string
+	if body != nil {
+		contentType = "application/json"
+	}
+
+	err := c.do(ctx, method, path, contentType, body, &status)
+
+	return status.Status, err
+}
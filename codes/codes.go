@@ -8,4 +8,17 @@ const (
 	CodeNoBridge
 	CodeErrorGatewayActive
 	CodeSaving
+	CodeErrorGenerator
+	CodeErrorReaction
+	CodeErrorTraffic
+	CodeErrorSchedule
+	CodeErrorStream
+	CodeErrorPatch
+	CodeErrorDeviceOff
+	CodeErrorCommand
+	CodeErrorNotFound
+	CodeErrorRequest
+	CodeErrorInternal
+	CodeErrorUnauthorized
+	CodeErrorForbidden
 )
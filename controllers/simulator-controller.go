@@ -1,11 +1,20 @@
 package controllers
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/arslab/lwnsimulator/models"
 	repo "github.com/arslab/lwnsimulator/repositories"
 
+	"github.com/arslab/lwnsimulator/simulator/clock"
 	dev "github.com/arslab/lwnsimulator/simulator/components/device"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/handler"
 	gw "github.com/arslab/lwnsimulator/simulator/components/gateway"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/simulator/load"
+	"github.com/arslab/lwnsimulator/simulator/report"
 	e "github.com/arslab/lwnsimulator/socket"
 	"github.com/brocaar/lorawan"
 	socketio "github.com/googollee/go-socket.io"
@@ -18,22 +27,63 @@ type SimulatorController interface {
 	Status() bool
 	GetIstance()
 	AddWebSocket(*socketio.Conn)
+	RemoveWebSocket(*socketio.Conn)
 	SaveBridgeAddress(models.AddressIP) error
 	GetBridgeAddress() models.AddressIP
-	GetGateways() []gw.Gateway
+	SaveRampUp(models.RampUp) error
+	GetRampUp() models.RampUp
+	GetGateways(fleet.Selection) []json.RawMessage
 	AddGateway(*gw.Gateway) (int, int, error)
 	UpdateGateway(*gw.Gateway) (int, error)
 	DeleteGateway(int) bool
 	AddDevice(*dev.Device) (int, int, error)
-	GetDevices() []dev.Device
+	GetDevices(fleet.Selection) []json.RawMessage
 	UpdateDevice(*dev.Device) (int, error)
 	DeleteDevice(int) bool
 	ToggleStateDevice(int)
 	SendMACCommand(lorawan.CID, e.MacCommand)
 	ChangePayload(e.NewPayload) (string, bool)
 	SendUplink(e.NewPayload)
+	TriggerUplink(e.NewPayload) bool
 	ChangeLocation(e.NewLocation) bool
 	ToggleStateGateway(int)
+	GetDownlinks(int) ([]handler.Downlink, error)
+	GetTemplates() []fleet.Template
+	SaveTemplate(fleet.Template) error
+	DeleteTemplate(string) bool
+	BulkAddDevices(fleet.Create) ([]fleet.Result, error)
+	BulkUpdateDevices(fleet.Update) ([]fleet.Result, error)
+	BulkDeleteDevices(fleet.Delete) []fleet.Result
+	ForceRejoin(fleet.Selection) []fleet.Result
+	DeviceAction(fleet.Action) (fleet.Action, []fleet.Result, error)
+	GetDeviceActions() []fleet.Action
+	CancelDeviceAction(int) bool
+	GetGroups() []fleet.Group
+	SaveGroup(fleet.Group) error
+	DeleteGroup(string) bool
+	DevicesCommand(fleet.Command) ([]fleet.Result, error)
+	GatewaysCommand(fleet.Command) ([]fleet.Result, error)
+	StartLoadProfile(load.Profile) error
+	StopLoadProfile() bool
+	GetLoadStatus() load.Status
+	GetReport(time.Time, time.Time) (report.Report, error)
+	GetReports() []string
+	GetRunReport(string, time.Time, time.Time) (report.Report, error)
+	SaveClock(clock.Config) error
+	GetClock() clock.Config
+	GetStopReport() models.StopReport
+	ListDevices(fleet.Query) (fleet.Page, error)
+	GetDevice(int) (json.RawMessage, int, error)
+	DeviceByEUI(lorawan.EUI64) (json.RawMessage, int, error)
+	MergeDevice(int, json.RawMessage) (int, error)
+	RemoveDevice(int) (int, error)
+	ListGateways(fleet.Query) (fleet.Page, error)
+	GetGateway(int) (json.RawMessage, int, error)
+	GatewayByMAC(lorawan.EUI64) (json.RawMessage, int, error)
+	MergeGateway(int, json.RawMessage) (int, error)
+	RemoveGateway(int) (int, error)
+	Subscribe(events.Filter) *events.Subscription
+	Unsubscribe(*events.Subscription)
 }
 
 type simulatorController struct {
@@ -56,6 +106,10 @@ func (c *simulatorController) AddWebSocket(socket *socketio.Conn) {
 	c.repo.AddWebSocket(socket)
 }
 
+func (c *simulatorController) RemoveWebSocket(socket *socketio.Conn) {
+	c.repo.RemoveWebSocket(socket)
+}
+
 func (c *simulatorController) Run() bool {
 	return c.repo.Run()
 }
@@ -76,8 +130,16 @@ func (c *simulatorController) GetBridgeAddress() models.AddressIP {
 	return c.repo.GetBridgeAddress()
 }
 
-func (c *simulatorController) GetGateways() []gw.Gateway {
-	return c.repo.GetGateways()
+func (c *simulatorController) SaveRampUp(rampUp models.RampUp) error {
+	return c.repo.SaveRampUp(rampUp)
+}
+
+func (c *simulatorController) GetRampUp() models.RampUp {
+	return c.repo.GetRampUp()
+}
+
+func (c *simulatorController) GetGateways(filter fleet.Selection) []json.RawMessage {
+	return c.repo.GetGateways(filter)
 }
 
 func (c *simulatorController) AddGateway(gateway *gw.Gateway) (int, int, error) {
@@ -96,8 +158,8 @@ func (c *simulatorController) AddDevice(device *dev.Device) (int, int, error) {
 	return c.repo.AddDevice(device)
 }
 
-func (c *simulatorController) GetDevices() []dev.Device {
-	return c.repo.GetDevices()
+func (c *simulatorController) GetDevices(filter fleet.Selection) []json.RawMessage {
+	return c.repo.GetDevices(filter)
 }
 
 func (c *simulatorController) UpdateDevice(device *dev.Device) (int, error) {
@@ -124,6 +186,10 @@ func (c *simulatorController) SendUplink(pl e.NewPayload) {
 	c.repo.SendUplink(pl)
 }
 
+func (c *simulatorController) TriggerUplink(pl e.NewPayload) bool {
+	return c.repo.TriggerUplink(pl)
+}
+
 func (c *simulatorController) ChangeLocation(loc e.NewLocation) bool {
 	return c.repo.ChangeLocation(loc)
 }
@@ -131,3 +197,151 @@ func (c *simulatorController) ChangeLocation(loc e.NewLocation) bool {
 func (c *simulatorController) ToggleStateGateway(Id int) {
 	c.repo.ToggleStateGateway(Id)
 }
+
+func (c *simulatorController) GetDownlinks(Id int) ([]handler.Downlink, error) {
+	return c.repo.GetDownlinks(Id)
+}
+
+func (c *simulatorController) GetTemplates() []fleet.Template {
+	return c.repo.GetTemplates()
+}
+
+func (c *simulatorController) SaveTemplate(template fleet.Template) error {
+	return c.repo.SaveTemplate(template)
+}
+
+func (c *simulatorController) DeleteTemplate(name string) bool {
+	return c.repo.DeleteTemplate(name)
+}
+
+func (c *simulatorController) BulkAddDevices(req fleet.Create) ([]fleet.Result, error) {
+	return c.repo.BulkAddDevices(req)
+}
+
+func (c *simulatorController) BulkUpdateDevices(req fleet.Update) ([]fleet.Result, error) {
+	return c.repo.BulkUpdateDevices(req)
+}
+
+func (c *simulatorController) BulkDeleteDevices(req fleet.Delete) []fleet.Result {
+	return c.repo.BulkDeleteDevices(req)
+}
+
+func (c *simulatorController) ForceRejoin(selection fleet.Selection) []fleet.Result {
+	return c.repo.ForceRejoin(selection)
+}
+
+func (c *simulatorController) DeviceAction(action fleet.Action) (fleet.Action, []fleet.Result, error) {
+	return c.repo.DeviceAction(action)
+}
+
+func (c *simulatorController) GetDeviceActions() []fleet.Action {
+	return c.repo.GetDeviceActions()
+}
+
+func (c *simulatorController) CancelDeviceAction(Id int) bool {
+	return c.repo.CancelDeviceAction(Id)
+}
+
+func (c *simulatorController) GetGroups() []fleet.Group {
+	return c.repo.GetGroups()
+}
+
+func (c *simulatorController) SaveGroup(group fleet.Group) error {
+	return c.repo.SaveGroup(group)
+}
+
+func (c *simulatorController) DeleteGroup(Name string) bool {
+	return c.repo.DeleteGroup(Name)
+}
+
+func (c *simulatorController) DevicesCommand(cmd fleet.Command) ([]fleet.Result, error) {
+	return c.repo.DevicesCommand(cmd)
+}
+
+func (c *simulatorController) GatewaysCommand(cmd fleet.Command) ([]fleet.Result, error) {
+	return c.repo.GatewaysCommand(cmd)
+}
+
+func (c *simulatorController) StartLoadProfile(profile load.Profile) error {
+	return c.repo.StartLoadProfile(profile)
+}
+
+func (c *simulatorController) StopLoadProfile() bool {
+	return c.repo.StopLoadProfile()
+}
+
+func (c *simulatorController) GetLoadStatus() load.Status {
+	return c.repo.GetLoadStatus()
+}
+
+func (c *simulatorController) GetReport(from time.Time, to time.Time) (report.Report, error) {
+	return c.repo.GetReport(from, to)
+}
+
+func (c *simulatorController) GetReports() []string {
+	return c.repo.GetReports()
+}
+
+func (c *simulatorController) GetRunReport(Id string, from time.Time, to time.Time) (report.Report, error) {
+	return c.repo.GetRunReport(Id, from, to)
+}
+
+func (c *simulatorController) SaveClock(config clock.Config) error {
+	return c.repo.SaveClock(config)
+}
+
+func (c *simulatorController) GetClock() clock.Config {
+	return c.repo.GetClock()
+}
+
+func (c *simulatorController) GetStopReport() models.StopReport {
+	return c.repo.GetStopReport()
+}
+
+func (c *simulatorController) ListDevices(query fleet.Query) (fleet.Page, error) {
+	return c.repo.ListDevices(query)
+}
+
+func (c *simulatorController) GetDevice(Id int) (json.RawMessage, int, error) {
+	return c.repo.GetDevice(Id)
+}
+
+func (c *simulatorController) DeviceByEUI(DevEUI lorawan.EUI64) (json.RawMessage, int, error) {
+	return c.repo.DeviceByEUI(DevEUI)
+}
+
+func (c *simulatorController) MergeDevice(Id int, patch json.RawMessage) (int, error) {
+	return c.repo.MergeDevice(Id, patch)
+}
+
+func (c *simulatorController) RemoveDevice(Id int) (int, error) {
+	return c.repo.RemoveDevice(Id)
+}
+
+func (c *simulatorController) ListGateways(query fleet.Query) (fleet.Page, error) {
+	return c.repo.ListGateways(query)
+}
+
+func (c *simulatorController) GetGateway(Id int) (json.RawMessage, int, error) {
+	return c.repo.GetGateway(Id)
+}
+
+func (c *simulatorController) GatewayByMAC(MACAddress lorawan.EUI64) (json.RawMessage, int, error) {
+	return c.repo.GatewayByMAC(MACAddress)
+}
+
+func (c *simulatorController) MergeGateway(Id int, patch json.RawMessage) (int, error) {
+	return c.repo.MergeGateway(Id, patch)
+}
+
+func (c *simulatorController) RemoveGateway(Id int) (int, error) {
+	return c.repo.RemoveGateway(Id)
+}
+
+func (c *simulatorController) Subscribe(filter events.Filter) *events.Subscription {
+	return c.repo.Subscribe(filter)
+}
+
+func (c *simulatorController) Unsubscribe(sub *events.Subscription) {
+	c.repo.Unsubscribe(sub)
+}
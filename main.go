@@ -23,8 +23,6 @@ import (
 
 	dev "github.com/arslab/lwnsimulator/simulator/components/device"
 
-	"encoding/hex"
-	"os"
 	"strconv"
 )
 
@@ -76,7 +74,17 @@ type Status struct {
 		FPort int `json:"fport"`
 		FCnt  int `json:"fcnt"`
 	} `json:"infoUplink"`
-	FCntDown int `json:"fcntDown"`
+	FCntDown   int         `json:"fcntDown"`
+	DataSource *DataSource `json:"dataSource,omitempty"`
+}
+
+// DataSource binds the device to a file replayed record by record
+type DataSource struct {
+	Format    string `json:"format"`
+	Path      string `json:"path"`
+	Policy    string `json:"policy"`
+	ChunkSize int    `json:"chunkSize"`
+	SendHex   bool   `json:"sendHex"`
 }
 
 // Configuration represents the "configuration" part of the structure
@@ -272,7 +280,6 @@ func main() {
 			deviceId, _ := deviceType["id"].(float64)
 			// fmt.Println("\n\n\n", deviceId)
 			var dataPath string
-			var payloadData string
 			if deviceId == 6199 {
 				dataPath = config.DataPathS
 			} else if deviceId == 6165 {
@@ -285,24 +292,14 @@ func main() {
 				// dataPath = path + config.DataPathS
 				continue
 			}
-			// Open the binary file
-			file, err := os.Open(dataPath)
-			if err != nil {
-				fmt.Println("Error opening file:", err)
-				return
+			// Replay the binary file in chunks of 128 bytes, sent as hex text
+			dataSource := &DataSource{
+				Format:    "binary",
+				Path:      dataPath,
+				Policy:    "loop",
+				ChunkSize: 128,
+				SendHex:   true,
 			}
-			defer file.Close()
-
-			// Read binary data into a buffer
-			buffer := make([]byte, 128)
-			_, err = file.Read(buffer)
-			if err != nil {
-				fmt.Println("Error reading binary data:", err)
-				return
-			}
-
-			// Convert binary data to a hexadecimal string
-			payloadData = hex.EncodeToString(buffer)
 
 			// Access specific properties
 			deviceID, _ := deviceMap["id"].(int)
@@ -341,9 +338,9 @@ func main() {
 						Altitude:  0,
 					},
 					Status: Status{
-						MType:   "ConfirmedDataUp",
-						Payload: payloadData,
-						Active:  true,
+						MType:      "ConfirmedDataUp",
+						Active:     true,
+						DataSource: dataSource,
 						InfoUplink: struct {
 							FPort int `json:"fport"`
 							FCnt  int `json:"fcnt"`
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	RoleViewer   = "viewer"   // read-only
+	RoleOperator = "operator" // viewer, start and stop, toggle, payloads, uplinks, MAC commands
+	RoleAdmin    = "admin"    // operator, creation, update and deletion, bridge and settings
+)
+
+// AuthConfig lists the accounts of the API, the API is open to everyone if it's empty
+type AuthConfig struct {
+	Users  []User  `json:"users,omitempty"`  // HTTP basic auth
+	Tokens []Token `json:"tokens,omitempty"` // Authorization: Bearer <token>
+}
+
+// User is an account of the basic auth
+type User struct {
+	Username string `json:"username"`
+	Password string `json:"password"` // bcrypt hash
+	Role     string `json:"role"`
+}
+
+// Token is an API token
+type Token struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Role  string `json:"role"`
+}
+
+// TLSConfig enables HTTPS if both files are set
+type TLSConfig struct {
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+// Enabled is true if there is at least an account
+func (a *AuthConfig) Enabled() bool {
+	return len(a.Users) > 0 || len(a.Tokens) > 0
+}
+
+// Validate checks the accounts
+func (a *AuthConfig) Validate() error {
+
+	for _, u := range a.Users {
+
+		if u.Username == "" || u.Password == "" {
+			return errors.New("User without username or password")
+		}
+
+		if _, err := bcrypt.Cost([]byte(u.Password)); err != nil {
+			return errors.New("Password of user " + u.Username + " must be a bcrypt hash")
+		}
+
+		if roleLevel(u.Role) == 0 {
+			return errors.New("Unknown role " + u.Role + " of user " + u.Username)
+		}
+	}
+
+	for _, t := range a.Tokens {
+
+		if t.Token == "" {
+			return errors.New("Empty token " + t.Name)
+		}
+
+		if roleLevel(t.Role) == 0 {
+			return errors.New("Unknown role " + t.Role + " of token " + t.Name)
+		}
+	}
+
+	return nil
+}
+
+// Enabled is true if the server has a certificate
+func (t *TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// HasRole is true if role grants the required one
+func HasRole(role string, required string) bool {
+	return roleLevel(role) >= roleLevel(required) && roleLevel(role) > 0
+}
+
+func roleLevel(role string) int {
+
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+
+	return 0
+}
+
+// ValidateOrigins checks the CORS origins, scheme and host without path
+func ValidateOrigins(origins []string) error {
+
+	for _, o := range origins {
+		if o != "*" && !strings.HasPrefix(o, "http://") && !strings.HasPrefix(o, "https://") {
+			return errors.New("Invalid origin " + o + ", it must start with http:// or https://")
+		}
+	}
+
+	return nil
+}
@@ -11,6 +11,20 @@ type ServerConfig struct {
 	MetricsPort   int    `json:"metricsPort"`
 	ConfigDirname string `json:"configDirname"`
 	AutoStart     bool   `json:"autoStart"`
+
+	Auth           AuthConfig `json:"auth"`           // accounts and roles, open API if empty
+	AllowedOrigins []string   `json:"allowedOrigins"` // CORS origins, "*" all, same origin only if empty
+	TLS            TLSConfig  `json:"tls"`
+}
+
+// Validate checks the accounts and the origins
+func (c *ServerConfig) Validate() error {
+
+	if err := c.Auth.Validate(); err != nil {
+		return err
+	}
+
+	return ValidateOrigins(c.AllowedOrigins)
 }
 
 func GetConfigFile(path string) (*ServerConfig, error) {
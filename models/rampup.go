@@ -0,0 +1,5 @@
+package models
+
+type RampUp struct {
+	DevicesPerSecond float64 `json:"devicesPerSecond"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// StopReport is the outcome of the last stop: the components still running when the
+// stop timeout expired
+type StopReport struct {
+	Time     time.Time `json:"time"`
+	Duration float64   `json:"duration"` // seconds
+	TimedOut bool      `json:"timedOut"`
+	Devices  []string  `json:"devices"`
+	Gateways []string  `json:"gateways"`
+}
@@ -1,7 +1,9 @@
 package repositories
 
 import (
+	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/brocaar/lorawan"
 
@@ -9,8 +11,14 @@ import (
 	e "github.com/arslab/lwnsimulator/socket"
 
 	"github.com/arslab/lwnsimulator/simulator"
+	"github.com/arslab/lwnsimulator/simulator/clock"
 	dev "github.com/arslab/lwnsimulator/simulator/components/device"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/handler"
 	gw "github.com/arslab/lwnsimulator/simulator/components/gateway"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/simulator/load"
+	"github.com/arslab/lwnsimulator/simulator/report"
 	"github.com/arslab/lwnsimulator/simulator/util"
 	socketio "github.com/googollee/go-socket.io"
 )
@@ -22,24 +30,66 @@ type SimulatorRepository interface {
 	Status() bool
 	GetIstance()
 	AddWebSocket(*socketio.Conn)
+	RemoveWebSocket(*socketio.Conn)
 	SaveBridgeAddress(models.AddressIP) error
 	GetBridgeAddress() models.AddressIP
-	GetGateways() []gw.Gateway
+	SaveRampUp(models.RampUp) error
+	GetRampUp() models.RampUp
+	GetGateways(fleet.Selection) []json.RawMessage
 	AddGateway(*gw.Gateway) (int, int, error)
 	UpdateGateway(*gw.Gateway) (int, error)
 	DeleteGateway(int) bool
 	AddDevice(*dev.Device) (int, int, error)
-	GetDevices() []dev.Device
+	GetDevices(fleet.Selection) []json.RawMessage
 	UpdateDevice(*dev.Device) (int, error)
 	DeleteDevice(int) bool
 	ToggleStateDevice(int)
 	SendMACCommand(lorawan.CID, e.MacCommand)
 	ChangePayload(e.NewPayload) (string, bool)
 	SendUplink(e.NewPayload)
+	TriggerUplink(e.NewPayload) bool
 	ChangeLocation(e.NewLocation) bool
 	ToggleStateGateway(int)
+	GetDownlinks(int) ([]handler.Downlink, error)
+	GetTemplates() []fleet.Template
+	SaveTemplate(fleet.Template) error
+	DeleteTemplate(string) bool
+	BulkAddDevices(fleet.Create) ([]fleet.Result, error)
+	BulkUpdateDevices(fleet.Update) ([]fleet.Result, error)
+	BulkDeleteDevices(fleet.Delete) []fleet.Result
+	ForceRejoin(fleet.Selection) []fleet.Result
+	DeviceAction(fleet.Action) (fleet.Action, []fleet.Result, error)
+	GetDeviceActions() []fleet.Action
+	CancelDeviceAction(int) bool
+	GetGroups() []fleet.Group
+	SaveGroup(fleet.Group) error
+	DeleteGroup(string) bool
+	DevicesCommand(fleet.Command) ([]fleet.Result, error)
+	GatewaysCommand(fleet.Command) ([]fleet.Result, error)
+	StartLoadProfile(load.Profile) error
+	StopLoadProfile() bool
+	GetLoadStatus() load.Status
+	GetReport(time.Time, time.Time) (report.Report, error)
+	GetReports() []string
+	GetRunReport(string, time.Time, time.Time) (report.Report, error)
+	SaveClock(clock.Config) error
+	GetClock() clock.Config
+	GetStopReport() models.StopReport
+	ListDevices(fleet.Query) (fleet.Page, error)
+	GetDevice(int) (json.RawMessage, int, error)
+	DeviceByEUI(lorawan.EUI64) (json.RawMessage, int, error)
+	MergeDevice(int, json.RawMessage) (int, error)
+	RemoveDevice(int) (int, error)
+	ListGateways(fleet.Query) (fleet.Page, error)
+	GetGateway(int) (json.RawMessage, int, error)
+	GatewayByMAC(lorawan.EUI64) (json.RawMessage, int, error)
+	MergeGateway(int, json.RawMessage) (int, error)
+	RemoveGateway(int) (int, error)
+	Subscribe(events.Filter) *events.Subscription
+	Unsubscribe(*events.Subscription)
 }
 
+// simulatorRepository runs every call on the command loop of the simulator
 type simulatorRepository struct {
 	sim *simulator.Simulator
 }
@@ -54,101 +104,322 @@ func (s *simulatorRepository) GetIstance() {
 }
 
 func (s *simulatorRepository) AddWebSocket(socket *socketio.Conn) {
-	s.sim.AddWebSocket(socket)
+	s.sim.Do(func() { s.sim.AddWebSocket(socket) })
 }
 
-func (s *simulatorRepository) Run() bool {
-	switch s.sim.State {
-	case util.Running:
-		s.sim.Print("", errors.New("Already run"), util.PrintOnlyConsole)
-		return false
-	case util.Stopped:
-		s.sim.Run()
-	}
-	return true
+func (s *simulatorRepository) RemoveWebSocket(socket *socketio.Conn) {
+	s.sim.Do(func() { s.sim.RemoveWebSocket(socket) })
 }
 
-func (s *simulatorRepository) Stop() bool {
-	switch s.sim.State {
-	case util.Stopped:
-		s.sim.Print("", errors.New("Already Stopped"), util.PrintOnlyConsole)
-		return false
-	default: //running
-		s.sim.Stop()
-		return true
-	}
+func (s *simulatorRepository) Run() (ok bool) {
+	s.sim.Do(func() {
+		switch s.sim.State {
+		case util.Running:
+			s.sim.Print("", errors.New("Already run"), util.PrintOnlyConsole)
+			ok = false
+		case util.Stopped:
+			if err := s.sim.CheckClock(s.sim.Clock); err != nil {
+				s.sim.Print("", err, util.PrintOnlyConsole)
+				ok = false
+				return
+			}
+			s.sim.Run()
+			ok = true
+		}
+	})
+	return
 }
 
-func (s *simulatorRepository) Status() bool {
-	switch s.sim.State {
-	case util.Running:
-		return true
-	}
-	return false
+func (s *simulatorRepository) Stop() (ok bool) {
+	s.sim.Do(func() {
+		switch s.sim.State {
+		case util.Stopped:
+			s.sim.Print("", errors.New("Already Stopped"), util.PrintOnlyConsole)
+			ok = false
+		default: //running
+			s.sim.Stop()
+			ok = true
+		}
+	})
+	return
 }
 
-func (s *simulatorRepository) SaveBridgeAddress(addr models.AddressIP) error {
-	return s.sim.SaveBridgeAddress(addr)
+func (s *simulatorRepository) Status() (ok bool) {
+	s.sim.Do(func() { ok = s.sim.State == util.Running })
+	return
 }
 
-func (s *simulatorRepository) GetBridgeAddress() models.AddressIP {
-	return s.sim.GetBridgeAddress()
+func (s *simulatorRepository) SaveBridgeAddress(addr models.AddressIP) (err error) {
+	s.sim.Do(func() { err = s.sim.SaveBridgeAddress(addr) })
+	return
 }
 
-func (s *simulatorRepository) GetGateways() []gw.Gateway {
-	return s.sim.GetGateways()
+func (s *simulatorRepository) GetBridgeAddress() (address models.AddressIP) {
+	s.sim.Do(func() { address = s.sim.GetBridgeAddress() })
+	return
 }
 
-func (s *simulatorRepository) AddGateway(gateway *gw.Gateway) (int, int, error) {
-	return s.sim.SetGateway(gateway, false)
+func (s *simulatorRepository) SaveRampUp(rampUp models.RampUp) (err error) {
+	s.sim.Do(func() { err = s.sim.SaveRampUp(rampUp) })
+	return
 }
 
-func (s *simulatorRepository) UpdateGateway(gateway *gw.Gateway) (int, error) {
-	code, _, err := s.sim.SetGateway(gateway, true)
-	return code, err
+func (s *simulatorRepository) GetRampUp() (rampUp models.RampUp) {
+	s.sim.Do(func() { rampUp = s.sim.GetRampUp() })
+	return
 }
 
-func (s *simulatorRepository) DeleteGateway(Id int) bool {
-	return s.sim.DeleteGateway(Id)
+func (s *simulatorRepository) GetGateways(filter fleet.Selection) (gateways []json.RawMessage) {
+	s.sim.Do(func() { gateways = s.sim.GetGateways(filter) })
+	return
 }
 
-func (s *simulatorRepository) AddDevice(device *dev.Device) (int, int, error) {
-	return s.sim.SetDevice(device, false)
+func (s *simulatorRepository) AddGateway(gateway *gw.Gateway) (code int, id int, err error) {
+	s.sim.Do(func() { code, id, err = s.sim.SetGateway(gateway, false) })
+	return
 }
 
-func (s *simulatorRepository) GetDevices() []dev.Device {
-	return s.sim.GetDevices()
+func (s *simulatorRepository) UpdateGateway(gateway *gw.Gateway) (code int, err error) {
+	s.sim.Do(func() { code, _, err = s.sim.SetGateway(gateway, true) })
+	return
 }
 
-func (s *simulatorRepository) UpdateDevice(device *dev.Device) (int, error) {
-	code, _, err := s.sim.SetDevice(device, true)
-	return code, err
+func (s *simulatorRepository) DeleteGateway(Id int) (ok bool) {
+	s.sim.Do(func() { ok = s.sim.DeleteGateway(Id) })
+	return
 }
 
-func (s *simulatorRepository) DeleteDevice(Id int) bool {
-	return s.sim.DeleteDevice(Id)
+func (s *simulatorRepository) AddDevice(device *dev.Device) (code int, id int, err error) {
+	s.sim.Do(func() { code, id, err = s.sim.SetDevice(device, false) })
+	return
+}
+
+func (s *simulatorRepository) GetDevices(filter fleet.Selection) (devices []json.RawMessage) {
+	s.sim.Do(func() { devices = s.sim.GetDevices(filter) })
+	return
+}
+
+func (s *simulatorRepository) UpdateDevice(device *dev.Device) (code int, err error) {
+	s.sim.Do(func() { code, _, err = s.sim.SetDevice(device, true) })
+	return
+}
+
+func (s *simulatorRepository) DeleteDevice(Id int) (ok bool) {
+	s.sim.Do(func() { ok = s.sim.DeleteDevice(Id) })
+	return
 }
 
 func (s *simulatorRepository) ToggleStateDevice(Id int) {
-	s.sim.ToggleStateDevice(Id)
+	s.sim.Do(func() { s.sim.ToggleStateDevice(Id) })
 }
 
 func (s *simulatorRepository) SendMACCommand(cid lorawan.CID, data e.MacCommand) {
-	s.sim.SendMACCommand(cid, data)
+	s.sim.Do(func() { s.sim.SendMACCommand(cid, data) })
 }
 
-func (s *simulatorRepository) ChangePayload(pl e.NewPayload) (string, bool) {
-	return s.sim.ChangePayload(pl)
+func (s *simulatorRepository) ChangePayload(pl e.NewPayload) (devEUI string, ok bool) {
+	s.sim.Do(func() { devEUI, ok = s.sim.ChangePayload(pl) })
+	return
 }
 
 func (s *simulatorRepository) SendUplink(pl e.NewPayload) {
-	s.sim.SendUplink(pl)
+	s.sim.Do(func() { s.sim.SendUplink(pl) })
 }
 
-func (s *simulatorRepository) ChangeLocation(loc e.NewLocation) bool {
-	return s.sim.ChangeLocation(loc)
+func (s *simulatorRepository) TriggerUplink(pl e.NewPayload) (ok bool) {
+	s.sim.Do(func() { ok = s.sim.TriggerUplink(pl) })
+	return
+}
+
+func (s *simulatorRepository) ChangeLocation(loc e.NewLocation) (ok bool) {
+	s.sim.Do(func() { ok = s.sim.ChangeLocation(loc) })
+	return
 }
 
 func (s *simulatorRepository) ToggleStateGateway(Id int) {
-	s.sim.ToggleStateGateway(Id)
+	s.sim.Do(func() { s.sim.ToggleStateGateway(Id) })
+}
+
+func (s *simulatorRepository) GetDownlinks(Id int) (downlinks []handler.Downlink, err error) {
+	s.sim.Do(func() { downlinks, err = s.sim.GetDownlinks(Id) })
+	return
+}
+
+func (s *simulatorRepository) GetTemplates() (templates []fleet.Template) {
+	s.sim.Do(func() { templates = s.sim.GetTemplates() })
+	return
+}
+
+func (s *simulatorRepository) SaveTemplate(template fleet.Template) (err error) {
+	s.sim.Do(func() { err = s.sim.SaveTemplate(template) })
+	return
+}
+
+func (s *simulatorRepository) DeleteTemplate(name string) (ok bool) {
+	s.sim.Do(func() { ok = s.sim.DeleteTemplate(name) })
+	return
+}
+
+func (s *simulatorRepository) BulkAddDevices(req fleet.Create) (results []fleet.Result, err error) {
+	s.sim.Do(func() { results, err = s.sim.BulkAddDevices(req) })
+	return
+}
+
+func (s *simulatorRepository) BulkUpdateDevices(req fleet.Update) (results []fleet.Result, err error) {
+	s.sim.Do(func() { results, err = s.sim.BulkUpdateDevices(req) })
+	return
+}
+
+func (s *simulatorRepository) BulkDeleteDevices(req fleet.Delete) (results []fleet.Result) {
+	s.sim.Do(func() { results = s.sim.BulkDeleteDevices(req) })
+	return
+}
+
+func (s *simulatorRepository) ForceRejoin(selection fleet.Selection) (results []fleet.Result) {
+	s.sim.Do(func() { results = s.sim.ForceRejoin(selection) })
+	return
+}
+
+func (s *simulatorRepository) DeviceAction(action fleet.Action) (scheduled fleet.Action, results []fleet.Result, err error) {
+	s.sim.Do(func() { scheduled, results, err = s.sim.DeviceAction(action) })
+	return
+}
+
+func (s *simulatorRepository) GetDeviceActions() (actions []fleet.Action) {
+	s.sim.Do(func() { actions = s.sim.GetDeviceActions() })
+	return
+}
+
+func (s *simulatorRepository) CancelDeviceAction(Id int) (ok bool) {
+	s.sim.Do(func() { ok = s.sim.CancelDeviceAction(Id) })
+	return
+}
+
+func (s *simulatorRepository) GetGroups() (groups []fleet.Group) {
+	s.sim.Do(func() { groups = s.sim.GetGroups() })
+	return
+}
+
+func (s *simulatorRepository) SaveGroup(group fleet.Group) (err error) {
+	s.sim.Do(func() { err = s.sim.SaveGroup(group) })
+	return
+}
+
+func (s *simulatorRepository) DeleteGroup(Name string) (ok bool) {
+	s.sim.Do(func() { ok = s.sim.DeleteGroup(Name) })
+	return
+}
+
+func (s *simulatorRepository) DevicesCommand(cmd fleet.Command) (results []fleet.Result, err error) {
+	s.sim.Do(func() { results, err = s.sim.DevicesCommand(cmd) })
+	return
+}
+
+func (s *simulatorRepository) GatewaysCommand(cmd fleet.Command) (results []fleet.Result, err error) {
+	s.sim.Do(func() { results, err = s.sim.GatewaysCommand(cmd) })
+	return
+}
+
+func (s *simulatorRepository) StartLoadProfile(profile load.Profile) (err error) {
+	s.sim.Do(func() { err = s.sim.StartLoadProfile(profile) })
+	return
+}
+
+func (s *simulatorRepository) StopLoadProfile() (ok bool) {
+	s.sim.Do(func() { ok = s.sim.StopLoadProfile() })
+	return
+}
+
+func (s *simulatorRepository) GetLoadStatus() (status load.Status) {
+	s.sim.Do(func() { status = s.sim.GetLoadStatus() })
+	return
+}
+
+func (s *simulatorRepository) GetReport(from time.Time, to time.Time) (r report.Report, err error) {
+	s.sim.Do(func() { r, err = s.sim.GetReport(from, to) })
+	return
+}
+
+func (s *simulatorRepository) GetReports() (ids []string) {
+	s.sim.Do(func() { ids = s.sim.GetReports() })
+	return
+}
+
+func (s *simulatorRepository) GetRunReport(Id string, from time.Time, to time.Time) (r report.Report, err error) {
+	s.sim.Do(func() { r, err = s.sim.GetRunReport(Id, from, to) })
+	return
+}
+
+func (s *simulatorRepository) SaveClock(config clock.Config) (err error) {
+	s.sim.Do(func() { err = s.sim.SaveClock(config) })
+	return
+}
+
+func (s *simulatorRepository) GetClock() (config clock.Config) {
+	s.sim.Do(func() { config = s.sim.GetClock() })
+	return
+}
+
+func (s *simulatorRepository) GetStopReport() (stopReport models.StopReport) {
+	s.sim.Do(func() { stopReport = s.sim.GetStopReport() })
+	return
+}
+
+func (s *simulatorRepository) ListDevices(query fleet.Query) (page fleet.Page, err error) {
+	s.sim.Do(func() { page, err = s.sim.ListDevices(query) })
+	return
+}
+
+func (s *simulatorRepository) GetDevice(Id int) (device json.RawMessage, code int, err error) {
+	s.sim.Do(func() { device, code, err = s.sim.GetDevice(Id) })
+	return
+}
+
+func (s *simulatorRepository) DeviceByEUI(DevEUI lorawan.EUI64) (device json.RawMessage, code int, err error) {
+	s.sim.Do(func() { device, code, err = s.sim.DeviceByEUI(DevEUI) })
+	return
+}
+
+func (s *simulatorRepository) MergeDevice(Id int, patch json.RawMessage) (code int, err error) {
+	s.sim.Do(func() { code, err = s.sim.MergeDevice(Id, patch) })
+	return
+}
+
+func (s *simulatorRepository) RemoveDevice(Id int) (code int, err error) {
+	s.sim.Do(func() { code, err = s.sim.RemoveDevice(Id) })
+	return
+}
+
+func (s *simulatorRepository) ListGateways(query fleet.Query) (page fleet.Page, err error) {
+	s.sim.Do(func() { page, err = s.sim.ListGateways(query) })
+	return
+}
+
+func (s *simulatorRepository) GetGateway(Id int) (gateway json.RawMessage, code int, err error) {
+	s.sim.Do(func() { gateway, code, err = s.sim.GetGateway(Id) })
+	return
+}
+
+func (s *simulatorRepository) GatewayByMAC(MACAddress lorawan.EUI64) (gateway json.RawMessage, code int, err error) {
+	s.sim.Do(func() { gateway, code, err = s.sim.GatewayByMAC(MACAddress) })
+	return
+}
+
+func (s *simulatorRepository) MergeGateway(Id int, patch json.RawMessage) (code int, err error) {
+	s.sim.Do(func() { code, err = s.sim.MergeGateway(Id, patch) })
+	return
+}
+
+func (s *simulatorRepository) RemoveGateway(Id int) (code int, err error) {
+	s.sim.Do(func() { code, err = s.sim.RemoveGateway(Id) })
+	return
+}
+
+func (s *simulatorRepository) Subscribe(filter events.Filter) (sub *events.Subscription) {
+	s.sim.Do(func() { sub = s.sim.Subscribe(filter) })
+	return
+}
+
+func (s *simulatorRepository) Unsubscribe(sub *events.Subscription) {
+	s.sim.Do(func() { s.sim.Unsubscribe(sub) })
 }
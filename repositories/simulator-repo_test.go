@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	dev "github.com/arslab/lwnsimulator/simulator/components/device"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	e "github.com/arslab/lwnsimulator/socket"
+)
+
+// device is an ABP device of EU868 sending every second, no gateway receives it
+const device = `{
+	"id": %d,
+	"info": {
+		"name": "dev%d",
+		"devEUI": "00000000000000%02x",
+		"devAddr": "000000%02x",
+		"nwkSKey": "00000000000000000000000000000000",
+		"appSKey": "00000000000000000000000000000000",
+		"location": {"latitude": 0, "longitude": 0, "altitude": 0},
+		"status": {
+			"mtype": "UnConfirmedDataUp",
+			"payload": "010203",
+			"active": true,
+			"infoUplink": {"fport": 1, "fcnt": 1},
+			"fcntDown": 0
+		},
+		"configuration": {
+			"region": 1,
+			"sendInterval": 1,
+			"ackTimeout": 1,
+			"range": 10000,
+			"supportedOtaa": false,
+			"supportedClassC": %t,
+			"dataRate": 5,
+			"nbRetransmission": 1
+		},
+		"rxs": [
+			{"delay": 1000, "durationOpen": 100, "channel": {"active": true, "freqDownlink": 868100000}, "dataRate": 5},
+			{"delay": 1000, "durationOpen": 100, "channel": {"active": true, "freqDownlink": 869525000}, "dataRate": 0}
+		]
+	}
+}`
+
+// setup returns a repository whose configuration is in a temporary directory
+func setup(t *testing.T) SimulatorRepository {
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	config := []byte(`{"address": "127.0.0.1", "port": 8000, "configDirname": "lwnsimulator"}`)
+	if err := os.WriteFile("config.json", config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSimulatorRepository()
+	repo.GetIstance()
+
+	return repo
+}
+
+// TestConcurrentAPI calls the API from many goroutines while the devices run, go test -race
+// reports the state read or written outside the command loop and the devices' owners
+func TestConcurrentAPI(t *testing.T) {
+
+	repo := setup(t)
+
+	var ids []int
+
+	for i := 1; i <= 8; i++ {
+
+		var d dev.Device
+		if err := json.Unmarshal([]byte(fmt.Sprintf(device, i, i, i, i, i%2 == 0)), &d); err != nil {
+			t.Fatal(err)
+		}
+
+		_, id, err := repo.AddDevice(&d)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if !repo.Run() {
+		t.Fatal("Simulator not started")
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	calls := []func(id int){
+		func(id int) { repo.GetDevices(fleet.Selection{}) },
+		func(id int) { repo.GetDevice(id) },
+		func(id int) { repo.ToggleStateDevice(id) },
+		func(id int) { repo.ForceRejoin(fleet.Selection{Ids: []int{id}}) },
+		func(id int) { repo.ChangeLocation(e.NewLocation{Id: id, Latitude: float64(id)}) },
+		func(id int) { repo.ChangePayload(e.NewPayload{Id: id, MType: "UnConfirmedDataUp", Payload: "0a0b"}) },
+		func(id int) { repo.SendUplink(e.NewPayload{Id: id, MType: "UnConfirmedDataUp", Payload: "0c"}) },
+		func(id int) { repo.TriggerUplink(e.NewPayload{Id: id, MType: "ConfirmedDataUp", Payload: "0d"}) },
+		func(id int) { repo.GetDownlinks(id) },
+		func(id int) { repo.GetLoadStatus() },
+	}
+
+	for i, call := range calls {
+
+		wg.Add(1)
+
+		go func(i int, call func(int)) {
+
+			defer wg.Done()
+
+			for n := 0; ; n++ {
+
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				call(ids[(i+n)%len(ids)])
+				time.Sleep(time.Millisecond)
+			}
+
+		}(i, call)
+	}
+
+	time.Sleep(3 * time.Second)
+
+	close(stop)
+	wg.Wait()
+
+	if !repo.Stop() {
+		t.Fatal("Simulator not stopped")
+	}
+
+	for _, raw := range repo.GetDevices(fleet.Selection{}) {
+
+		var d dev.Device
+		if err := json.Unmarshal(raw, &d); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package simulator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/arslab/lwnsimulator/codes"
+	"github.com/arslab/lwnsimulator/simulator/clock"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/simulator/util"
+)
+
+type scheduledAction struct {
+	action fleet.Action
+	timer  clock.Timer
+}
+
+// DeviceAction applies the lifecycle action to the selected devices now,
+// or schedules it and returns it with its id
+func (s *Simulator) DeviceAction(action fleet.Action) (fleet.Action, []fleet.Result, error) {
+
+	if err := action.Validate(); err != nil {
+		return action, nil, err
+	}
+
+	now := s.Resources.Clock.Now()
+	at := action.Time(now)
+
+	if !at.After(now) {
+		return action, s.applyAction(action), nil
+	}
+
+	s.actionsMutex.Lock()
+	defer s.actionsMutex.Unlock()
+
+	if s.actions == nil {
+		s.actions = make(map[int]*scheduledAction)
+	}
+
+	s.nextActionId++
+	action.Id = s.nextActionId
+	action.At = &at
+	action.Delay = 0
+
+	scheduled := &scheduledAction{action: action}
+	s.armAction(scheduled)
+
+	s.actions[action.Id] = scheduled
+
+	s.Print(fmt.Sprintf("Action %v scheduled at %v", action.Action, at.Format(time.RFC3339)), nil, util.PrintBoth)
+
+	return action, nil, nil
+}
+
+// GetDeviceActions returns the scheduled actions not yet applied
+func (s *Simulator) GetDeviceActions() []fleet.Action {
+
+	s.actionsMutex.Lock()
+	defer s.actionsMutex.Unlock()
+
+	actions := []fleet.Action{}
+	for _, scheduled := range s.actions {
+		actions = append(actions, scheduled.action)
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].At.Before(*actions[j].At)
+	})
+
+	return actions
+}
+
+// armAction waits the time of the action on the clock of the simulation, a virtual clock
+// doesn't move while the action is applied. Called with actionsMutex locked
+func (s *Simulator) armAction(scheduled *scheduledAction) {
+
+	action := scheduled.action
+	c := s.Resources.Clock
+
+	scheduled.timer = c.AfterFunc(clock.Until(c, *action.At), func() {
+
+		s.actionsMutex.Lock()
+		delete(s.actions, action.Id)
+		s.actionsMutex.Unlock()
+
+		c.Go(func() { s.Do(func() { s.applyAction(action) }) })
+	})
+}
+
+// rearmActions moves the scheduled actions on the clock just set
+func (s *Simulator) rearmActions() {
+
+	s.actionsMutex.Lock()
+	defer s.actionsMutex.Unlock()
+
+	for _, scheduled := range s.actions {
+		if scheduled.timer.Stop() { // not being applied
+			s.armAction(scheduled)
+		}
+	}
+}
+
+// CancelDeviceAction removes a scheduled action
+func (s *Simulator) CancelDeviceAction(Id int) bool {
+
+	s.actionsMutex.Lock()
+	defer s.actionsMutex.Unlock()
+
+	scheduled, ok := s.actions[Id]
+	if !ok || !scheduled.timer.Stop() {
+		return false
+	}
+
+	delete(s.actions, Id)
+
+	return true
+}
+
+func (s *Simulator) applyAction(action fleet.Action) []fleet.Result {
+
+	var results []fleet.Result
+
+	for _, id := range s.selectDevices(action.Selection) {
+		results = append(results, s.deviceAction(id, action.Action))
+	}
+
+	s.saveDevices()
+	s.Print(fmt.Sprintf("Action %v applied to %v devices", action.Action, len(results)), nil, util.PrintBoth)
+
+	return results
+}
+
+func (s *Simulator) deviceAction(Id int, action string) fleet.Result {
+
+	d := s.Devices[Id]
+	on := d.IsOn()
+
+	result := fleet.Result{
+		Id:     Id,
+		Name:   d.Info.Name,
+		Code:   codes.CodeOK,
+		Status: "<nil>",
+	}
+
+	switch action {
+
+	case fleet.ActionReboot:
+
+		if !on {
+			result.Code = codes.CodeErrorDeviceOff
+			result.Status = "Device is turned off"
+			break
+		}
+
+		if !s.turnOFFDevice(Id) {
+			result.Code = codes.CodeErrorDeviceActive
+			result.Status = "Device didn't exit within the stop timeout, left off"
+			break
+		}
+
+		d.Reboot()
+		s.turnONDevice(Id)
+
+	case fleet.ActionFactoryReset:
+
+		if on && !s.turnOFFDevice(Id) {
+			result.Code = codes.CodeErrorDeviceActive
+			result.Status = "Device didn't exit within the stop timeout, left off"
+			break
+		}
+
+		d.FactoryReset()
+
+		if on {
+			s.turnONDevice(Id)
+		}
+
+	case fleet.ActionBrownOut:
+
+		if !on {
+			result.Code = codes.CodeErrorDeviceOff
+			result.Status = "Device is turned off"
+			break
+		}
+
+		d.BrownOut()
+
+	}
+
+	return result
+}
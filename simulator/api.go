@@ -1,6 +1,7 @@
 package simulator
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -13,11 +14,19 @@ import (
 	"github.com/arslab/lwnsimulator/codes"
 	"github.com/arslab/lwnsimulator/models"
 
+	"github.com/arslab/lwnsimulator/simulator/clock"
 	dev "github.com/arslab/lwnsimulator/simulator/components/device"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/handler"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/stream"
 	f "github.com/arslab/lwnsimulator/simulator/components/forwarder"
 	mfw "github.com/arslab/lwnsimulator/simulator/components/forwarder/models"
 	gw "github.com/arslab/lwnsimulator/simulator/components/gateway"
 	c "github.com/arslab/lwnsimulator/simulator/console"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/simulator/metrics"
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+	"github.com/arslab/lwnsimulator/simulator/scheduler"
 	"github.com/arslab/lwnsimulator/simulator/util"
 	"github.com/arslab/lwnsimulator/socket"
 	socketio "github.com/googollee/go-socket.io"
@@ -35,54 +44,96 @@ func GetIstance() *Simulator {
 	s.ActiveGateways = make(map[int]int)
 
 	s.Forwarder = *f.Setup()
+	s.Resources.Scheduler = scheduler.Setup()
+	s.Resources.Clock = clock.Real{}
+	s.Resources.Context = context.Background()
 
-	s.Console = c.Console{}
+	s.Console = c.New()
+
+	s.Resources.Events = events.NewBus()
+	s.Resources.Events.Attach("console", &s.Console)
+	s.Resources.Events.Attach("metrics", events.ConsumerFunc(metrics.Consume))
+	s.Resources.Events.Attach("stats", &s.Resources.Stats)
+
+	s.commands = make(chan func())
+	go s.loop()
 
 	return &s
 }
 
 func (s *Simulator) AddWebSocket(WebSocket *socketio.Conn) {
-	s.Console.SetupWebSocket(WebSocket)
-	s.Resources.AddWebSocket(WebSocket)
-	s.SetupConsole()
+	s.Console.AddWebSocket(WebSocket)
+}
+
+func (s *Simulator) RemoveWebSocket(WebSocket *socketio.Conn) {
+	s.Console.RemoveWebSocket(WebSocket)
+}
+
+// Subscribe adds a subscriber of the structured events
+func (s *Simulator) Subscribe(filter events.Filter) *events.Subscription {
+	return s.Resources.Events.Subscribe(filter)
+}
+
+func (s *Simulator) Unsubscribe(sub *events.Subscription) {
+	s.Resources.Events.Unsubscribe(sub)
 }
 
 func (s *Simulator) Run() {
 
 	s.State = util.Running
+	s.startContext()
+	s.startClock()
 	s.setup()
+	s.startReport()
 
 	s.Print("START", nil, util.PrintBoth)
+	s.publish(events.TypeState, &events.State{On: true})
 
 	for _, id := range s.ActiveGateways {
 		s.turnONGateway(id)
 	}
 
-	for _, id := range s.ActiveDevices {
-		s.turnONDevice(id)
-	}
+	s.turnONDevices()
+
+	s.startCheckpoints()
+
+	s.releaseClock()
 }
 
 func (s *Simulator) Stop() {
 
-	s.State = util.Stopped
-	s.Resources.ExitGroup.Add(len(s.ActiveGateways) + len(s.ActiveDevices) - s.ComponentsInactiveTmp)
+	s.State = util.Stopped // the load profile doesn't turn on the devices it restores
+	s.stopLoad()
+
+	s.stopRamp()
+	s.stopCheckpoints()
+
+	s.cancelContext() // every wait of the components returns
+
+	// the components still waiting the ramp-up are closed already, the ones turning off
+	// are waited too
+	var components []exiting
 
 	for _, id := range s.ActiveGateways {
 		s.Gateways[id].TurnOFF()
+		components = append(components, exiting{name: s.Gateways[id].Info.Name, gateway: true, done: s.Gateways[id].Done()})
 	}
 
 	for _, id := range s.ActiveDevices {
 		s.Devices[id].TurnOFF()
+		components = append(components, exiting{name: s.Devices[id].Info.Name, done: s.Devices[id].Done()})
 	}
 
-	s.Resources.ExitGroup.Wait()
+	s.stopReport = s.waitExit(components)
 
 	s.saveStatus()
+	s.saveReport()
+	s.stopClock()
 
 	s.Forwarder.Reset()
 
 	s.Print("STOPPED", nil, util.PrintBoth)
+	s.publish(events.TypeState, &events.State{On: false})
 
 	s.reset()
 
@@ -114,6 +165,64 @@ func (s *Simulator) SaveBridgeAddress(remoteAddr models.AddressIP) error {
 	return nil
 }
 
+func (s *Simulator) SaveRampUp(rampUp models.RampUp) error {
+
+	if rampUp.DevicesPerSecond < 0 {
+		return errors.New("Devices per second can't be negative")
+	}
+
+	s.RampUp = rampUp.DevicesPerSecond
+
+	pathDir, err := util.GetPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path := pathDir + "/simulator.json"
+	s.saveComponent(path, &s)
+
+	s.Print("Ramp-up saved", nil, util.PrintOnlyConsole)
+
+	return nil
+}
+
+func (s *Simulator) GetRampUp() models.RampUp {
+	return models.RampUp{DevicesPerSecond: s.RampUp}
+}
+
+func (s *Simulator) SaveClock(config clock.Config) error {
+
+	if s.State == util.Running {
+		return errors.New("Simulator is running, unable to change the clock")
+	}
+
+	if config.Duration < 0 {
+		return errors.New("Duration can't be negative")
+	}
+
+	if err := s.CheckClock(config); err != nil {
+		return err
+	}
+
+	s.Clock = config
+
+	pathDir, err := util.GetPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path := pathDir + "/simulator.json"
+	s.saveComponent(path, &s)
+
+	s.Print("Clock saved", nil, util.PrintOnlyConsole)
+
+	return nil
+}
+
+func (s *Simulator) GetClock() clock.Config {
+	return s.Clock
+}
+
 func (s *Simulator) GetBridgeAddress() models.AddressIP {
 
 	var rServer models.AddressIP
@@ -129,24 +238,45 @@ func (s *Simulator) GetBridgeAddress() models.AddressIP {
 	return rServer
 }
 
-func (s *Simulator) GetGateways() []gw.Gateway {
+// GetGateways returns the JSON of the selected gateways, all if the filter is empty
+func (s *Simulator) GetGateways(filter fleet.Selection) []json.RawMessage {
+
+	var gateways []json.RawMessage
 
-	var gateways []gw.Gateway
+	for id, g := range s.Gateways {
+		if filter.Empty() || s.gatewaySelected(id, filter) {
 
-	for _, g := range s.Gateways {
-		gateways = append(gateways, *g)
+			bytes, err := json.Marshal(g)
+			if err != nil {
+				s.Print("", err, util.PrintOnlyConsole)
+				continue
+			}
+
+			gateways = append(gateways, bytes)
+		}
 	}
 
 	return gateways
 
 }
 
-func (s *Simulator) GetDevices() []dev.Device {
+// GetDevices returns the JSON of the selected devices, all if the filter is empty.
+// A running device returns its last snapshot
+func (s *Simulator) GetDevices(filter fleet.Selection) []json.RawMessage {
+
+	var devices []json.RawMessage
 
-	var devices []dev.Device
+	for id, d := range s.Devices {
+		if filter.Empty() || s.deviceSelected(id, filter) {
 
-	for _, d := range s.Devices {
-		devices = append(devices, *d)
+			bytes, err := json.Marshal(d)
+			if err != nil {
+				s.Print("", err, util.PrintOnlyConsole)
+				continue
+			}
+
+			devices = append(devices, bytes)
+		}
 	}
 
 	return devices
@@ -157,6 +287,8 @@ func (s *Simulator) SetGateway(gateway *gw.Gateway, update bool) (int, int, erro
 
 	emptyAddr := lorawan.EUI64{0, 0, 0, 0, 0, 0, 0, 0}
 
+	gateway.Info.Tags = fleet.NormalizeTags(gateway.Info.Tags)
+
 	if gateway.Info.MACAddress == emptyAddr {
 
 		s.Print("Error: MAC Address invalid", nil, util.PrintOnlyConsole)
@@ -235,31 +367,21 @@ func (s *Simulator) SetGateway(gateway *gw.Gateway, update bool) (int, int, erro
 }
 
 func (s *Simulator) DeleteGateway(Id int) bool {
-
-	if s.Gateways[Id].IsOn() {
-		return false
-	}
-
-	delete(s.Gateways, Id)
-	delete(s.ActiveGateways, Id)
-
-	pathDir, err := util.GetPath()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	path := pathDir + "/gateways.json"
-	s.saveComponent(path, &s.Gateways)
-
-	s.Print("Gateway Deleted", nil, util.PrintOnlyConsole)
-
-	return true
+	code, _ := s.RemoveGateway(Id)
+	return code == codes.CodeOK
 }
 
 func (s *Simulator) SetDevice(device *dev.Device, update bool) (int, int, error) {
+	return s.setDevice(device, update, true, nil)
+}
+
+// setDevice validates and stores the device, used indexes the identifiers of a bulk creation, nil searches them
+func (s *Simulator) setDevice(device *dev.Device, update bool, save bool, used *identifiers) (int, int, error) {
 
 	emptyAddr := lorawan.EUI64{0, 0, 0, 0, 0, 0, 0, 0}
 
+	device.Info.Tags = fleet.NormalizeTags(device.Info.Tags)
+
 	if device.Info.DevEUI == emptyAddr {
 
 		s.Print("DevEUI invalid", nil, util.PrintOnlyConsole)
@@ -280,7 +402,14 @@ func (s *Simulator) SetDevice(device *dev.Device, update bool) (int, int, error)
 
 	}
 
-	code, err := s.searchName(device.Info.Name, device.Id, false)
+	var code int
+	var err error
+
+	if used != nil {
+		code, err = used.searchName(device.Info.Name)
+	} else {
+		code, err = s.searchName(device.Info.Name, device.Id, false)
+	}
 	if err != nil {
 
 		s.Print("Name already used", nil, util.PrintOnlyConsole)
@@ -288,7 +417,11 @@ func (s *Simulator) SetDevice(device *dev.Device, update bool) (int, int, error)
 
 	}
 
-	code, err = s.searchAddress(device.Info.DevEUI, device.Id, false)
+	if used != nil {
+		code, err = used.searchAddress(device.Info.DevEUI)
+	} else {
+		code, err = s.searchAddress(device.Info.DevEUI, device.Id, false)
+	}
 	if err != nil {
 
 		s.Print("DevEUI already used", nil, util.PrintOnlyConsole)
@@ -296,19 +429,99 @@ func (s *Simulator) SetDevice(device *dev.Device, update bool) (int, int, error)
 
 	}
 
-	s.Devices[device.Id] = device
+	sources := 0
+	for _, set := range []bool{device.Info.Status.Generator != nil, device.Info.Status.Codec != nil,
+		device.Info.Status.DataSource != nil} {
+		if set {
+			sources++
+		}
+	}
 
-	pathDir, err := util.GetPath()
+	if sources > 1 {
+
+		s.Print("More payload sources set", nil, util.PrintOnlyConsole)
+		return codes.CodeErrorGenerator, -1, errors.New("Error: set only one of payload generator, codec and data source")
+
+	}
+
+	if device.Info.Status.Generator != nil {
+
+		err = device.Info.Status.Generator.Setup()
+		if err != nil {
+
+			s.Print("Payload generator invalid", nil, util.PrintOnlyConsole)
+			return codes.CodeErrorGenerator, -1, err
+
+		}
+
+	}
+
+	if device.Info.Status.Codec != nil {
+
+		err = device.Info.Status.Codec.Setup()
+		if err != nil {
+
+			s.Print("Codec invalid", nil, util.PrintOnlyConsole)
+			return codes.CodeErrorGenerator, -1, err
+
+		}
+
+	}
+
+	err = device.Info.Configuration.Traffic.Setup()
 	if err != nil {
-		log.Fatal(err)
+
+		s.Print("Traffic model invalid", nil, util.PrintOnlyConsole)
+		return codes.CodeErrorTraffic, -1, err
+
 	}
 
-	path := pathDir + "/devices.json"
-	s.saveComponent(path, &s.Devices)
-	path = pathDir + "/simulator.json"
-	s.saveComponent(path, &s)
+	if device.Info.Status.Schedule != nil {
 
-	s.Print("Device Saved", nil, util.PrintOnlyConsole)
+		err = device.Info.Status.Schedule.Setup(s.Resources.Clock.Now())
+		if err != nil {
+
+			s.Print("Schedule invalid", nil, util.PrintOnlyConsole)
+			return codes.CodeErrorSchedule, -1, err
+
+		}
+
+	}
+
+	err = stream.Setup(device.Info.Status.Streams, s.Resources.Clock.Now())
+	if err != nil {
+
+		s.Print("Uplink streams invalid", nil, util.PrintOnlyConsole)
+		return codes.CodeErrorStream, -1, err
+
+	}
+
+	err = device.Info.Status.Downlinks.Setup()
+	if err != nil {
+
+		s.Print("Downlink reactions invalid", nil, util.PrintOnlyConsole)
+		return codes.CodeErrorReaction, -1, err
+
+	}
+
+	if device.Info.Status.DataSource != nil {
+
+		err = device.Info.Status.DataSource.Load()
+		if err != nil {
+
+			s.Print("Data source invalid", nil, util.PrintOnlyConsole)
+			return codes.CodeErrorGenerator, -1, err
+
+		}
+
+	}
+
+	s.Devices[device.Id] = device
+
+	if save {
+		s.saveDevices()
+		s.Print("Device Saved", nil, util.PrintOnlyConsole)
+	}
 
 	if device.Info.Status.Active {
 
@@ -329,6 +542,11 @@ func (s *Simulator) SetDevice(device *dev.Device, update bool) (int, int, error)
 }
 
 func (s *Simulator) DeleteDevice(Id int) bool {
+	code, _ := s.RemoveDevice(Id)
+	return code == codes.CodeOK
+}
+
+func (s *Simulator) deleteDevice(Id int) bool {
 
 	if s.Devices[Id].IsOn() {
 		return false
@@ -337,21 +555,15 @@ func (s *Simulator) DeleteDevice(Id int) bool {
 	delete(s.Devices, Id)
 	delete(s.ActiveDevices, Id)
 
-	pathDir, err := util.GetPath()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	path := pathDir + "/devices.json"
-	s.saveComponent(path, &s.Devices)
-
-	s.Print("Device Deleted", nil, util.PrintOnlyConsole)
-
 	return true
 }
 
 func (s *Simulator) ToggleStateDevice(Id int) {
 
+	if s.State != util.Running { // turned on at the start with the others
+		return
+	}
+
 	if s.Devices[Id].State == util.Stopped {
 		s.turnONDevice(Id)
 	} else if s.Devices[Id].State == util.Running {
@@ -413,11 +625,35 @@ func (s *Simulator) SendUplink(pl socket.NewPayload) {
 		MType = lorawan.ConfirmedDataUp
 	}
 
-	s.Devices[pl.Id].NewUplink(MType, pl.Payload)
+	if pl.FPort > 223 {
+		s.Console.PrintSocket(socket.EventResponseCommand, "FPort must be from 1 to 223")
+		return
+	}
+
+	s.Devices[pl.Id].NewUplink(MType, pl.FPort, pl.Payload)
 
 	s.Console.PrintSocket(socket.EventResponseCommand, "Uplink queued")
 }
 
+func (s *Simulator) TriggerUplink(pl socket.NewPayload) bool {
+
+	device, ok := s.Devices[pl.Id]
+	if !ok || !device.IsOn() || pl.FPort > 223 {
+		return false
+	}
+
+	MType := lorawan.UnconfirmedDataUp
+	if pl.MType == "ConfirmedDataUp" {
+		MType = lorawan.ConfirmedDataUp
+	}
+
+	device.TriggerUplink(MType, pl.FPort, pl.Payload)
+
+	s.Console.PrintSocket(socket.EventResponseCommand, device.Info.Name+": Uplink triggered")
+
+	return true
+}
+
 func (s *Simulator) ChangeLocation(l socket.NewLocation) bool {
 
 	if !s.Devices[l.Id].IsOn() {
@@ -427,9 +663,13 @@ func (s *Simulator) ChangeLocation(l socket.NewLocation) bool {
 	s.Devices[l.Id].ChangeLocation(l.Latitude, l.Longitude, l.Altitude)
 
 	info := mfw.InfoDevice{
-		DevEUI:   s.Devices[l.Id].Info.DevEUI,
-		Location: s.Devices[l.Id].Info.Location,
-		Range:    s.Devices[l.Id].Info.Configuration.Range,
+		DevEUI: s.Devices[l.Id].Info.DevEUI,
+		Location: loc.Location{
+			Latitude:  l.Latitude,
+			Longitude: l.Longitude,
+			Altitude:  l.Altitude,
+		},
+		Range: s.Devices[l.Id].Info.Configuration.Range,
 	}
 
 	s.Forwarder.UpdateDevice(info)
@@ -437,9 +677,29 @@ func (s *Simulator) ChangeLocation(l socket.NewLocation) bool {
 	return true
 }
 
+func (s *Simulator) GetDownlinks(Id int) ([]handler.Downlink, error) {
+
+	d, ok := s.Devices[Id]
+	if !ok {
+		return nil, errors.New("Device not found")
+	}
+
+	return d.GetDownlinks(), nil
+}
+
 func (s *Simulator) ToggleStateGateway(Id int) {
 
+	if s.State != util.Running {
+		return
+	}
+
 	if s.Gateways[Id].State == util.Stopped {
+
+		if s.Resources.Clock.Virtual() {
+			s.Print("", errors.New("Virtual clock not supported with active gateways"), util.PrintOnlyConsole)
+			return
+		}
+
 		s.turnONGateway(Id)
 	} else {
 		s.turnOFFGateway(Id)
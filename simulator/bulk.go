@@ -0,0 +1,420 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/arslab/lwnsimulator/codes"
+	dev "github.com/arslab/lwnsimulator/simulator/components/device"
+	rp "github.com/arslab/lwnsimulator/simulator/components/device/regional_parameters"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+	"github.com/arslab/lwnsimulator/simulator/util"
+	"github.com/brocaar/lorawan"
+)
+
+// identifiers are the names and the addresses in use, indexed once for a bulk creation instead
+// of scanning all the components for each new device
+type identifiers struct {
+	names     map[string]bool
+	addresses map[lorawan.EUI64]bool
+}
+
+func (s *Simulator) identifiers() *identifiers {
+
+	used := &identifiers{
+		names:     make(map[string]bool, len(s.Devices)+len(s.Gateways)),
+		addresses: make(map[lorawan.EUI64]bool, len(s.Devices)+len(s.Gateways)),
+	}
+
+	for _, g := range s.Gateways {
+		used.add(g.Info.Name, g.Info.MACAddress)
+	}
+
+	for _, d := range s.Devices {
+		used.add(d.Info.Name, d.Info.DevEUI)
+	}
+
+	return used
+}
+
+func (ids *identifiers) add(Name string, address lorawan.EUI64) {
+	ids.names[Name] = true
+	ids.addresses[address] = true
+}
+
+func (ids *identifiers) searchName(Name string) (int, error) {
+
+	if ids.names[Name] {
+		return codes.CodeErrorName, errors.New("Error: Name already used")
+	}
+
+	return codes.CodeOK, nil
+}
+
+func (ids *identifiers) searchAddress(address lorawan.EUI64) (int, error) {
+
+	if ids.addresses[address] {
+		return codes.CodeErrorAddress, errors.New("Error: DevEUI already used")
+	}
+
+	return codes.CodeOK, nil
+}
+
+func (s *Simulator) loadTemplates() {
+
+	s.Templates = make(map[string]fleet.Template)
+
+	path, err := util.GetPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path += "/templates.json"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return
+	}
+
+	err = util.RecoverConfigFile(path, &s.Templates)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+}
+
+func (s *Simulator) saveTemplates() {
+
+	pathDir, err := util.GetPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.saveComponent(pathDir+"/templates.json", &s.Templates)
+}
+
+func (s *Simulator) GetTemplates() []fleet.Template {
+
+	var templates []fleet.Template
+
+	for _, t := range s.Templates {
+		templates = append(templates, t)
+	}
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	return templates
+}
+
+func (s *Simulator) SaveTemplate(template fleet.Template) error {
+
+	if err := template.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkDevice(template.Device); err != nil {
+		return err
+	}
+
+	var device dev.Device
+	if err := json.Unmarshal(template.Device, &device); err != nil {
+		return err
+	}
+
+	s.Templates[template.Name] = template
+	s.saveTemplates()
+
+	s.Print("Template "+template.Name+" saved", nil, util.PrintOnlyConsole)
+
+	return nil
+}
+
+func (s *Simulator) DeleteTemplate(Name string) bool {
+
+	if _, ok := s.Templates[Name]; !ok {
+		return false
+	}
+
+	delete(s.Templates, Name)
+	s.saveTemplates()
+
+	s.Print("Template "+Name+" deleted", nil, util.PrintOnlyConsole)
+
+	return true
+}
+
+// BulkAddDevices creates the devices from a template, it stops at the first error
+func (s *Simulator) BulkAddDevices(req fleet.Create) ([]fleet.Result, error) {
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	template, ok := s.Templates[req.Template]
+	if !ok {
+		return nil, fmt.Errorf("Template %v not found", req.Template)
+	}
+
+	base, err := fleet.Merge(template.Device, req.Override)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkDevice(base); err != nil {
+		return nil, err
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+
+	keys, err := fleet.NewKeys(req.Keys, req.RootKey, random)
+	if err != nil {
+		return nil, err
+	}
+
+	var center loc.Location
+	if req.Placement.Gateway != nil {
+
+		g, ok := s.Gateways[*req.Placement.Gateway]
+		if !ok {
+			return nil, errors.New("Gateway not found")
+		}
+
+		center = g.Info.Location
+	}
+
+	firstEUI, _ := fleet.ParseEUI(req.DevEUI)
+	firstAddr := random.Uint32()
+	if req.DevAddr != "" {
+		firstAddr, _ = fleet.ParseDevAddr(req.DevAddr)
+	}
+
+	var results []fleet.Result
+
+	used := s.identifiers()
+
+	for i := 0; i < req.Count; i++ {
+
+		device := dev.Device{}
+		if err = json.Unmarshal(base, &device); err != nil {
+			break
+		}
+
+		device.Info.DevEUI = fleet.EUI(firstEUI + uint64(i))
+		device.Info.Name = req.DeviceName(i, device.Info.DevEUI)
+		device.Info.Status.Active = req.Active
+
+		if device.Info.Configuration.SupportedOtaa {
+			device.Info.AppKey = keys.AppKey(device.Info.DevEUI)
+		} else {
+			device.Info.DevAddr = fleet.DevAddr(firstAddr + uint32(i))
+			device.Info.NwkSKey, device.Info.AppSKey = keys.SessionKeys(device.Info.DevEUI)
+		}
+
+		switch {
+		case len(req.Placement.Polygon) > 0:
+			device.Info.Location, err = fleet.Inside(req.Placement.Polygon, random)
+		case req.Placement.Gateway != nil:
+			device.Info.Location = fleet.Around(center, req.Placement.Radius, random)
+		}
+
+		if err != nil {
+			results = append(results, fleet.Result{
+				Id:     -1,
+				Name:   device.Info.Name,
+				Code:   codes.CodeErrorRequest,
+				Status: fmt.Sprintf("%v", err),
+			})
+			break
+		}
+
+		code, id, errSet := s.setDevice(&device, false, false, used)
+		results = append(results, fleet.Result{
+			Id:     id,
+			Name:   device.Info.Name,
+			Code:   code,
+			Status: fmt.Sprintf("%v", errSet),
+		})
+
+		if errSet != nil {
+			err = errSet
+			break
+		}
+
+		used.add(device.Info.Name, device.Info.DevEUI)
+
+	}
+
+	s.saveDevices()
+	s.Print(fmt.Sprintf("%v devices created from template %v", len(results), req.Template), nil, util.PrintBoth)
+
+	return results, err
+}
+
+// BulkUpdateDevices merges the patch over the selected devices, running devices aren't updated
+func (s *Simulator) BulkUpdateDevices(req fleet.Update) ([]fleet.Result, error) {
+
+	var results []fleet.Result
+
+	for _, id := range s.selectDevices(req.Selection) {
+
+		result := fleet.Result{
+			Id:   id,
+			Name: s.Devices[id].Info.Name,
+			Code: codes.CodeOK,
+		}
+
+		device, err := s.patchDevice(id, req.Patch)
+		if err == nil {
+			result.Code, _, err = s.setDevice(device, true, false, nil)
+		} else {
+			result.Code = codes.CodeErrorPatch
+		}
+
+		result.Status = fmt.Sprintf("%v", err)
+		results = append(results, result)
+	}
+
+	s.saveDevices()
+	s.Print(fmt.Sprintf("Bulk update of %v devices", len(results)), nil, util.PrintBoth)
+
+	return results, nil
+}
+
+// BulkDeleteDevices removes the selected devices, running devices aren't deleted
+func (s *Simulator) BulkDeleteDevices(req fleet.Delete) []fleet.Result {
+
+	var results []fleet.Result
+
+	for _, id := range s.selectDevices(req.Selection) {
+
+		result := fleet.Result{
+			Id:     id,
+			Name:   s.Devices[id].Info.Name,
+			Code:   codes.CodeOK,
+			Status: "<nil>",
+		}
+
+		if !s.deleteDevice(id) {
+			result.Code = codes.CodeErrorDeviceActive
+			result.Status = "Device is running, unable delete"
+		}
+
+		results = append(results, result)
+	}
+
+	s.saveDevices()
+	s.Print(fmt.Sprintf("Bulk delete of %v devices", len(results)), nil, util.PrintBoth)
+
+	return results
+}
+
+// checkDevice verifies the partial device has a supported region and both RX windows
+func checkDevice(device []byte) error {
+
+	partial := struct {
+		Info struct {
+			Configuration struct {
+				Region *int `json:"region"`
+			} `json:"configuration"`
+			RX []json.RawMessage `json:"rxs"`
+		} `json:"info"`
+	}{}
+
+	if err := json.Unmarshal(device, &partial); err != nil {
+		return err
+	}
+
+	region := partial.Info.Configuration.Region
+	if region == nil || *region < rp.Code_Eu868 || *region > rp.Code_Ru864 {
+		return errors.New("Device requires a supported info.configuration.region")
+	}
+
+	if len(partial.Info.RX) != 2 {
+		return errors.New("Device requires info.rxs with RX1 and RX2")
+	}
+
+	return nil
+}
+
+// ForceRejoin discards the sessions of the selected devices: OTAA devices join again
+// now if running, at the next start otherwise
+func (s *Simulator) ForceRejoin(selection fleet.Selection) []fleet.Result {
+
+	var results []fleet.Result
+
+	for _, id := range s.selectDevices(selection) {
+
+		s.Devices[id].ForceRejoin()
+
+		results = append(results, fleet.Result{
+			Id:     id,
+			Name:   s.Devices[id].Info.Name,
+			Code:   codes.CodeOK,
+			Status: "<nil>",
+		})
+	}
+
+	s.saveDevices()
+	s.Print(fmt.Sprintf("Rejoin forced for %v devices", len(results)), nil, util.PrintBoth)
+
+	return results
+}
+
+func (s *Simulator) selectDevices(selection fleet.Selection) []int {
+
+	var ids []int
+
+	for id := range s.Devices {
+		if s.deviceSelected(id, selection) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+// deviceSelected is true if the device matches the selection or its group
+func (s *Simulator) deviceSelected(Id int, selection fleet.Selection) bool {
+
+	d := s.Devices[Id]
+	if selection.Match(Id, d.Info.Name, d.Info.Tags) {
+		return true
+	}
+
+	group, ok := s.Groups[selection.Group]
+
+	return ok && group.Devices.Match(Id, d.Info.Name, d.Info.Tags)
+}
+
+func (s *Simulator) patchDevice(Id int, patch json.RawMessage) (*dev.Device, error) {
+
+	current, err := json.Marshal(s.Devices[Id])
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := fleet.Merge(current, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkDevice(merged); err != nil {
+		return nil, err
+	}
+
+	device := dev.Device{}
+	if err := json.Unmarshal(merged, &device); err != nil {
+		return nil, err
+	}
+
+	device.Id = Id
+
+	return &device, nil
+}
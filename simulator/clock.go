@@ -0,0 +1,87 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/clock"
+	"github.com/arslab/lwnsimulator/simulator/util"
+)
+
+// CheckClock returns an error if the clock is virtual while the simulation exchanges frames with
+// a real network: the gateway bridge and the gateways run in wall time
+func (s *Simulator) CheckClock(config clock.Config) error {
+
+	if !config.Virtual {
+		return nil
+	}
+
+	if s.BridgeAddress != "" {
+		return errors.New("Virtual clock not supported with a gateway bridge configured")
+	}
+
+	if len(s.ActiveGateways) > 0 {
+		return errors.New("Virtual clock not supported with active gateways")
+	}
+
+	return nil
+}
+
+// startClock sets the clock of the run: a virtual clock is held until all the
+// components have been turned on
+func (s *Simulator) startClock() {
+
+	var c clock.Clock = clock.Real{}
+
+	if s.Clock.Virtual {
+		c = clock.NewVirtual(time.Now())
+		c.Hold()
+	}
+
+	s.setClock(c)
+}
+
+// releaseClock lets a virtual clock move, it stops the run after the duration configured
+func (s *Simulator) releaseClock() {
+
+	c := s.Resources.Clock
+	if !c.Virtual() {
+		return
+	}
+
+	if s.Clock.Duration > 0 {
+
+		c.AfterFunc(s.Clock.Duration, func() {
+			go s.Do(func() {
+				if s.State == util.Running {
+					s.Stop()
+				}
+			})
+		})
+
+		s.Print(fmt.Sprintf("Virtual time, stop after %v", s.Clock.Duration), nil, util.PrintBoth)
+
+	} else {
+		s.Print("Virtual time", nil, util.PrintBoth)
+	}
+
+	c.Release()
+}
+
+// stopClock closes a virtual clock, the components are stopped
+func (s *Simulator) stopClock() {
+
+	if v, ok := s.Resources.Clock.(*clock.Virtual); ok {
+		v.Close()
+	}
+
+	s.setClock(clock.Real{})
+}
+
+func (s *Simulator) setClock(c clock.Clock) {
+	s.Resources.Clock = c
+	s.Resources.Scheduler.SetClock(c)
+	s.Forwarder.Clock = c
+	s.rearmActions()
+}
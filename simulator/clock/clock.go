@@ -0,0 +1,83 @@
+package clock
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Clock is the time of the simulation: the wall clock or a virtual clock
+type Clock interface {
+	Now() time.Time
+
+	// Sleep waits d, it returns false if cancel is closed first. A nil cancel never closes
+	Sleep(d time.Duration, cancel <-chan struct{}) bool
+
+	// AfterFunc calls f after d on the clock's goroutine, f must not block
+	AfterFunc(d time.Duration, f func()) Timer
+
+	// Go runs f on a new goroutine, a virtual clock doesn't move while f is running
+	Go(f func())
+
+	// Hold stops a virtual clock until Release, while the caller schedules the simulation
+	Hold()
+	Release()
+
+	Virtual() bool
+}
+
+// Timer is a function waiting on the clock
+type Timer interface {
+	// Stop cancels the call, it returns false if it has already been called
+	Stop() bool
+}
+
+// Config of the clock of the simulation
+type Config struct {
+	Virtual  bool          `json:"virtual"`  // discrete-event clock, as fast as the CPU allows
+	Duration time.Duration `json:"duration"` // virtual time after which the simulation stops, 0 never
+}
+
+// Since returns the time of the clock elapsed since t
+func Since(c Clock, t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Until returns the time of the clock until t
+func Until(c Clock, t time.Time) time.Duration {
+	return t.Sub(c.Now())
+}
+
+// MarshalJSON of the configuration, duration in seconds
+func (c Config) MarshalJSON() ([]byte, error) {
+
+	type Alias Config
+
+	return json.Marshal(&struct {
+		Duration float64 `json:"duration"`
+		*Alias
+	}{
+		Duration: c.Duration.Seconds(),
+		Alias:    (*Alias)(&c),
+	})
+}
+
+// UnmarshalJSON of the configuration, duration in seconds
+func (c *Config) UnmarshalJSON(data []byte) error {
+
+	type Alias Config
+
+	aux := &struct {
+		Duration float64 `json:"duration"`
+		*Alias
+	}{
+		Alias: (*Alias)(c),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	c.Duration = time.Duration(aux.Duration * float64(time.Second))
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package clock
+
+import "time"
+
+// Real is the wall clock
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) Sleep(d time.Duration, cancel <-chan struct{}) bool {
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+
+	case <-timer.C:
+		return true
+
+	case <-cancel:
+		return false
+	}
+}
+
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (Real) Go(f func()) {
+	go f()
+}
+
+func (Real) Hold() {}
+
+func (Real) Release() {}
+
+func (Real) Virtual() bool {
+	return false
+}
@@ -0,0 +1,230 @@
+package clock
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Virtual is a discrete-event clock: when no goroutine started with Go is running, the time
+// jumps to the next sleep or function waiting, so the simulation runs as fast as the CPU allows.
+// Only the goroutines of the simulation must wait on it, a network server in real time can't
+type Virtual struct {
+	now    time.Time
+	active int // goroutines running, the time moves only at 0
+	queue  events
+	seq    uint64
+	closed bool
+
+	mutex sync.Mutex
+	moved *sync.Cond // signals the goroutine moving the time
+}
+
+type event struct {
+	at    time.Time
+	seq   uint64
+	f     func()        // AfterFunc
+	wake  chan struct{} // Sleep
+	index int
+}
+
+type virtualTimer struct {
+	clock *Virtual
+	event *event
+}
+
+// NewVirtual returns a virtual clock starting at start
+func NewVirtual(start time.Time) *Virtual {
+
+	v := &Virtual{now: start}
+	v.moved = sync.NewCond(&v.mutex)
+
+	go v.run()
+
+	return v
+}
+
+// Close stops the clock, the goroutines still sleeping are never woken up
+func (v *Virtual) Close() {
+
+	v.mutex.Lock()
+	v.closed = true
+	v.moved.Signal()
+	v.mutex.Unlock()
+}
+
+func (v *Virtual) Now() time.Time {
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return v.now
+}
+
+func (v *Virtual) Sleep(d time.Duration, cancel <-chan struct{}) bool {
+
+	e := &event{wake: make(chan struct{})}
+
+	v.mutex.Lock()
+	v.push(e, d)
+	v.active-- // the caller was running
+	v.moved.Signal()
+	v.mutex.Unlock()
+
+	select {
+
+	case <-e.wake: // counted as running by the clock
+		return true
+
+	case <-cancel:
+		v.mutex.Lock()
+		if e.index >= 0 {
+			heap.Remove(&v.queue, e.index)
+			v.active++
+		}
+		v.mutex.Unlock()
+
+		return false
+	}
+}
+
+func (v *Virtual) AfterFunc(d time.Duration, f func()) Timer {
+
+	e := &event{f: f}
+
+	v.mutex.Lock()
+	v.push(e, d)
+	v.moved.Signal()
+	v.mutex.Unlock()
+
+	return &virtualTimer{clock: v, event: e}
+}
+
+func (v *Virtual) Go(f func()) {
+
+	v.Hold()
+
+	go func() {
+		defer v.Release()
+		f()
+	}()
+}
+
+func (v *Virtual) Hold() {
+
+	v.mutex.Lock()
+	v.active++
+	v.mutex.Unlock()
+}
+
+func (v *Virtual) Release() {
+
+	v.mutex.Lock()
+	v.active--
+	v.moved.Signal()
+	v.mutex.Unlock()
+}
+
+func (v *Virtual) Virtual() bool {
+	return true
+}
+
+func (t *virtualTimer) Stop() bool {
+
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	if t.event.index < 0 {
+		return false
+	}
+
+	heap.Remove(&t.clock.queue, t.event.index)
+
+	return true
+}
+
+// push queues the event after d, called with the mutex locked
+func (v *Virtual) push(e *event, d time.Duration) {
+
+	if d < 0 {
+		d = 0
+	}
+
+	v.seq++
+	e.at = v.now.Add(d)
+	e.seq = v.seq
+
+	heap.Push(&v.queue, e)
+}
+
+func (v *Virtual) run() {
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for {
+
+		for !v.closed && (v.active > 0 || len(v.queue) == 0) {
+			v.moved.Wait()
+		}
+
+		if v.closed {
+			return
+		}
+
+		e := heap.Pop(&v.queue).(*event)
+		if e.at.After(v.now) {
+			v.now = e.at
+		}
+
+		if e.wake != nil {
+			v.active++
+			close(e.wake)
+			continue
+		}
+
+		v.mutex.Unlock()
+		e.f()
+		v.mutex.Lock()
+	}
+}
+
+// events is a min-heap by time
+type events []*event
+
+func (q events) Len() int { return len(q) }
+
+func (q events) Less(i, j int) bool {
+
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+
+	return q[i].at.Before(q[j].at)
+}
+
+func (q events) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *events) Push(x interface{}) {
+	e := x.(*event)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *events) Pop() interface{} {
+
+	old := *q
+	n := len(old)
+
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+
+	*q = old[:n-1]
+
+	return e
+}
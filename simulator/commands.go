@@ -0,0 +1,199 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brocaar/lorawan"
+
+	"github.com/arslab/lwnsimulator/codes"
+	mfw "github.com/arslab/lwnsimulator/simulator/components/forwarder/models"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+	"github.com/arslab/lwnsimulator/simulator/util"
+)
+
+var macCommands = map[string]lorawan.CID{
+	"DeviceTimeReq":   lorawan.DeviceTimeReq,
+	"LinkCheckReq":    lorawan.LinkCheckReq,
+	"PingSlotInfoReq": lorawan.PingSlotInfoReq,
+}
+
+// DevicesCommand applies the command to every selected device
+func (s *Simulator) DevicesCommand(cmd fleet.Command) ([]fleet.Result, error) {
+
+	if err := cmd.Validate(false); err != nil {
+		return nil, err
+	}
+
+	var results []fleet.Result
+
+	for _, id := range s.selectDevices(cmd.Selection) {
+		results = append(results, s.deviceCommand(id, cmd))
+	}
+
+	s.saveDevices()
+	s.Print(fmt.Sprintf("Command %v applied to %v devices", cmd.Command, len(results)), nil, util.PrintBoth)
+
+	return results, nil
+}
+
+// GatewaysCommand applies the command to every selected gateway
+func (s *Simulator) GatewaysCommand(cmd fleet.Command) ([]fleet.Result, error) {
+
+	if err := cmd.Validate(true); err != nil {
+		return nil, err
+	}
+
+	var results []fleet.Result
+
+	for _, id := range s.selectGateways(cmd.Selection) {
+		results = append(results, s.gatewayCommand(id, cmd))
+	}
+
+	s.saveStatus()
+	s.Print(fmt.Sprintf("Command %v applied to %v gateways", cmd.Command, len(results)), nil, util.PrintBoth)
+
+	return results, nil
+}
+
+func (s *Simulator) deviceCommand(Id int, cmd fleet.Command) fleet.Result {
+
+	d := s.Devices[Id]
+
+	result := fleet.Result{
+		Id:     Id,
+		Name:   d.Info.Name,
+		Code:   codes.CodeOK,
+		Status: "<nil>",
+	}
+
+	switch cmd.Command {
+
+	case fleet.CommandTurnOn:
+
+		d.SetActive(true)
+		s.ActiveDevices[Id] = Id
+
+		if s.State == util.Running && !d.IsOn() {
+			s.turnONDevice(Id)
+		}
+
+	case fleet.CommandTurnOff:
+
+		d.SetActive(false)
+
+		if d.IsOn() {
+			s.turnOFFDevice(Id)
+		}
+		delete(s.ActiveDevices, Id)
+
+	case fleet.CommandPayload:
+
+		mtype := lorawan.UnconfirmedDataUp
+		if cmd.MType == "ConfirmedDataUp" {
+			mtype = lorawan.ConfirmedDataUp
+		}
+
+		d.ChangePayload(mtype, &lorawan.DataPayload{Bytes: []byte(cmd.Payload)})
+
+	case fleet.CommandInterval:
+		d.SetSendInterval(time.Duration(cmd.Interval * float64(time.Second)))
+
+	case fleet.CommandMACCommand:
+
+		cid, ok := macCommands[cmd.CID]
+
+		switch {
+
+		case !d.IsOn():
+			result.Code = codes.CodeErrorDeviceOff
+			result.Status = "Device is turned off"
+
+		case !ok:
+			result.Code = codes.CodeErrorCommand
+			result.Status = "MAC command " + cmd.CID + " not supported"
+
+		default:
+			if err := d.SendMACCommand(cid, cmd.Periodicity); err != nil {
+				result.Code = codes.CodeErrorCommand
+				result.Status = err.Error()
+			}
+
+		}
+
+	case fleet.CommandMove:
+
+		d.ChangeLocation(cmd.Latitude, cmd.Longitude, cmd.Altitude)
+
+		if d.IsOn() {
+			s.Forwarder.UpdateDevice(mfw.InfoDevice{
+				DevEUI: d.Info.DevEUI,
+				Location: loc.Location{
+					Latitude:  cmd.Latitude,
+					Longitude: cmd.Longitude,
+					Altitude:  cmd.Altitude,
+				},
+				Range: d.Info.Configuration.Range,
+			})
+		}
+
+	case fleet.CommandReboot:
+		result = s.deviceAction(Id, fleet.ActionReboot)
+
+	}
+
+	return result
+}
+
+func (s *Simulator) gatewayCommand(Id int, cmd fleet.Command) fleet.Result {
+
+	g := s.Gateways[Id]
+
+	result := fleet.Result{
+		Id:     Id,
+		Name:   g.Info.Name,
+		Code:   codes.CodeOK,
+		Status: "<nil>",
+	}
+
+	switch cmd.Command {
+
+	case fleet.CommandTurnOn:
+
+		g.Info.Active = true
+		s.ActiveGateways[Id] = Id
+
+		if s.State == util.Running && !g.IsOn() {
+			s.turnONGateway(Id)
+		}
+
+	case fleet.CommandTurnOff:
+
+		g.Info.Active = false
+
+		if g.IsOn() {
+			s.turnOFFGateway(Id)
+		}
+		delete(s.ActiveGateways, Id)
+
+	case fleet.CommandMove:
+
+		g.ChangeLocation(loc.Location{
+			Latitude:  cmd.Latitude,
+			Longitude: cmd.Longitude,
+			Altitude:  cmd.Altitude,
+		})
+
+		if g.IsOn() {
+			s.Forwarder.UpdateGateway(mfw.InfoGateway{
+				MACAddress: g.Info.MACAddress,
+				Buffer:     &g.BufferUplink,
+				Location:   g.Info.Location,
+			})
+		}
+
+	}
+
+	return result
+}
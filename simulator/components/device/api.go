@@ -2,12 +2,19 @@ package device
 
 import (
 	"errors"
-	"sync"
+	"fmt"
+	"time"
 
 	"github.com/arslab/lwnsimulator/simulator/components/device/classes"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/backoff"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/beacon"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/handler"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/stream"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/traffic"
 	mup "github.com/arslab/lwnsimulator/simulator/components/device/frames/uplink/models"
 	f "github.com/arslab/lwnsimulator/simulator/components/forwarder"
 	c "github.com/arslab/lwnsimulator/simulator/console"
+	"github.com/arslab/lwnsimulator/simulator/events"
 	res "github.com/arslab/lwnsimulator/simulator/resources"
 	"github.com/arslab/lwnsimulator/simulator/util"
 	"github.com/brocaar/lorawan"
@@ -17,8 +24,6 @@ func (d *Device) Setup(Resources *res.Resources, forwarder *f.Forwarder) {
 
 	d.State = util.Stopped
 
-	d.Exit = make(chan struct{})
-
 	d.Info.JoinEUI = lorawan.EUI64{0, 0, 0, 0, 0, 0, 0, 0}
 	d.Info.NetID = lorawan.NetID{0, 0, 0}
 
@@ -35,6 +40,20 @@ func (d *Device) Setup(Resources *res.Resources, forwarder *f.Forwarder) {
 	}
 
 	d.Info.Configuration.Region.Setup()
+
+	d.Info.Configuration.Timing.DataRate = d.Info.Configuration.Region.GetDataRate
+	for i := range d.Info.RX {
+		d.Info.RX[i].Timing = &d.Info.Configuration.Timing
+	}
+	if d.Info.Status.InfoClassB.PingSlot.GetListeningFrequency() == 0 { // the ping slot of the region
+		periodicity := d.Info.Status.InfoClassB.Periodicity
+		d.Info.Status.InfoClassB = d.Info.Configuration.Region.GetParameters().InfoClassB
+		d.Info.Status.InfoClassB.Periodicity = periodicity
+	}
+	if d.Info.Status.InfoClassB.PingSlot.DurationOpen == 0 {
+		d.Info.Status.InfoClassB.PingSlot.DurationOpen = beacon.SlotLen
+	}
+	d.Info.Status.InfoClassB.PingSlot.Timing = &d.Info.Configuration.Timing
 	d.Info.Status.DataUplink.ADR.Setup(d.Info.Configuration.SupportedADR)
 
 	d.Info.Status.DataUplink.DwellTime = lorawan.DwellTime400ms
@@ -47,23 +66,78 @@ func (d *Device) Setup(Resources *res.Resources, forwarder *f.Forwarder) {
 	d.Info.Status.InfoChannelsUS915.ListChannelsLastPass = [8]int{-1, -1, -1, -1, -1, -1, -1, -1}
 
 	d.Info.Status.CounterRepUnConfirmedDataUp = 1
-	d.Info.Configuration.NbRepUnconfirmedDataUp = 1
 
-	//class C
-	if d.Info.Configuration.SupportedClassC {
-		d.Info.Status.InfoClassC.Setup()
+	if err := d.Info.Configuration.Backoff.Setup(); err != nil {
+		d.Print("", err, util.PrintBoth)
+		d.Info.Configuration.Backoff = backoff.Policy{}
+		d.Info.Configuration.Backoff.Setup()
 	}
+	d.Info.Configuration.NbRepUnconfirmedDataUp = d.Info.Configuration.Backoff.Retransmission.InitialNbTrans()
 
 	d.Resources = Resources
 	d.Info.Forwarder = forwarder
 
-	d.Info.ReceivedDownlink.Notify = sync.NewCond(&d.Info.ReceivedDownlink.Mutex)
+	d.Info.ReceivedDownlink.SetHandler(func() { d.dispatch(eventDownlink) })
 
 	d.Info.Configuration.Channels = d.Info.Configuration.Region.GetChannels()
 
 	d.Class = classes.GetClass(classes.ClassA)
 	d.Class.Setup(&d.Info)
 
+	if d.Info.Session != nil { // the DevNonce is in NVM, the next join goes on counting
+		d.Info.DevNonce = d.Info.Session.DevNonce
+	}
+
+	if d.Info.Session != nil && d.Info.Session.Joined { // resume without joining again
+		d.Info.RestoreSession(d.Info.Session)
+		d.Info.Status.Mode = util.Normal
+		d.Print("Session restored", nil, util.PrintOnlyConsole)
+	}
+
+	if err := d.Info.Configuration.Traffic.Setup(); err != nil {
+		d.Print("", err, util.PrintBoth)
+		d.Info.Configuration.Traffic = traffic.Traffic{}
+		d.Info.Configuration.Traffic.Setup()
+	}
+
+	if d.Info.Status.Generator != nil {
+		if err := d.Info.Status.Generator.Setup(); err != nil {
+			d.Print("", err, util.PrintBoth)
+			d.Info.Status.Generator = nil
+		}
+	}
+
+	if d.Info.Status.Codec != nil {
+		if err := d.Info.Status.Codec.Setup(); err != nil {
+			d.Print("", err, util.PrintBoth)
+			d.Info.Status.Codec = nil
+		}
+	}
+
+	if err := d.Info.Status.Downlinks.Setup(); err != nil {
+		d.Print("", err, util.PrintBoth)
+		d.Info.Status.Downlinks.Reactions = nil
+	}
+
+	if d.Info.Status.DataSource != nil {
+		if err := d.Info.Status.DataSource.Load(); err != nil {
+			d.Print("", err, util.PrintBoth)
+			d.Info.Status.DataSource = nil
+		}
+	}
+
+	if d.Info.Status.Schedule != nil {
+		if err := d.Info.Status.Schedule.Setup(d.now()); err != nil {
+			d.Print("", err, util.PrintBoth)
+			d.Info.Status.Schedule = nil
+		}
+	}
+
+	if err := stream.Setup(d.Info.Status.Streams, d.now()); err != nil {
+		d.Print("", err, util.PrintBoth)
+		d.Info.Status.Streams = nil
+	}
+
 	d.Print("Setup OK!", nil, util.PrintOnlyConsole)
 
 }
@@ -72,27 +146,96 @@ func (d *Device) SetConsole(console *c.Console) {
 	d.Console = *console
 }
 
+// TurnOFF stops the device and cancels its events, Done is closed when the event being handled is over
 func (d *Device) TurnOFF() {
 
 	d.Mutex.Lock()
+
+	if d.State == util.Stopped {
+		d.Mutex.Unlock()
+		return
+	}
+
 	d.State = util.Stopped
+
+	d.cancelTasks()
+	busy := d.busy
+	d.offPending = busy
+
 	d.Mutex.Unlock()
 
-	d.Exit <- struct{}{}
+	if !busy {
+		d.turnedOff()
+	}
+
+}
+
+// Done is closed when the device has been turned off, it's closed if never turned on
+func (d *Device) Done() <-chan struct{} {
 
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+
+	if d.done == nil {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+
+	return d.done
 }
 
 func (d *Device) TurnON() {
 
+	d.Mutex.Lock()
+	d.done = make(chan struct{})
 	d.State = util.Running
+	d.Mutex.Unlock()
 
-	go d.Run()
+	d.refresh()
 
 	d.Print("Turn ON", nil, util.PrintBoth)
+	d.publish(events.TypeState, &events.State{On: true})
+
+	offset := d.Info.Configuration.Traffic.Offset(d.Info.Configuration.SendInterval)
+	if offset > 0 { // desynchronize the devices turned on together
+		d.Print(fmt.Sprintf("Start delayed of %v", offset), nil, util.PrintOnlyConsole)
+	}
+
+	d.schedule(&d.startTask, d.now().Add(offset), eventStart)
+}
+
+// ForceRejoin discards the MAC state of the saved session, an OTAA device joins again
+func (d *Device) ForceRejoin() {
+
+	d.Apply(func() {
+
+		d.Mutex.Lock()
+		d.Info.Session = d.Info.GetNVMSession(d.now())
+		d.Mutex.Unlock()
+
+		if d.IsOn() && d.UnJoined() {
+			d.Print("Rejoin forced", nil, util.PrintBoth)
+		}
+
+	})
+
+}
+
+// saveSession keeps a copy of the MAC state to resume it at the next start
+func (d *Device) saveSession() {
+
+	d.Mutex.Lock()
+	d.Info.Session = d.Info.GetSession(d.now())
+	d.Mutex.Unlock()
+
 }
 
 func (d *Device) IsOn() bool {
 
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+
 	if d.State == util.Running {
 		return true
 	}
@@ -118,8 +261,6 @@ func (d *Device) SendMACCommand(cid lorawan.CID, periodicity uint8) error {
 				},
 			},
 		}
-		d.Info.Status.InfoClassB.Periodicity = periodicity
-
 	} else {
 
 		command = []lorawan.Payload{
@@ -130,12 +271,21 @@ func (d *Device) SendMACCommand(cid lorawan.CID, periodicity uint8) error {
 
 	}
 
-	d.newMACComands(command)
+	d.Apply(func() {
+
+		if cid == lorawan.PingSlotInfoReq {
+			d.Info.Status.InfoClassB.Periodicity = periodicity
+		}
+
+		d.newMACComands(command)
+	})
+
+	d.publish(events.TypeMACCommand, &events.MACCommand{DevEUI: d.Info.DevEUI, CID: cid.String(), Direction: events.DirectionUplink})
 
 	return nil
 }
 
-func (d *Device) NewUplink(mtype lorawan.MType, payload string) {
+func (d *Device) NewUplink(mtype lorawan.MType, fport uint8, payload string) {
 
 	FRMPayload := &lorawan.DataPayload{
 		Bytes: []byte(payload),
@@ -143,24 +293,69 @@ func (d *Device) NewUplink(mtype lorawan.MType, payload string) {
 
 	info := mup.InfoFrame{
 		MType:   mtype,
+		FPort:   fport,
 		Payload: FRMPayload,
 	}
 
-	d.Info.Status.BufferUplinks = append(d.Info.Status.BufferUplinks, info)
+	d.Apply(func() {
+		d.Info.Status.BufferUplinks = append(d.Info.Status.BufferUplinks, info)
+	})
 
 }
 
 func (d *Device) ChangePayload(mtype lorawan.MType, payload lorawan.Payload) {
 
-	d.Info.Status.MType = mtype
-	d.Info.Status.Payload = payload
+	d.Apply(func() {
+		d.Info.Status.MType = mtype
+		d.Info.Status.Payload = payload
+	})
 
 }
 
 func (d *Device) ChangeLocation(lat float64, lng float64, alt int32) {
 
-	d.Info.Location.Latitude = lat
-	d.Info.Location.Longitude = lng
-	d.Info.Location.Altitude = alt
+	d.Apply(func() {
+		d.Info.Location.Latitude = lat
+		d.Info.Location.Longitude = lng
+		d.Info.Location.Altitude = alt
+	})
+
+}
+
+// SetActive sets if the device is turned on at the start of the simulation
+func (d *Device) SetActive(active bool) {
+
+	d.Apply(func() {
+		d.Info.Status.Active = active
+	})
+
+}
+
+// SetSendInterval changes the interval between the periodic uplinks, a shorter one
+// brings the pending uplink forward
+func (d *Device) SetSendInterval(interval time.Duration) {
+
+	d.Apply(func() {
+
+		shorter := interval < d.Info.Configuration.SendInterval
+		d.Info.Configuration.SendInterval = interval
+
+		d.Mutex.Lock()
+		pending := d.periodicTask != nil
+		d.Mutex.Unlock()
+
+		if !shorter || !pending {
+			return
+		}
+
+		if next := d.now().Add(d.nextSendInterval()); next.Before(d.next) {
+			d.next = next
+			d.schedule(&d.periodicTask, d.next, eventPeriodic)
+		}
+	})
+
+}
 
+func (d *Device) GetDownlinks() []handler.Downlink {
+	return d.Info.Status.Downlinks.GetReceived()
 }
@@ -0,0 +1,129 @@
+package device
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/codec"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/handler"
+	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
+	"github.com/arslab/lwnsimulator/simulator/util"
+	"github.com/arslab/lwnsimulator/socket"
+	"github.com/brocaar/lorawan"
+)
+
+// ExecuteApplicationPayload stores and emits the application payload of a downlink
+// and executes the reactions configured for it
+func (d *Device) ExecuteApplicationPayload(downlink dl.InformationDownlink) {
+
+	if downlink.FPort == 0 { //no application payload
+		return
+	}
+
+	received := handler.Downlink{
+		Time:      d.now(),
+		FPort:     downlink.FPort,
+		Payload:   downlink.DataPayload,
+		Confirmed: downlink.MType == lorawan.ConfirmedDataDown,
+	}
+
+	reactions := d.Info.Status.Downlinks.Receive(received)
+
+	msg := fmt.Sprintf("Application downlink on FPort %v: %v", received.FPort, hex.EncodeToString(received.Payload))
+	d.Print(msg, nil, util.PrintBoth)
+
+	d.Console.PrintSocket(socket.EventReceivedDownlink, socket.ReceivedDownlink{
+		Id:        d.Id,
+		DevEUI:    d.Info.DevEUI,
+		FPort:     received.FPort,
+		Payload:   hex.EncodeToString(received.Payload),
+		Confirmed: received.Confirmed,
+	})
+
+	d.applyCodec(received.Payload)
+
+	for _, r := range reactions {
+		d.executeReaction(r, received)
+	}
+
+}
+
+func (d *Device) applyCodec(payload []byte) {
+
+	if len(payload) == 0 || d.Info.Status.Codec == nil || d.Info.Status.Codec.Format != codec.FormatCayenneLPP {
+		return
+	}
+
+	measures, err := codec.Decode(payload)
+	if err != nil {
+		d.Print("", err, util.PrintBoth)
+		return
+	}
+
+	for _, m := range d.Info.Status.Codec.Apply(measures) {
+		msg := fmt.Sprintf("Actuator %v[%v] set to %v", m.Type, m.Channel, m.Values)
+		d.Print(msg, nil, util.PrintBoth)
+	}
+
+}
+
+func (d *Device) executeReaction(r handler.Reaction, downlink handler.Downlink) {
+
+	switch r.Action {
+
+	case handler.ActionSetInterval:
+
+		interval, err := r.Interval(downlink.Payload)
+		if err != nil {
+			d.Print("", err, util.PrintBoth)
+			return
+		}
+
+		d.Info.Configuration.SendInterval = interval
+		d.Print(fmt.Sprintf("Send interval set to %v", interval), nil, util.PrintBoth)
+
+	case handler.ActionSetPayload:
+
+		bytes, err := r.Bytes(downlink.Payload)
+		if err != nil {
+			d.Print("", err, util.PrintBoth)
+			return
+		}
+
+		d.ChangePayload(d.Info.Status.MType, &lorawan.DataPayload{Bytes: bytes})
+		d.Print("Payload changed by downlink", nil, util.PrintBoth)
+
+	case handler.ActionTogglePayload:
+
+		alternative, _ := hex.DecodeString(r.Payload)
+		current, _ := d.Info.Status.Payload.MarshalBinary()
+
+		if string(current) == string(alternative) { //toggle back
+			alternative = d.Info.Status.Downlinks.Toggled
+		}
+		d.Info.Status.Downlinks.Toggled = current
+
+		d.ChangePayload(d.Info.Status.MType, &lorawan.DataPayload{Bytes: alternative})
+		d.Print("Payload toggled by downlink", nil, util.PrintBoth)
+
+	case handler.ActionEcho:
+
+		d.NewUplink(lorawan.UnconfirmedDataUp, downlink.FPort, string(downlink.Payload))
+		d.Print("Echo queued", nil, util.PrintBoth)
+
+	case handler.ActionReboot:
+
+		d.Print("Reboot requested by downlink", nil, util.PrintBoth)
+		d.loseRAM()
+
+	case handler.ActionRejoin:
+
+		if d.UnJoined() {
+			d.Print("Rejoin requested by downlink", nil, util.PrintBoth)
+		} else {
+			d.Print("Rejoin requested by downlink, ABP device can't join", nil, util.PrintBoth)
+		}
+
+	}
+
+}
@@ -0,0 +1,91 @@
+package device
+
+import (
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/classes"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/beacon"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/arslab/lwnsimulator/simulator/util"
+	"github.com/brocaar/lorawan"
+)
+
+// schedulePing schedules the next ping slot of class B, the other classes have none. The slot
+// is timed from its beacon, so the clock drift and the window widening grow with the offset
+func (d *Device) schedulePing() {
+
+	d.ping = nil
+
+	if d.Class.GetClass() != classes.ClassB {
+		d.schedule(&d.pingTask, time.Time{}, eventPing)
+		return
+	}
+
+	pingSlot := &d.Info.Status.InfoClassB.PingSlot
+
+	start, slot := beacon.Next(d.now(), d.Info.DevAddr, d.Info.Status.InfoClassB.Periodicity)
+	open, close := pingSlot.Schedule(start, slot.Sub(start))
+
+	d.ping = &window{
+		rx:    pingSlot,
+		open:  open,
+		close: close,
+		done:  d.receivePingSlot,
+	}
+
+	d.schedule(&d.pingTask, open, eventPing)
+}
+
+// openPingSlot listens the ping slot until it closes, a slot passed while the device was
+// busy with an uplink is skipped
+func (d *Device) openPingSlot() {
+
+	w := d.ping
+	d.ping = nil
+
+	if w == nil || d.Class.GetClass() != classes.ClassB {
+		return
+	}
+
+	now := d.now()
+	if !now.Before(w.close) {
+		d.schedulePing()
+		return
+	}
+
+	if now.After(w.open) {
+		w.open = now
+	}
+
+	w.rx.LastReception = features.Reception{}
+	w.rx.Received = time.Time{}
+	w.listening = w.rx.GetListeningFrequency()
+
+	d.Info.Forwarder.Register(w.listening, d.Info.DevEUI, &d.Info.ReceivedDownlink)
+	d.rx = w
+
+	d.after(w.close.Sub(now), d.closeWindow)
+}
+
+// receivePingSlot handles the downlink received in the ping slot, nil if none, then schedules the next slot
+func (d *Device) receivePingSlot(phy *lorawan.PHYPayload) {
+
+	d.reportReception(events.WindowPing, d.Info.Status.InfoClassB.PingSlot.LastReception)
+
+	if phy != nil {
+
+		downlink, err := d.ProcessDownlink(*phy)
+		if err != nil {
+			d.Print("", err, util.PrintBoth)
+		} else if downlink != nil {
+			d.recordDownlinkIn(events.WindowPing, *downlink)
+			d.ExecuteMACCommand(*downlink)
+			d.ExecuteApplicationPayload(*downlink)
+		}
+
+		d.saveSession()
+	}
+
+	d.schedulePing()
+}
@@ -2,38 +2,38 @@ package device
 
 import (
 	"github.com/arslab/lwnsimulator/simulator/components/device/classes"
+	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
+	"github.com/arslab/lwnsimulator/simulator/events"
 	"github.com/arslab/lwnsimulator/simulator/util"
 )
 
-func (d *Device) DownlinkReceivedRX2ClassC() {
+// receiveClassC handles a downlink of the RX2 always open of class C, it's an event of the
+// device: the counters and the keys are read by the goroutine owning its state
+func (d *Device) receiveClassC() {
 
-	for d.Class.GetClass() == classes.ClassC {
-
-		if !d.CanExecute() {
-			return
-		}
-
-		d.Info.Status.InfoClassC.WaitDevice()
-
-		if !d.CanExecute() {
-			return
-		}
+	phy := d.Info.ReceivedDownlink.Take()
+	if phy == nil || d.Class.GetClass() != classes.ClassC { // taken by a receive window
+		return
+	}
 
-		d.Info.Status.InfoClassC.Mutex.Lock()
+	downlink, err := dl.GetDownlink(*phy, d.Info.Configuration.DisableFCntDown, d.Info.Status.FCntDown,
+		d.Info.NwkSKey, d.Info.AppSKey)
+	if err != nil {
+		return
+	}
 
-		downlink := d.Info.Status.InfoClassC.Downlink
+	d.recordDownlinkIn(events.WindowClassC, *downlink)
 
-		d.ExecuteMACCommand(downlink)
+	d.ExecuteMACCommand(*downlink)
+	d.ExecuteApplicationPayload(*downlink)
 
-		d.ADRProcedure()
+	d.ADRProcedure(func() {
 
 		if d.Info.Status.Mode != util.Retransmission {
-			d.FPendingProcedure(&downlink)
+			d.FPendingProcedure(downlink, d.saveSession)
+			return
 		}
 
-		d.Info.Status.InfoClassC.WakeUpClass()
-
-		d.Info.Status.InfoClassC.Mutex.Unlock()
-	}
-
+		d.saveSession()
+	})
 }
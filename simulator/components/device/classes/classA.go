@@ -8,7 +8,6 @@ import (
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
 	"github.com/arslab/lwnsimulator/simulator/util"
-	"github.com/brocaar/lorawan"
 
 	"github.com/arslab/lwnsimulator/simulator/components/device/models"
 )
@@ -21,11 +20,12 @@ func (a *TypeA) Setup(info *models.InformationDevice) {
 	a.Info = info
 }
 
-func (a *TypeA) SendData(rxpk pkt.RXPK) {
+func (a *TypeA) SendData(rxpk pkt.RXPK, sent time.Time) {
 
 	var indexChannelRX1 int
 
-	a.Info.Forwarder.Uplink(rxpk, a.Info.DevEUI)
+	tmst := a.Info.Forwarder.Uplink(rxpk, a.Info.DevEUI)
+	a.Info.ReceivedDownlink.SetReference(sent, tmst)
 
 	a.Info.RX[0].DataRate, indexChannelRX1 = a.Info.Configuration.Region.SetupRX1(
 		a.Info.Status.DataRate, a.Info.Configuration.RX1DROffset,
@@ -38,34 +38,14 @@ func (a *TypeA) SendData(rxpk pkt.RXPK) {
 	a.Info.RX[0].Channel.MaxDR = regionalChannel.MaxDR
 }
 
-func (a *TypeA) ReceiveWindows(delayRX1 time.Duration, delayRX2 time.Duration) *lorawan.PHYPayload {
-
-	for i := 0; i < 2; i++ {
-
-		var delay time.Duration
-		if i == 0 {
-			delay = delayRX1
-		} else {
-			delay = delayRX2
-		}
-
-		a.Info.Forwarder.Register(a.Info.RX[i].GetListeningFrequency(), a.Info.DevEUI, &a.Info.ReceivedDownlink)
-
-		resp := a.Info.RX[i].OpenWindow(delay, &a.Info.ReceivedDownlink)
-
-		a.Info.Forwarder.UnRegister(a.Info.RX[i].GetListeningFrequency(), a.Info.DevEUI)
-
-		if resp != nil {
-			return resp
-		}
-	}
-
-	return nil
+// Windows returns the receive windows opened after an uplink: RX1 and RX2
+func (a *TypeA) Windows() int {
+	return 2
 }
 
 func (a *TypeA) RetransmissionCData(downlink *dl.InformationDownlink) error {
 
-	if a.Info.Status.CounterRepConfirmedDataUp < a.Info.Configuration.NbRepConfirmedDataUp {
+	if a.Info.Status.CounterRepConfirmedDataUp < a.Info.Configuration.MaxConfirmedRetransmissions() {
 
 		if downlink != nil { ///downlink ricevuto
 
@@ -84,7 +64,12 @@ func (a *TypeA) RetransmissionCData(downlink *dl.InformationDownlink) error {
 
 		a.Info.Status.Mode = util.Normal
 		a.Info.Status.CounterRepConfirmedDataUp = 0
-		err := fmt.Sprintf("Last Uplink sent %v times", a.Info.Configuration.NbRepConfirmedDataUp)
+
+		if downlink != nil && downlink.ACK { // ACK on the last transmission
+			return nil
+		}
+
+		err := fmt.Sprintf("Last Uplink sent %v times", a.Info.Configuration.MaxConfirmedRetransmissions())
 
 		return errors.New(err)
 	}
@@ -122,4 +107,6 @@ func (a *TypeA) ToString() string {
 	return "A"
 }
 
+func (a *TypeA) OpenRX2() {}
+
 func (a *TypeA) CloseRX2() {}
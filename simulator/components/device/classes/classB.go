@@ -9,7 +9,6 @@ import (
 	"github.com/arslab/lwnsimulator/simulator/components/device/models"
 	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
 	"github.com/arslab/lwnsimulator/simulator/util"
-	"github.com/brocaar/lorawan"
 )
 
 const (
@@ -32,11 +31,12 @@ func (b *TypeB) Setup(info *models.InformationDevice) {
 	b.Info = info
 }
 
-func (b *TypeB) SendData(rxpk pkt.RXPK) {
+func (b *TypeB) SendData(rxpk pkt.RXPK, sent time.Time) {
 
 	var indexChannelRX1 int
 
-	b.Info.Forwarder.Uplink(rxpk, b.Info.DevEUI)
+	tmst := b.Info.Forwarder.Uplink(rxpk, b.Info.DevEUI)
+	b.Info.ReceivedDownlink.SetReference(sent, tmst)
 
 	b.Info.RX[0].DataRate, indexChannelRX1 = b.Info.Configuration.Region.SetupRX1(
 		b.Info.Status.DataRate, b.Info.Configuration.RX1DROffset,
@@ -45,36 +45,14 @@ func (b *TypeB) SendData(rxpk pkt.RXPK) {
 	b.Info.RX[0].Channel = b.Info.Configuration.Channels[indexChannelRX1]
 }
 
-func (b *TypeB) ReceiveWindows(delayRX1 time.Duration, delayRX2 time.Duration) *lorawan.PHYPayload {
-
-	for i := 0; i < 2; i++ {
-
-		var delay time.Duration
-		if i == 0 {
-			delay = delayRX1
-		} else {
-			delay = delayRX2
-		}
-
-		b.Info.Forwarder.Register(b.Info.RX[i].GetListeningFrequency(), b.Info.DevEUI, &b.Info.ReceivedDownlink)
-
-		resp := b.Info.RX[i].OpenWindow(delay, &b.Info.ReceivedDownlink)
-
-		b.Info.Forwarder.UnRegister(b.Info.RX[i].GetListeningFrequency(), b.Info.DevEUI)
-
-		if resp != nil {
-			return resp
-		}
-
-	}
-
-	return nil
-
+// Windows returns the receive windows opened after an uplink: RX1 and RX2
+func (b *TypeB) Windows() int {
+	return 2
 }
 
 func (b *TypeB) RetransmissionCData(downlink *dl.InformationDownlink) error {
 
-	if b.Info.Status.CounterRepConfirmedDataUp < b.Info.Configuration.NbRepConfirmedDataUp {
+	if b.Info.Status.CounterRepConfirmedDataUp < b.Info.Configuration.MaxConfirmedRetransmissions() {
 
 		if downlink != nil { ///downlink ricevuto
 
@@ -94,7 +72,12 @@ func (b *TypeB) RetransmissionCData(downlink *dl.InformationDownlink) error {
 
 		b.Info.Status.Mode = util.Normal
 		b.Info.Status.CounterRepConfirmedDataUp = 0
-		err := fmt.Sprintf("Last Uplink sent %v times", b.Info.Configuration.NbRepConfirmedDataUp)
+
+		if downlink != nil && downlink.ACK { // ACK on the last transmission
+			return nil
+		}
+
+		err := fmt.Sprintf("Last Uplink sent %v times", b.Info.Configuration.MaxConfirmedRetransmissions())
 
 		return errors.New(err)
 
@@ -138,4 +121,6 @@ func (b *TypeB) ToString() string {
 	return "B"
 }
 
+func (b *TypeB) OpenRX2() {}
+
 func (b *TypeB) CloseRX2() {}
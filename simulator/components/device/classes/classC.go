@@ -3,46 +3,37 @@ package classes
 import (
 	"errors"
 	"fmt"
-	"sync"
 	"time"
 
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	"github.com/arslab/lwnsimulator/simulator/components/device/models"
 	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
 	"github.com/arslab/lwnsimulator/simulator/util"
-	"github.com/brocaar/lorawan"
 )
 
-const (
-	Close = iota
-	Open
-	Exit
-)
-
-//TypeC mode
+// TypeC mode: the RX2 is always open, except while sending and in RX1. The downlinks
+// received in it are events of the device
 type TypeC struct {
 	Info      *models.InformationDevice
 	Supported bool `json:"supported"`
 
-	Mutex    sync.Mutex `json:"-"`
-	Open     int        `json:"-"`
-	CondOpen *sync.Cond `json:"-"`
+	listening uint32 // frequency of the RX2 registered to the forwarder, 0 if closed
 }
 
 func (c *TypeC) Setup(info *models.InformationDevice) {
 	c.Info = info
-	c.CondOpen = sync.NewCond(&c.Mutex)
-	go c.RX2()
+	c.OpenWindow()
 }
 
-func (c *TypeC) SendData(rxpk pkt.RXPK) {
+func (c *TypeC) SendData(rxpk pkt.RXPK, sent time.Time) {
 
 	var indexChannelRX1 int
 
 	c.CloseWindow()
 	defer c.OpenWindow()
 
-	c.Info.Forwarder.Uplink(rxpk, c.Info.DevEUI)
+	tmst := c.Info.Forwarder.Uplink(rxpk, c.Info.DevEUI)
+	c.Info.ReceivedDownlink.SetReference(sent, tmst)
 
 	c.Info.RX[0].DataRate, indexChannelRX1 = c.Info.Configuration.Region.SetupRX1(
 		c.Info.Status.DataRate, c.Info.Configuration.RX1DROffset,
@@ -51,24 +42,14 @@ func (c *TypeC) SendData(rxpk pkt.RXPK) {
 	c.Info.RX[0].Channel = c.Info.Configuration.Channels[indexChannelRX1]
 }
 
-func (c *TypeC) ReceiveWindows(delayRX1 time.Duration, delayRX2 time.Duration) *lorawan.PHYPayload {
-
-	c.CloseWindow()
-	defer c.OpenWindow()
-
-	c.Info.Forwarder.Register(c.Info.RX[0].GetListeningFrequency(), c.Info.DevEUI, &c.Info.ReceivedDownlink)
-
-	resp := c.Info.RX[0].OpenWindow(0, &c.Info.ReceivedDownlink)
-
-	c.Info.Forwarder.UnRegister(c.Info.RX[0].GetListeningFrequency(), c.Info.DevEUI)
-
-	return resp
-
+// Windows returns the receive windows opened after an uplink: RX1, the RX2 is always open
+func (c *TypeC) Windows() int {
+	return 1
 }
 
 func (c *TypeC) RetransmissionCData(downlink *dl.InformationDownlink) error {
 
-	if c.Info.Status.CounterRepConfirmedDataUp < c.Info.Configuration.NbRepConfirmedDataUp {
+	if c.Info.Status.CounterRepConfirmedDataUp < c.Info.Configuration.MaxConfirmedRetransmissions() {
 
 		if downlink != nil { ///downlink ricevuto
 
@@ -89,7 +70,12 @@ func (c *TypeC) RetransmissionCData(downlink *dl.InformationDownlink) error {
 
 		c.Info.Status.Mode = util.Normal
 		c.Info.Status.CounterRepConfirmedDataUp = 0
-		err := fmt.Sprintf("Last Uplink sent %v times", c.Info.Configuration.NbRepConfirmedDataUp)
+
+		if downlink != nil && downlink.ACK { // ACK on the last transmission
+			return nil
+		}
+
+		err := fmt.Sprintf("Last Uplink sent %v times", c.Info.Configuration.MaxConfirmedRetransmissions())
 
 		return errors.New(err)
 
@@ -132,95 +118,31 @@ func (c *TypeC) ToString() string {
 	return "C"
 }
 
-func (c *TypeC) RX2() {
-
-	c.Info.Forwarder.Register(c.Info.RX[1].GetListeningFrequency(), c.Info.DevEUI, &c.Info.ReceivedDownlink)
-
-	for {
-
-		switch c.isOpenWindow() {
-		case Exit:
-			c.Info.Forwarder.UnRegister(c.Info.RX[1].GetListeningFrequency(), c.Info.DevEUI)
-			return
-
-		case Close:
-			c.Info.Forwarder.UnRegister(c.Info.RX[1].GetListeningFrequency(), c.Info.DevEUI)
-
-			c.Mutex.Lock()
-			c.CondOpen.Wait()
-			c.Mutex.Unlock()
-
-			c.Info.Forwarder.Register(c.Info.RX[1].GetListeningFrequency(), c.Info.DevEUI, &c.Info.ReceivedDownlink)
-
-			continue
-		}
-
-		c.Info.ReceivedDownlink.Wait()
-
-		switch c.isOpenWindow() {
-		case Exit:
-			c.Info.Forwarder.UnRegister(c.Info.RX[1].GetListeningFrequency(), c.Info.DevEUI)
-			return
-
-		case Close:
-			c.Info.Forwarder.UnRegister(c.Info.RX[1].GetListeningFrequency(), c.Info.DevEUI)
-
-			c.Mutex.Lock()
-			c.CondOpen.Wait()
-			c.Mutex.Unlock()
-
-			c.Info.Forwarder.Register(c.Info.RX[1].GetListeningFrequency(), c.Info.DevEUI, &c.Info.ReceivedDownlink)
-
-			continue
-		}
-
-		phy := c.Info.ReceivedDownlink.Pull()
-		if phy != nil { //response
-
-			downlink, err := dl.GetDownlink(*phy, c.Info.Configuration.DisableFCntDown, c.Info.Status.FCntDown,
-				c.Info.NwkSKey, c.Info.AppSKey)
-			if err != nil {
-				continue
-			}
-
-			c.Info.Status.InfoClassC.InsertDownlink(*downlink)
-			c.Info.Status.InfoClassC.WaitClass()
-
-		}
-
-	}
-
-}
-
+// OpenWindow opens the RX2, the forwarder delivers its downlinks to the device
 func (c *TypeC) OpenWindow() {
 
-	c.Mutex.Lock()
-	c.Open = Open
-	c.CondOpen.Broadcast()
-
-	c.Mutex.Unlock()
+	if c.listening != 0 {
+		return
+	}
 
+	c.listening = c.Info.RX[1].GetListeningFrequency()
+	c.Info.Forwarder.Register(c.listening, c.Info.DevEUI, &c.Info.ReceivedDownlink)
 }
 
 func (c *TypeC) CloseWindow() {
 
-	c.Mutex.Lock()
-	c.Open = Close
-	c.Mutex.Unlock()
+	if c.listening == 0 {
+		return
+	}
 
+	c.Info.Forwarder.UnRegister(c.listening, c.Info.DevEUI) // the frequency can be changed by a MAC command meanwhile
+	c.listening = 0
 }
 
-func (c *TypeC) isOpenWindow() int {
-
-	c.Mutex.Lock()
-	defer c.Mutex.Unlock()
-
-	return c.Open
+func (c *TypeC) OpenRX2() {
+	c.OpenWindow()
 }
 
 func (c *TypeC) CloseRX2() {
-	c.Mutex.Lock()
-	c.Open = Exit
-	c.CondOpen.Broadcast()
-	c.Mutex.Unlock()
+	c.CloseWindow()
 }
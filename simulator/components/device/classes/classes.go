@@ -6,7 +6,6 @@ import (
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	"github.com/arslab/lwnsimulator/simulator/components/device/models"
 	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
-	"github.com/brocaar/lorawan"
 )
 
 const (
@@ -17,12 +16,15 @@ const (
 
 type Class interface {
 	Setup(*models.InformationDevice)
-	SendData(rxpk pkt.RXPK)
-	ReceiveWindows(time.Duration, time.Duration) *lorawan.PHYPayload
+	SendData(rxpk pkt.RXPK, sent time.Time) // sent is the time of the simulation
+	Windows() int // receive windows opened after an uplink
 	RetransmissionCData(downlink *dl.InformationDownlink) error
 	RetransmissionUnCData(downlink *dl.InformationDownlink) error
 	GetClass() int
 	ToString() string
+
+	// the RX2 always open of class C, the other classes have none
+	OpenRX2()
 	CloseRX2()
 }
 
@@ -2,6 +2,7 @@ package models_classes
 
 import (
 	"github.com/arslab/lwnsimulator/simulator/components/device/features"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/beacon"
 	"github.com/arslab/lwnsimulator/simulator/components/device/features/channels"
 )
 
@@ -17,6 +18,7 @@ type InfoClassB struct {
 func (b *InfoClassB) Setup(freqBeacon uint32, freqPingSlot uint32, datarate uint8, minDr uint8, maxDr uint8) {
 
 	b.FrequencyBeacon = freqBeacon //freq
+	b.DataRate = datarate
 
 	channel := channels.Channel{
 		Active:            true,
@@ -29,6 +31,7 @@ func (b *InfoClassB) Setup(freqBeacon uint32, freqPingSlot uint32, datarate uint
 
 	b.PingSlot.Channel = channel
 	b.PingSlot.Delay = 0
-	b.PingSlot.DurationOpen = 30 //30 ms
+	b.PingSlot.DurationOpen = beacon.SlotLen
+	b.PingSlot.DataRate = datarate
 
 }
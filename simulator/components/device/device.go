@@ -1,72 +1,406 @@
 package device
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
 	c "github.com/arslab/lwnsimulator/simulator/console"
+	"github.com/arslab/lwnsimulator/simulator/events"
 	res "github.com/arslab/lwnsimulator/simulator/resources"
 
 	"github.com/arslab/lwnsimulator/simulator/components/device/classes"
 	"github.com/arslab/lwnsimulator/simulator/components/device/models"
+	"github.com/arslab/lwnsimulator/simulator/scheduler"
 	"github.com/arslab/lwnsimulator/simulator/util"
 	"github.com/arslab/lwnsimulator/socket"
 )
 
 type Device struct {
 	State     int                      `json:"-"`
-	Exit      chan struct{}            `json:"-"`
 	Id        int                      `json:"id"`
 	Info      models.InformationDevice `json:"info"`
 	Class     classes.Class            `json:"-"`
 	Resources *res.Resources           `json:"-"`
 	Mutex     sync.Mutex               `json:"-"`
 	Console   c.Console                `json:"-"`
+
+	events     uint8    // received and not yet handled
+	busy       bool     // a goroutine owns the state: it handles the events or applies the changes
+	pending    []func() // changes applied before the next event
+	offPending bool     // turned off while busy, the owner releases the device
+	snapshot   []byte   // JSON of the running device, read by the API
+
+	startTask      *scheduler.Task
+	periodicTask   *scheduler.Task
+	scheduleTask   *scheduler.Task // absolute-time and stream uplinks
+	retransmitTask *scheduler.Task
+	pingTask       *scheduler.Task
+	next           time.Time // next periodic uplink
+	ping           *window   // next ping slot of class B
+
+	// transaction in progress: an uplink with its receive windows and ACK timeout, or a join
+	waiting    func() // continuation, nil if none: written with the mutex locked, the changes wait its end
+	resumeAt   time.Time
+	resumeTask *scheduler.Task
+	rx         *window // receive window open
+
+	done chan struct{} // closed once turned off
+
+	brownOut   bool // power lost at the next transmission
+	lastUplink time.Time
 }
 
+// events of the device, handled one at a time in this order
+const (
+	eventStart    = 1 << iota
+	eventResume   // continuation of the transaction
+	eventDownlink // received in a receive window or in the RX2 always open of class C
+	eventPing     // opening of a ping slot of class B
+	eventPeriodic
+	eventScheduled
+	eventWake
+	eventRetransmit
+)
+
+var eventOrder = []uint8{eventStart, eventResume, eventDownlink, eventPing, eventPeriodic, eventScheduled, eventWake, eventRetransmit}
+
 // *******************Intern func*******************/
-func (d *Device) Run() {
 
-	defer d.Resources.ExitGroup.Done()
+// dispatch delivers the event to the device: the device has a goroutine only while it handles
+// its events, waiting the next one it's just a task of the scheduler
+func (d *Device) dispatch(event uint8) {
 
-	d.OtaaActivation()
+	d.Mutex.Lock()
 
-	ticker := time.NewTicker(d.Info.Configuration.SendInterval)
+	if d.State == util.Stopped {
+		d.Mutex.Unlock()
+		return
+	}
+
+	d.events |= event
+
+	idle := !d.busy
+	d.busy = true
+
+	d.Mutex.Unlock()
+
+	if idle {
+		d.Resources.Clock.Go(d.process)
+	}
+}
+
+// process handles the changes and the events received until none is left or the device is turned off
+func (d *Device) process() {
 
 	for {
 
-		select {
+		d.Mutex.Lock()
+
+		if len(d.pending) > 0 && d.waiting == nil {
 
-		case <-ticker.C:
-			break
+			changes := d.pending
+			d.pending = nil
 
-		case <-d.Exit:
-			d.Print("Turn OFF", nil, util.PrintBoth)
+			d.Mutex.Unlock()
+
+			for _, f := range changes {
+				f()
+			}
+			d.refresh()
+
+			continue
+		}
+
+		if d.State == util.Stopped {
+
+			off := d.offPending
+			d.offPending = false
+			d.busy = false
+
+			d.Mutex.Unlock()
+
+			if off {
+				d.turnedOff()
+			}
+			return
+		}
+
+		event := uint8(0)
+		for _, e := range eventOrder {
+			if d.events&e != 0 && d.ready(e) {
+				event = e
+				break
+			}
+		}
+
+		if event == 0 {
+			d.busy = false
+			d.Mutex.Unlock()
 			return
 		}
 
-		if d.CanExecute() {
+		d.events &^= event
 
-			if d.Info.Status.Joined {
+		d.Mutex.Unlock()
 
-				if d.Info.Configuration.SupportedClassC {
-					d.SwitchClass(classes.ClassC)
-				} else if d.Info.Configuration.SupportedClassB {
-					d.SwitchClass(classes.ClassB)
-				}
+		d.handle(event)
 
-				d.Execute()
+		if d.waiting == nil { // the snapshot is taken at the end of the transaction
+			d.refresh()
+		}
+	}
+}
 
-			} else {
-				d.OtaaActivation()
+// ready is true if the event can be handled now: during a transaction only its continuation and the
+// downlinks of the window open, the other events wait its end
+func (d *Device) ready(event uint8) bool {
 
-				d.Info.Status.DoSwitchChannel = true
+	if d.waiting == nil {
+		return true
+	}
+
+	return event == eventResume || (event == eventDownlink && d.rx != nil)
+}
+
+// Apply changes the state of the device: right away if it's idle, otherwise the goroutine
+// handling its events applies the change before the next one, once the transaction in progress
+// is over. It doesn't wait the change
+func (d *Device) Apply(f func()) {
+
+	d.Mutex.Lock()
+
+	if d.busy || d.waiting != nil {
+		d.pending = append(d.pending, f)
+		d.Mutex.Unlock()
+		return
+	}
+
+	d.busy = true
+
+	d.Mutex.Unlock()
+
+	f()
+	d.refresh()
+
+	d.Mutex.Lock()
+
+	more := len(d.pending) > 0 || d.offPending || (d.events != 0 && d.State == util.Running)
+	if !more {
+		d.busy = false
+	}
+
+	d.Mutex.Unlock()
+
+	if more { // received meanwhile
+		d.Resources.Clock.Go(d.process)
+	}
+}
+
+// refresh takes the snapshot of the running device, called by the owner of the state
+func (d *Device) refresh() {
+
+	d.Mutex.Lock()
+	running := d.State == util.Running
+	d.Mutex.Unlock()
+
+	if !running {
+		return
+	}
+
+	snapshot, err := json.Marshal((*deviceJSON)(d))
+	if err != nil {
+		d.Print("", err, util.PrintOnlyConsole)
+		return
+	}
+
+	d.Mutex.Lock()
+	if d.State == util.Running {
+		d.snapshot = snapshot
+	}
+	d.Mutex.Unlock()
+}
+
+// deviceJSON is the device without its MarshalJSON
+type deviceJSON Device
+
+// MarshalJSON of the device: a running device returns the snapshot taken after its last event
+// or change, its state is changing meanwhile
+func (d *Device) MarshalJSON() ([]byte, error) {
+
+	d.Mutex.Lock()
+	snapshot := d.snapshot
+	d.Mutex.Unlock()
+
+	if snapshot != nil {
+		return snapshot, nil
+	}
+
+	return json.Marshal((*deviceJSON)(d))
+}
+
+func (d *Device) handle(event uint8) {
+
+	switch event {
+
+	case eventResume:
+		d.resume()
+		return
+
+	case eventDownlink:
+		d.receiveDownlink()
+		return
+
+	case eventPing:
+		d.openPingSlot()
+		return
+
+	case eventStart:
+
+		d.OtaaActivation(func() {
+
+			d.saveSession()
+
+			d.next = d.now().Add(d.nextSendInterval())
+			d.schedule(&d.periodicTask, d.next, eventPeriodic)
+
+			d.subscribe()
+			d.scheduleWakeUp()
+		})
+
+		return
+
+	case eventScheduled:
+		d.queueScheduled(d.now())
+
+	case eventWake, eventRetransmit:
+		break
+	}
+
+	if d.CanExecute() {
+
+		if d.Info.Status.Joined {
+
+			if d.Info.Configuration.SupportedClassC {
+				d.SwitchClass(classes.ClassC)
+			} else if d.Info.Configuration.SupportedClassB {
+				d.SwitchClass(classes.ClassB)
 			}
+
+			d.Execute(func() { d.handled(event) })
+			return
+
+		} else if event == eventPeriodic { // events wait the join
+
+			d.OtaaActivation(func() {
+				d.Info.Status.DoSwitchChannel = true
+				d.handled(event)
+			})
+			return
 		}
+	}
+
+	d.handled(event)
+}
 
+// handled schedules the next events once the uplink of the event and its transaction are over
+func (d *Device) handled(event uint8) {
+
+	if d.Info.Status.Mode == util.Retransmission && d.Info.Configuration.Backoff.Retransmission.Enabled {
+		d.schedule(&d.retransmitTask, d.now().Add(d.Info.Configuration.Backoff.Retransmission.Delay()), eventRetransmit)
+	}
+
+	d.saveSession()
+
+	if d.pendingTriggered() { // interleaved with the RX windows of the previous one
+		d.wakeUp()
 	}
 
+	d.scheduleWakeUp()
+
+	if event != eventPeriodic { // the periodic uplink is still pending
+		return
+	}
+
+	d.next = d.next.Add(d.nextSendInterval())
+	if !d.next.After(d.now()) { // late, like a ticker drop the missed sends
+		d.next = d.now().Add(d.nextSendInterval())
+	}
+	d.schedule(&d.periodicTask, d.next, eventPeriodic)
+}
+
+// schedule replaces the task with the event at the given time, a zero time only cancels it
+func (d *Device) schedule(task **scheduler.Task, at time.Time, event uint8) {
+
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+
+	d.Resources.Scheduler.Cancel(*task)
+	*task = nil
+
+	if d.State == util.Stopped || at.IsZero() {
+		return
+	}
+
+	*task = d.Resources.Scheduler.At(at, func() { d.dispatch(event) })
+}
+
+// cancelTasks removes the events of the device from the scheduler, called with the mutex locked
+func (d *Device) cancelTasks() {
+
+	for _, task := range []**scheduler.Task{&d.startTask, &d.periodicTask, &d.scheduleTask, &d.retransmitTask, &d.resumeTask, &d.pingTask} {
+		d.Resources.Scheduler.Cancel(*task)
+		*task = nil
+	}
+
+	d.events = 0
+}
+
+// turnedOff releases the device once it's no longer handling events
+func (d *Device) turnedOff() {
+
+	d.closeWindows()
+	d.Class.CloseRX2()
+	d.Resources.Triggers.Unsubscribe(d.Id)
+
+	d.Mutex.Lock()
+	changes := d.pending // waiting the transaction interrupted
+	d.pending = nil
+	d.Mutex.Unlock()
+
+	for _, f := range changes {
+		f()
+	}
+
+	d.Mutex.Lock()
+	d.snapshot = nil // the state is no longer changing
+	d.Mutex.Unlock()
+
+	d.Print("Turn OFF", nil, util.PrintBoth)
+	d.publish(events.TypeState, &events.State{On: false})
+
+	close(d.done)
+}
+
+// now returns the time of the simulation
+func (d *Device) now() time.Time {
+
+	if d.Resources == nil || d.Resources.Clock == nil { // not set up yet
+		return time.Now()
+	}
+
+	return d.Resources.Clock.Now()
+}
+
+// nextSendInterval returns the time until the next periodic uplink
+func (d *Device) nextSendInterval() time.Duration {
+
+	if d.Info.Status.DataSource != nil {
+		return d.Info.Status.DataSource.Interval(d.Info.Configuration.SendInterval)
+	}
+
+	return d.Info.Configuration.Traffic.Interval(d.Info.Configuration.SendInterval, d.now())
 }
 
 func (d *Device) modeToString() string {
@@ -93,7 +427,7 @@ func (d *Device) modeToString() string {
 
 func (d *Device) Print(content string, err error, printType int) {
 
-	now := time.Now()
+	now := d.now()
 	message := ""
 	messageLog := ""
 	event := socket.EventDev
@@ -107,6 +441,8 @@ func (d *Device) Print(content string, err error, printType int) {
 		message = fmt.Sprintf("[ %s ] DEV[%s] |%s| {%s} [ERROR]: %s", now.Format(time.Stamp), d.Info.Name, mode, class, err)
 		messageLog = fmt.Sprintf("DEV[%s] |%s| {%s} [ERROR]: %s", d.Info.Name, mode, class, err)
 		event = socket.EventError
+
+		d.publish(events.TypeError, &events.Error{Message: err.Error()})
 	}
 
 	data := socket.ConsoleLog{
@@ -0,0 +1,128 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/clock"
+	f "github.com/arslab/lwnsimulator/simulator/components/forwarder"
+	mfw "github.com/arslab/lwnsimulator/simulator/components/forwarder/models"
+	c "github.com/arslab/lwnsimulator/simulator/console"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	res "github.com/arslab/lwnsimulator/simulator/resources"
+	"github.com/arslab/lwnsimulator/simulator/scheduler"
+)
+
+// fleetSize is the target of a single machine
+const fleetSize = 100000
+
+// template is an ABP device of EU868 sending every minute, no gateway receives it
+const template = `{
+	"id": %d,
+	"info": {
+		"name": "dev%d",
+		"devEUI": "%016x",
+		"devAddr": "%08x",
+		"nwkSKey": "00000000000000000000000000000000",
+		"appSKey": "00000000000000000000000000000000",
+		"location": {"latitude": 0, "longitude": 0, "altitude": 0},
+		"status": {
+			"mtype": "UnConfirmedDataUp",
+			"payload": "010203",
+			"active": true,
+			"infoUplink": {"fport": 1, "fcnt": 1},
+			"fcntDown": 0
+		},
+		"configuration": {
+			"region": 1,
+			"sendInterval": 60,
+			"ackTimeout": 2,
+			"range": 10000,
+			"supportedOtaa": false,
+			"dataRate": 5,
+			"nbRetransmission": 1
+		},
+		"rxs": [
+			{"delay": 1000, "durationOpen": 100, "channel": {"active": true, "freqDownlink": 868100000}, "dataRate": 5},
+			{"delay": 1000, "durationOpen": 100, "channel": {"active": true, "freqDownlink": 869525000}, "dataRate": 0}
+		]
+	}
+}`
+
+// BenchmarkFleet runs 100k devices for 10 minutes of virtual time, each uplink with its
+// receive windows: the devices are tasks of the scheduler, not goroutines
+func BenchmarkFleet(b *testing.B) {
+
+	output := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(output)
+
+	resources := &res.Resources{
+		Events:    events.NewBus(),
+		Scheduler: scheduler.Setup(),
+	}
+	forwarder := f.Setup()
+	console := c.New()
+
+	devices := make([]*Device, fleetSize)
+
+	for i := range devices {
+
+		d := &Device{}
+		if err := json.Unmarshal([]byte(fmt.Sprintf(template, i, i, i+1, i+1)), d); err != nil {
+			b.Fatal(err)
+		}
+
+		forwarder.AddDevice(mfw.InfoDevice{DevEUI: d.Info.DevEUI, Location: d.Info.Location, Range: d.Info.Configuration.Range})
+
+		devices[i] = d
+	}
+
+	var uplinks int64
+	resources.Events.Attach("benchmark", events.ConsumerFunc(func(e events.Event) {
+		if e.Type == events.TypeUplink {
+			atomic.AddInt64(&uplinks, 1)
+		}
+	}))
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+
+		virtual := clock.NewVirtual(time.Now())
+		resources.Clock = virtual
+		resources.Scheduler.SetClock(virtual)
+		forwarder.Clock = virtual
+
+		over := make(chan struct{})
+
+		virtual.Hold() // all the devices start together
+		virtual.AfterFunc(10*time.Minute, func() { close(over) })
+
+		for _, d := range devices {
+			d.SetConsole(&console)
+			d.Setup(resources, forwarder)
+			d.TurnON()
+		}
+
+		virtual.Release()
+		<-over
+
+		for _, d := range devices {
+			d.TurnOFF()
+		}
+
+		for _, d := range devices {
+			<-d.Done()
+		}
+
+		virtual.Close()
+	}
+
+	b.ReportMetric(float64(uplinks)/float64(b.N), "uplinks/op")
+}
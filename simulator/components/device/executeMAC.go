@@ -12,6 +12,7 @@ import (
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	mac "github.com/arslab/lwnsimulator/simulator/components/device/macCommands"
 	rp "github.com/arslab/lwnsimulator/simulator/components/device/regional_parameters"
+	"github.com/arslab/lwnsimulator/simulator/events"
 	"github.com/arslab/lwnsimulator/simulator/util"
 	"github.com/brocaar/lorawan"
 )
@@ -66,6 +67,8 @@ func (d *Device) ExecuteMACCommand(downlink dl.InformationDownlink) {
 			return
 		}
 
+		d.publish(events.TypeMACCommand, &events.MACCommand{DevEUI: d.Info.DevEUI, CID: cid.String(), Direction: events.DirectionDownlink})
+
 		switch cid {
 		case lorawan.LinkCheckAns:
 			d.executeLinkCheckAns(payloadBytes)
@@ -0,0 +1,83 @@
+package airtime
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	//PreambleSymbols is the number of programmed preamble symbols used by LoRaWAN
+	PreambleSymbols = 8
+	//SyncSymbols are the fixed symbols added to the programmed preamble
+	SyncSymbols = 4.25
+)
+
+//ParseDataRate extracts spreading factor and bandwidth (kHz) from a LoRa datarate identifier (eg. SF7BW125)
+func ParseDataRate(datr string) (int, int, error) {
+
+	var sf, bw int
+
+	_, err := fmt.Sscanf(datr, "SF%dBW%d", &sf, &bw)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid LoRa datarate %v", datr)
+	}
+
+	return sf, bw, nil
+}
+
+//SymbolTime returns the duration of one LoRa symbol
+func SymbolTime(datr string) (time.Duration, error) {
+
+	sf, bw, err := ParseDataRate(datr)
+	if err != nil {
+		return 0, err
+	}
+
+	seconds := math.Pow(2, float64(sf)) / float64(bw*1000)
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+//PreambleTime returns the on-air duration of the whole preamble
+func PreambleTime(datr string) (time.Duration, error) {
+
+	tSym, err := SymbolTime(datr)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(float64(tSym) * (PreambleSymbols + SyncSymbols)), nil
+}
+
+//TimeOnAir returns the on-air duration of a LoRa frame with explicit header and CRC
+func TimeOnAir(datr string, codr string, size int) (time.Duration, error) {
+
+	sf, _, err := ParseDataRate(datr)
+	if err != nil {
+		return 0, err
+	}
+
+	cr := 1
+	fmt.Sscanf(codr, "4/%d", &cr)
+	cr = cr - 4
+	if cr < 1 || cr > 4 {
+		cr = 1
+	}
+
+	tSym, _ := SymbolTime(datr)
+
+	de := 0.0
+	if tSym > 16*time.Millisecond { //low data rate optimization
+		de = 1.0
+	}
+
+	num := 8*float64(size) - 4*float64(sf) + 28 + 16
+	den := 4 * (float64(sf) - 2*de)
+
+	nbPayload := 8 + math.Max(math.Ceil(num/den)*float64(cr+4), 0)
+
+	total := (PreambleSymbols + SyncSymbols + nbPayload) * float64(tSym)
+
+	return time.Duration(total), nil
+}
@@ -0,0 +1,226 @@
+package backoff
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+const (
+	//JoinDutyCycleFirstHour is the join-request duty cycle in the first hour after reset
+	JoinDutyCycleFirstHour = 0.01
+	//JoinDutyCycleNext10Hours is the join-request duty cycle in the next 10 hours
+	JoinDutyCycleNext10Hours = 0.001
+	//JoinDutyCycleAfter is the join-request duty cycle after the first 11 hours
+	JoinDutyCycleAfter = 0.0001
+
+	//RetransmitTimeoutMin and RetransmitTimeoutMax bound the random delay before a retransmission (LoRaWAN 1.0.4)
+	RetransmitTimeoutMin = 1 * time.Second
+	RetransmitTimeoutMax = 3 * time.Second
+)
+
+// Policy is the join and retransmission timing of the device profile
+type Policy struct {
+	Join           Join           `json:"join"`
+	Retransmission Retransmission `json:"retransmission"`
+}
+
+// Join applies the join-request duty cycle of the specification plus a random delay
+type Join struct {
+	Enabled  bool          `json:"enabled"`
+	MinDelay time.Duration `json:"minDelay"` // random delay added to the off time (s)
+	MaxDelay time.Duration `json:"maxDelay"`
+
+	start  time.Time
+	random *rand.Rand
+}
+
+// Retransmission uses the timing and the NbTrans semantics of LoRaWAN 1.0.4
+// instead of waiting the next send interval
+type Retransmission struct {
+	Enabled  bool          `json:"enabled"`
+	MinDelay time.Duration `json:"minDelay"` // RETRANSMIT_TIMEOUT, 1 s if 0
+	MaxDelay time.Duration `json:"maxDelay"` // 3 s if 0
+	NbTrans  uint8         `json:"nbTrans"`  // transmissions of each uplink until LinkADRReq changes it, 1 if 0
+
+	random *rand.Rand
+}
+
+// Setup validates the policy and restarts the join duty cycle
+func (p *Policy) Setup() error {
+
+	random := rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+
+	p.Join.random = random
+	p.Join.Reset()
+	p.Retransmission.random = random
+
+	if p.Join.MinDelay < 0 || p.Join.MaxDelay < p.Join.MinDelay {
+		return errors.New("Join backoff: maxDelay must be greater than minDelay")
+	}
+
+	if p.Retransmission.MinDelay < 0 || p.Retransmission.MaxDelay < 0 ||
+		(p.Retransmission.MaxDelay != 0 && p.Retransmission.MaxDelay < p.Retransmission.MinDelay) {
+		return errors.New("Retransmission: maxDelay must be greater than minDelay")
+	}
+
+	if p.Retransmission.NbTrans > 15 {
+		return errors.New("Retransmission: nbTrans must be from 1 to 15")
+	}
+
+	return nil
+}
+
+// Reset restarts the duty cycle as after a power-up
+func (j *Join) Reset() {
+	j.start = time.Time{}
+}
+
+// DutyCycle returns the join-request duty cycle allowed at now
+func (j *Join) DutyCycle(now time.Time) float64 {
+
+	if j.start.IsZero() {
+		return JoinDutyCycleFirstHour
+	}
+
+	switch elapsed := now.Sub(j.start); {
+
+	case elapsed < time.Hour:
+		return JoinDutyCycleFirstHour
+
+	case elapsed < 11*time.Hour:
+		return JoinDutyCycleNext10Hours
+
+	}
+
+	return JoinDutyCycleAfter
+}
+
+// Delay returns the wait before the next join-request, after one of duration toa sent at now
+func (j *Join) Delay(now time.Time, toa time.Duration) time.Duration {
+
+	if !j.Enabled {
+		return 0
+	}
+
+	if j.start.IsZero() {
+		j.start = now
+	}
+
+	off := time.Duration(float64(toa) * (1/j.DutyCycle(now) - 1))
+
+	return off + randomDelay(j.random, j.MinDelay, j.MaxDelay)
+}
+
+// Delay returns the wait before a retransmission
+func (r *Retransmission) Delay() time.Duration {
+
+	min, max := r.MinDelay, r.MaxDelay
+	if min == 0 && max == 0 {
+		min, max = RetransmitTimeoutMin, RetransmitTimeoutMax
+	}
+
+	return randomDelay(r.random, min, max)
+}
+
+// InitialNbTrans returns the transmissions of each uplink before any LinkADRReq
+func (r *Retransmission) InitialNbTrans() uint8 {
+
+	if !r.Enabled || r.NbTrans == 0 {
+		return 1
+	}
+
+	return r.NbTrans
+}
+
+func randomDelay(random *rand.Rand, min time.Duration, max time.Duration) time.Duration {
+
+	if max <= min {
+		return min
+	}
+
+	if random == nil {
+		random = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	}
+
+	return min + time.Duration(random.Int63n(int64(max-min)))
+}
+
+// MarshalJSON of the join backoff
+func (j *Join) MarshalJSON() ([]byte, error) {
+
+	type Alias Join
+
+	return json.Marshal(&struct {
+		MinDelay float64 `json:"minDelay"`
+		MaxDelay float64 `json:"maxDelay"`
+		*Alias
+	}{
+		MinDelay: j.MinDelay.Seconds(),
+		MaxDelay: j.MaxDelay.Seconds(),
+		Alias:    (*Alias)(j),
+	})
+}
+
+// UnmarshalJSON of the join backoff
+func (j *Join) UnmarshalJSON(data []byte) error {
+
+	type Alias Join
+
+	aux := &struct {
+		MinDelay float64 `json:"minDelay"`
+		MaxDelay float64 `json:"maxDelay"`
+		*Alias
+	}{
+		Alias: (*Alias)(j),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	j.MinDelay = time.Duration(aux.MinDelay * float64(time.Second))
+	j.MaxDelay = time.Duration(aux.MaxDelay * float64(time.Second))
+
+	return nil
+}
+
+// MarshalJSON of the retransmission timing
+func (r *Retransmission) MarshalJSON() ([]byte, error) {
+
+	type Alias Retransmission
+
+	return json.Marshal(&struct {
+		MinDelay float64 `json:"minDelay"`
+		MaxDelay float64 `json:"maxDelay"`
+		*Alias
+	}{
+		MinDelay: r.MinDelay.Seconds(),
+		MaxDelay: r.MaxDelay.Seconds(),
+		Alias:    (*Alias)(r),
+	})
+}
+
+// UnmarshalJSON of the retransmission timing
+func (r *Retransmission) UnmarshalJSON(data []byte) error {
+
+	type Alias Retransmission
+
+	aux := &struct {
+		MinDelay float64 `json:"minDelay"`
+		MaxDelay float64 `json:"maxDelay"`
+		*Alias
+	}{
+		Alias: (*Alias)(r),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.MinDelay = time.Duration(aux.MinDelay * float64(time.Second))
+	r.MaxDelay = time.Duration(aux.MaxDelay * float64(time.Second))
+
+	return nil
+}
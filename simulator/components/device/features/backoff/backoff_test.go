@@ -0,0 +1,170 @@
+package backoff
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJoinDelay(t *testing.T) {
+
+	toa := 100 * time.Millisecond
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{0, 9900 * time.Millisecond}, // 1%
+		{time.Hour - time.Second, 9900 * time.Millisecond},
+		{time.Hour, 99900 * time.Millisecond}, // 0.1%
+		{11*time.Hour - time.Second, 99900 * time.Millisecond},
+		{11 * time.Hour, 999900 * time.Millisecond}, // 0.01%
+		{24 * time.Hour, 999900 * time.Millisecond},
+	}
+
+	p := Policy{Join: Join{Enabled: true}}
+	if err := p.Setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+
+		got := p.Join.Delay(start.Add(test.elapsed), toa)
+		if diff := got - test.want; diff < -time.Microsecond || diff > time.Microsecond {
+			t.Errorf("delay %v after the reset = %v, want %v", test.elapsed, got, test.want)
+		}
+	}
+
+	p.Join.Reset() // the first hour again
+	if got := p.Join.Delay(start.Add(48*time.Hour), toa); got < 9899*time.Millisecond || got > 9901*time.Millisecond {
+		t.Errorf("delay after Reset = %v", got)
+	}
+
+	p.Join.Enabled = false
+	if got := p.Join.Delay(start, toa); got != 0 {
+		t.Errorf("delay disabled = %v", got)
+	}
+}
+
+func TestJoinDutyCycle(t *testing.T) {
+
+	var j Join
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d := j.DutyCycle(start.Add(24 * time.Hour)); d != JoinDutyCycleFirstHour {
+		t.Errorf("DutyCycle before the first join = %v", d)
+	}
+
+	j.Enabled = true
+	j.Delay(start, time.Second)
+
+	tests := []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{30 * time.Minute, JoinDutyCycleFirstHour},
+		{time.Hour, JoinDutyCycleNext10Hours},
+		{11 * time.Hour, JoinDutyCycleAfter},
+	}
+
+	for _, test := range tests {
+		if d := j.DutyCycle(start.Add(test.elapsed)); d != test.want {
+			t.Errorf("DutyCycle %v after the reset = %v, want %v", test.elapsed, d, test.want)
+		}
+	}
+}
+
+func TestRandomDelay(t *testing.T) {
+
+	p := Policy{
+		Join:           Join{Enabled: true, MinDelay: 2 * time.Second, MaxDelay: 5 * time.Second},
+		Retransmission: Retransmission{Enabled: true},
+	}
+
+	if err := p.Setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	off := 99 * time.Second // 1% of a 1 s join-request
+
+	for i := 0; i < 100; i++ {
+
+		if d := p.Join.Delay(now, time.Second) - off; d < p.Join.MinDelay || d >= p.Join.MaxDelay {
+			t.Fatalf("random join delay %v out of [%v, %v)", d, p.Join.MinDelay, p.Join.MaxDelay)
+		}
+
+		if d := p.Retransmission.Delay(); d < RetransmitTimeoutMin || d >= RetransmitTimeoutMax {
+			t.Fatalf("retransmission delay %v out of RETRANSMIT_TIMEOUT", d)
+		}
+	}
+
+	fixed := Retransmission{MinDelay: 2 * time.Second, MaxDelay: 2 * time.Second}
+	if d := fixed.Delay(); d != 2*time.Second {
+		t.Errorf("fixed retransmission delay = %v", d)
+	}
+}
+
+func TestInitialNbTrans(t *testing.T) {
+
+	tests := []struct {
+		retransmission Retransmission
+		want           uint8
+	}{
+		{Retransmission{}, 1},
+		{Retransmission{NbTrans: 3}, 1}, // disabled
+		{Retransmission{Enabled: true}, 1},
+		{Retransmission{Enabled: true, NbTrans: 3}, 3},
+	}
+
+	for _, test := range tests {
+		if got := test.retransmission.InitialNbTrans(); got != test.want {
+			t.Errorf("InitialNbTrans(%+v) = %v, want %v", test.retransmission, got, test.want)
+		}
+	}
+}
+
+func TestSetupErrors(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		policy Policy
+	}{
+		{"join delays", Policy{Join: Join{MinDelay: 5 * time.Second, MaxDelay: time.Second}}},
+		{"join negative", Policy{Join: Join{MinDelay: -time.Second}}},
+		{"retransmission delays", Policy{Retransmission: Retransmission{MinDelay: 5 * time.Second, MaxDelay: time.Second}}},
+		{"nbTrans", Policy{Retransmission: Retransmission{NbTrans: 16}}},
+	}
+
+	for _, test := range tests {
+		if err := test.policy.Setup(); err == nil {
+			t.Errorf("%v accepted", test.name)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+
+	data := []byte(`{"join":{"enabled":true,"minDelay":1.5,"maxDelay":3},"retransmission":{"enabled":true,"minDelay":0.5,"maxDelay":2,"nbTrans":2}}`)
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Join.MinDelay != 1500*time.Millisecond || p.Join.MaxDelay != 3*time.Second ||
+		p.Retransmission.MinDelay != 500*time.Millisecond || p.Retransmission.MaxDelay != 2*time.Second || p.Retransmission.NbTrans != 2 {
+		t.Fatalf("Unmarshal = %+v", p)
+	}
+
+	out, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var q Policy // delays in seconds again
+	if err := json.Unmarshal(out, &q); err != nil || q.Join.MinDelay != p.Join.MinDelay || q.Retransmission.MinDelay != p.Retransmission.MinDelay {
+		t.Errorf("Marshal = %s, %v", out, err)
+	}
+}
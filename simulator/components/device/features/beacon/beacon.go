@@ -0,0 +1,77 @@
+package beacon
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"time"
+
+	"github.com/brocaar/lorawan"
+)
+
+const (
+	//Period between two beacons, they start at the multiples of 128 s of GPS time
+	Period = 128 * time.Second
+	//Reserved is the time after the beacon before the first ping slot
+	Reserved = 2120 * time.Millisecond
+	//SlotLen is the length of a ping slot
+	SlotLen = 30 * time.Millisecond
+	//WindowSlots is the number of ping slots of a beacon period
+	WindowSlots = 4096
+
+	//LeapSeconds between the GPS time and the UTC
+	LeapSeconds = 18
+)
+
+var gpsEpoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// Time returns the beacon starting the period that contains t
+func Time(t time.Time) time.Time {
+
+	gps := int64(t.Sub(gpsEpoch)/time.Second) + LeapSeconds
+	gps -= gps % int64(Period/time.Second)
+
+	return gpsEpoch.Add(time.Duration(gps-LeapSeconds) * time.Second)
+}
+
+// Offset returns the first ping slot of the device in the period of the beacon, pseudo-random
+// as in the specification so the ping slots of the devices don't collide
+func Offset(beacon time.Time, DevAddr lorawan.DevAddr, pingPeriod int) int {
+
+	gps := uint32(int64(beacon.Sub(gpsEpoch)/time.Second) + LeapSeconds)
+
+	addr, _ := DevAddr.MarshalBinary() // little endian
+
+	var block [16]byte
+	binary.LittleEndian.PutUint32(block[:4], gps)
+	copy(block[4:8], addr)
+
+	cipher, _ := aes.NewCipher(make([]byte, 16)) // 16 bytes key never fails
+	cipher.Encrypt(block[:], block[:])
+
+	return (int(block[0]) + int(block[1])*256) % pingPeriod
+}
+
+// Next returns the beacon and the first ping slot of the device after t, the device opens
+// 2^(7-periodicity) ping slots per beacon period
+func Next(t time.Time, DevAddr lorawan.DevAddr, periodicity uint8) (time.Time, time.Time) {
+
+	if periodicity > 7 {
+		periodicity = 7
+	}
+
+	pingNb := 1 << (7 - periodicity)
+	pingPeriod := WindowSlots / pingNb
+
+	for beacon := Time(t); ; beacon = beacon.Add(Period) {
+
+		offset := Offset(beacon, DevAddr, pingPeriod)
+
+		for n := 0; n < pingNb; n++ {
+
+			slot := beacon.Add(Reserved + time.Duration(offset+n*pingPeriod)*SlotLen)
+			if slot.After(t) {
+				return beacon, slot
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	gen "github.com/arslab/lwnsimulator/simulator/components/device/features/generator"
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+)
+
+const (
+	FormatCayenneLPP = "cayennelpp" // [channel][type][value]
+	FormatTLV        = "tlv"        // [type][length][value], CayenneLPP types and resolutions
+	FormatTV         = "tv"         // [type][value], CayenneLPP types and resolutions
+)
+
+// Channel is a typed measure of the device
+type Channel struct {
+	Channel uint8       `json:"channel"`
+	Type    string      `json:"type"`
+	Values  []gen.Field `json:"values"` // one per axis; GPS without values follows the device's location
+}
+
+// Measure is a decoded channel
+type Measure struct {
+	Channel uint8     `json:"channel"`
+	Type    string    `json:"type"`
+	Values  []float64 `json:"values"`
+}
+
+// Codec encodes channels in a standard payload format
+type Codec struct {
+	Format   string    `json:"format"`
+	Channels []Channel `json:"channels"`
+
+	random *rand.Rand
+}
+
+// Setup validates the codec
+func (c *Codec) Setup() error {
+
+	c.random = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+
+	switch c.Format {
+	case FormatCayenneLPP, FormatTLV, FormatTV:
+	default:
+		return fmt.Errorf("Format %v not supported", c.Format)
+	}
+
+	if len(c.Channels) == 0 {
+		return errors.New("Codec without channels")
+	}
+
+	for i, ch := range c.Channels {
+
+		t, ok := measureTypes[ch.Type]
+		if !ok {
+			return fmt.Errorf("Channel %v: type %v not supported", ch.Channel, ch.Type)
+		}
+
+		if len(ch.Values) == 0 && ch.Type == TypeGPS {
+			continue
+		}
+
+		if len(ch.Values) != t.Axes {
+			return fmt.Errorf("Channel %v: %v requires %v values", ch.Channel, ch.Type, t.Axes)
+		}
+
+		for j := range ch.Values {
+			if err := c.Channels[i].Values[j].Validate(); err != nil {
+				return fmt.Errorf("Channel %v: %v", ch.Channel, err)
+			}
+		}
+
+	}
+
+	return nil
+}
+
+// Encode evaluates all channels and packs them
+func (c *Codec) Encode(now time.Time, location loc.Location) ([]byte, error) {
+
+	if c.random == nil {
+		if err := c.Setup(); err != nil {
+			return nil, err
+		}
+	}
+
+	var payload []byte
+
+	for i := range c.Channels {
+
+		ch := &c.Channels[i]
+		t := measureTypes[ch.Type]
+
+		var values []float64
+		if len(ch.Values) == 0 { //GPS
+			values = []float64{location.Latitude, location.Longitude, float64(location.Altitude)}
+		} else {
+			for j := range ch.Values {
+				values = append(values, ch.Values[j].Next(now, c.random))
+			}
+		}
+
+		var data []byte
+		for j, v := range values {
+			data = append(data, packValue(v, t.Resolution[j], t.Size, t.Signed)...)
+		}
+
+		switch c.Format {
+
+		case FormatCayenneLPP:
+			payload = append(payload, ch.Channel, t.LPP)
+
+		case FormatTLV:
+			payload = append(payload, t.LPP, uint8(len(data)))
+
+		case FormatTV:
+			payload = append(payload, t.LPP)
+
+		}
+
+		payload = append(payload, data...)
+	}
+
+	return payload, nil
+}
+
+// Decode unpacks a CayenneLPP payload
+func Decode(payload []byte) ([]Measure, error) {
+
+	var measures []Measure
+
+	for i := 0; i < len(payload); {
+
+		if len(payload)-i < 2 {
+			return measures, errors.New("Truncated CayenneLPP payload")
+		}
+
+		channel, lpp := payload[i], payload[i+1]
+		i += 2
+
+		name, t, ok := typeByLPP(lpp)
+		if !ok {
+			return measures, fmt.Errorf("CayenneLPP type %v not supported", lpp)
+		}
+
+		if len(payload)-i < t.Axes*t.Size {
+			return measures, errors.New("Truncated CayenneLPP payload")
+		}
+
+		measure := Measure{
+			Channel: channel,
+			Type:    name,
+		}
+
+		for j := 0; j < t.Axes; j++ {
+			measure.Values = append(measure.Values, unpackValue(payload[i:i+t.Size], t.Resolution[j], t.Signed))
+			i += t.Size
+		}
+
+		measures = append(measures, measure)
+	}
+
+	return measures, nil
+}
+
+// Apply sets the actuators addressed by the measures, it returns the channels updated
+func (c *Codec) Apply(measures []Measure) []Measure {
+
+	var applied []Measure
+
+	for _, m := range measures {
+
+		if !measureTypes[m.Type].Actuator {
+			continue
+		}
+
+		for i := range c.Channels {
+
+			ch := &c.Channels[i]
+			if ch.Channel != m.Channel || ch.Type != m.Type {
+				continue
+			}
+
+			for j := range ch.Values {
+				ch.Values[j].Signal = gen.SignalConstant
+				ch.Values[j].Value = m.Values[j]
+			}
+
+			applied = append(applied, m)
+		}
+
+	}
+
+	return applied
+}
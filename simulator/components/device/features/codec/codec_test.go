@@ -0,0 +1,260 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+
+	gen "github.com/arslab/lwnsimulator/simulator/components/device/features/generator"
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+)
+
+// vectors are CayenneLPP payloads, the first four are the examples of its documentation
+var vectors = []struct {
+	payload  string
+	measures []Measure
+}{
+	{"03670110056700ff", []Measure{
+		{Channel: 3, Type: TypeTemperature, Values: []float64{27.2}},
+		{Channel: 5, Type: TypeTemperature, Values: []float64{25.5}},
+	}},
+	{"0167ffd7", []Measure{
+		{Channel: 1, Type: TypeTemperature, Values: []float64{-4.1}},
+	}},
+	{"067104d2fb2e0000", []Measure{
+		{Channel: 6, Type: TypeAccelerometer, Values: []float64{1.234, -1.234, 0}},
+	}},
+	{"018806765ff2960a0003e8", []Measure{
+		{Channel: 1, Type: TypeGPS, Values: []float64{42.3519, -87.9094, 10}},
+	}},
+	{"026861030064040204d2", []Measure{
+		{Channel: 2, Type: TypeHumidity, Values: []float64{48.5}},
+		{Channel: 3, Type: TypeDigitalInput, Values: []float64{100}},
+		{Channel: 4, Type: TypeAnalogInput, Values: []float64{12.34}},
+	}},
+}
+
+// constant returns the fields of a measure with fixed values
+func constant(values []float64) []gen.Field {
+
+	var fields []gen.Field
+	for _, v := range values {
+		fields = append(fields, gen.Field{Signal: gen.SignalConstant, Value: v})
+	}
+
+	return fields
+}
+
+func equal(a []float64, b []float64) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestDecode(t *testing.T) {
+
+	for _, v := range vectors {
+
+		payload, _ := hex.DecodeString(v.payload)
+
+		measures, err := Decode(payload)
+		if err != nil {
+			t.Errorf("Decode(%v): %v", v.payload, err)
+			continue
+		}
+
+		if len(measures) != len(v.measures) {
+			t.Errorf("Decode(%v) = %v measures, want %v", v.payload, len(measures), len(v.measures))
+			continue
+		}
+
+		for i, m := range measures {
+
+			want := v.measures[i]
+			if m.Channel != want.Channel || m.Type != want.Type || !equal(m.Values, want.Values) {
+				t.Errorf("Decode(%v)[%v] = %+v, want %+v", v.payload, i, m, want)
+			}
+		}
+	}
+}
+
+func TestEncodeCayenneLPP(t *testing.T) {
+
+	for _, v := range vectors {
+
+		c := Codec{Format: FormatCayenneLPP}
+		for _, m := range v.measures {
+			c.Channels = append(c.Channels, Channel{Channel: m.Channel, Type: m.Type, Values: constant(m.Values)})
+		}
+
+		if err := c.Setup(); err != nil {
+			t.Errorf("%v: %v", v.payload, err)
+			continue
+		}
+
+		payload, err := c.Encode(time.Now(), loc.Location{})
+		if err != nil {
+			t.Errorf("%v: %v", v.payload, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(payload); got != v.payload {
+			t.Errorf("Encode = %v, want %v", got, v.payload)
+		}
+
+		measures, err := Decode(payload) // round trip
+		if err != nil || len(measures) != len(v.measures) {
+			t.Errorf("Decode(Encode) of %v = %v, %v", v.payload, measures, err)
+		}
+	}
+}
+
+func TestEncodeFormats(t *testing.T) {
+
+	channels := []Channel{
+		{Channel: 3, Type: TypeTemperature, Values: constant([]float64{27.2})},
+		{Channel: 4, Type: TypeBattery, Values: constant([]float64{87})},
+		{Channel: 5, Type: TypeGPS}, // the location of the device
+	}
+
+	location := loc.Location{Latitude: 42.3519, Longitude: -87.9094, Altitude: 10}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatCayenneLPP, "0367011004785705" + "88" + "06765ff2960a0003e8"},
+		{FormatTLV, "67020110" + "780157" + "8809" + "06765ff2960a0003e8"},
+		{FormatTV, "670110" + "7857" + "88" + "06765ff2960a0003e8"},
+	}
+
+	for _, test := range tests {
+
+		c := Codec{Format: test.format, Channels: channels}
+		if err := c.Setup(); err != nil {
+			t.Errorf("%v: %v", test.format, err)
+			continue
+		}
+
+		payload, err := c.Encode(time.Now(), location)
+		if err != nil {
+			t.Errorf("%v: %v", test.format, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(payload); got != test.want {
+			t.Errorf("%v = %v, want %v", test.format, got, test.want)
+		}
+	}
+}
+
+func TestPackValue(t *testing.T) {
+
+	tests := []struct {
+		value      float64
+		resolution float64
+		size       int
+		signed     bool
+		want       []byte
+		clamped    bool
+	}{
+		{-4.1, 0.1, 2, true, []byte{0xff, 0xd7}, false},
+		{27.25, 0.1, 2, true, []byte{0x01, 0x11}, false}, // rounded away from zero
+		{4000, 0.1, 2, true, []byte{0x7f, 0xff}, true},
+		{-1, 1, 1, false, []byte{0x00}, true},
+		{300, 1, 1, false, []byte{0xff}, true},
+		{-87.9094, 0.0001, 3, true, []byte{0xf2, 0x96, 0x0a}, false},
+	}
+
+	for _, test := range tests {
+
+		got := packValue(test.value, test.resolution, test.size, test.signed)
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("packValue(%v, %v, %v, %v) = % x, want % x", test.value, test.resolution, test.size, test.signed, got, test.want)
+		}
+
+		if !test.clamped {
+			if v := unpackValue(got, test.resolution, test.signed); math.Abs(v-test.value) > test.resolution {
+				t.Errorf("unpackValue(% x) = %v, want %v", got, v, test.value)
+			}
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+
+	for _, payload := range []string{"03", "0367", "036701", "03ff0000"} {
+
+		b, _ := hex.DecodeString(payload)
+		if _, err := Decode(b); err == nil {
+			t.Errorf("Decode(%v) accepted", payload)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		codec Codec
+		valid bool
+	}{
+		{"gps without values", Codec{Format: FormatTLV, Channels: []Channel{{Type: TypeGPS}}}, true},
+		{"format", Codec{Format: "json", Channels: []Channel{{Type: TypeGPS}}}, false},
+		{"no channels", Codec{Format: FormatCayenneLPP}, false},
+		{"type", Codec{Format: FormatCayenneLPP, Channels: []Channel{{Type: "wind", Values: constant([]float64{1})}}}, false},
+		{"axes", Codec{Format: FormatCayenneLPP, Channels: []Channel{{Type: TypeAccelerometer, Values: constant([]float64{1})}}}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.codec.Setup(); (err == nil) != test.valid {
+			t.Errorf("%v: Setup() = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+
+	c := Codec{
+		Format: FormatCayenneLPP,
+		Channels: []Channel{
+			{Channel: 1, Type: TypeDigitalOutput, Values: constant([]float64{0})},
+			{Channel: 2, Type: TypeTemperature, Values: constant([]float64{20})},
+		},
+	}
+
+	if err := c.Setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	command, _ := hex.DecodeString("010101" + "026700c8") // output on, temperature is a sensor
+	measures, err := Decode(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := c.Apply(measures)
+	if len(applied) != 1 || applied[0].Channel != 1 {
+		t.Fatalf("Apply = %+v, want only the digital output", applied)
+	}
+
+	payload, err := c.Encode(time.Now(), loc.Location{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hex.EncodeToString(payload); got != "010101"+"026700c8" {
+		t.Errorf("Encode after Apply = %v", got)
+	}
+}
@@ -0,0 +1,110 @@
+package codec
+
+const (
+	TypeDigitalInput  = "digitalInput"
+	TypeDigitalOutput = "digitalOutput"
+	TypeAnalogInput   = "analogInput"
+	TypeAnalogOutput  = "analogOutput"
+	TypeIlluminance   = "illuminance"
+	TypePresence      = "presence"
+	TypeTemperature   = "temperature"
+	TypeHumidity      = "humidity"
+	TypeAccelerometer = "accelerometer"
+	TypeBarometer     = "barometer"
+	TypeVoltage       = "voltage"
+	TypeBattery       = "battery" // percentage
+	TypeGyrometer     = "gyrometer"
+	TypeGPS           = "gps"
+)
+
+// measureType describes how a measure is packed: each axis takes Size bytes with Resolution
+type measureType struct {
+	LPP        uint8
+	Axes       int
+	Size       int
+	Resolution []float64 // per axis
+	Signed     bool
+	Actuator   bool
+}
+
+var measureTypes = map[string]measureType{
+	TypeDigitalInput:  {LPP: 0, Axes: 1, Size: 1, Resolution: []float64{1}},
+	TypeDigitalOutput: {LPP: 1, Axes: 1, Size: 1, Resolution: []float64{1}, Actuator: true},
+	TypeAnalogInput:   {LPP: 2, Axes: 1, Size: 2, Resolution: []float64{0.01}, Signed: true},
+	TypeAnalogOutput:  {LPP: 3, Axes: 1, Size: 2, Resolution: []float64{0.01}, Signed: true, Actuator: true},
+	TypeIlluminance:   {LPP: 101, Axes: 1, Size: 2, Resolution: []float64{1}},
+	TypePresence:      {LPP: 102, Axes: 1, Size: 1, Resolution: []float64{1}},
+	TypeTemperature:   {LPP: 103, Axes: 1, Size: 2, Resolution: []float64{0.1}, Signed: true},
+	TypeHumidity:      {LPP: 104, Axes: 1, Size: 1, Resolution: []float64{0.5}},
+	TypeAccelerometer: {LPP: 113, Axes: 3, Size: 2, Resolution: []float64{0.001, 0.001, 0.001}, Signed: true},
+	TypeBarometer:     {LPP: 115, Axes: 1, Size: 2, Resolution: []float64{0.1}},
+	TypeVoltage:       {LPP: 116, Axes: 1, Size: 2, Resolution: []float64{0.01}},
+	TypeBattery:       {LPP: 120, Axes: 1, Size: 1, Resolution: []float64{1}},
+	TypeGyrometer:     {LPP: 134, Axes: 3, Size: 2, Resolution: []float64{0.01, 0.01, 0.01}, Signed: true},
+	TypeGPS:           {LPP: 136, Axes: 3, Size: 3, Resolution: []float64{0.0001, 0.0001, 0.01}, Signed: true},
+}
+
+func typeByLPP(lpp uint8) (string, measureType, bool) {
+
+	for name, t := range measureTypes {
+		if t.LPP == lpp {
+			return name, t, true
+		}
+	}
+
+	return "", measureType{}, false
+}
+
+// packValue writes value with resolution in size bytes, big endian
+func packValue(value float64, resolution float64, size int, signed bool) []byte {
+
+	raw := int64(round(value / resolution))
+
+	bits := uint(8 * size)
+	max := int64(1)<<bits - 1
+	min := int64(0)
+	if signed {
+		max = int64(1)<<(bits-1) - 1
+		min = -int64(1) << (bits - 1)
+	}
+
+	if raw > max {
+		raw = max
+	} else if raw < min {
+		raw = min
+	}
+
+	b := make([]byte, size)
+	u := uint64(raw)
+	for i := size - 1; i >= 0; i-- {
+		b[i] = byte(u)
+		u >>= 8
+	}
+
+	return b
+}
+
+// unpackValue reads a big endian value of size bytes
+func unpackValue(b []byte, resolution float64, signed bool) float64 {
+
+	var u uint64
+	for _, x := range b {
+		u = u<<8 | uint64(x)
+	}
+
+	raw := int64(u)
+	if signed && len(b) > 0 && b[0]&0x80 != 0 {
+		raw -= int64(1) << uint(8*len(b))
+	}
+
+	return float64(raw) * resolution
+}
+
+func round(v float64) float64 {
+
+	if v < 0 {
+		return float64(int64(v - 0.5))
+	}
+
+	return float64(int64(v + 0.5))
+}
@@ -0,0 +1,158 @@
+package datasource
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatJSONL  = "jsonl"
+	FormatBinary = "binary" // fixed size chunks
+
+	PolicyLoop = "loop" // restart from the first record
+	PolicyStop = "stop" // no more uplinks
+	PolicyHold = "hold" // repeat the last record
+
+	EncodingHex    = "hex"
+	EncodingBase64 = "base64"
+	EncodingText   = "text"
+)
+
+// Record is a payload of the time series
+type Record struct {
+	Time    time.Time
+	Payload []byte
+}
+
+// DataSource replays a file record by record
+type DataSource struct {
+	Format string `json:"format"`
+	Path   string `json:"path"`
+	Policy string `json:"policy"`
+
+	ChunkSize int `json:"chunkSize,omitempty"` // binary
+
+	Column     string  `json:"column,omitempty"`     // csv/jsonl field with the payload
+	Encoding   string  `json:"encoding,omitempty"`   // payload's encoding (hex, base64, text), hex default
+	TimeColumn string  `json:"timeColumn,omitempty"` // csv/jsonl field with the timestamp, empty uses SendInterval
+	TimeLayout string  `json:"timeLayout,omitempty"` // Go layout, "unix" or "unixms", RFC3339 default
+	Speed      float64 `json:"speed,omitempty"`      // replay speed factor of timestamps, 1 default
+	SendHex    bool    `json:"sendHex,omitempty"`    // payload is sent as hex text
+
+	Position int `json:"position"` // next record to send, saved with the device
+
+	records []Record
+}
+
+// Load reads all records of the file
+func (d *DataSource) Load() error {
+
+	file, err := os.Open(d.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch d.Policy {
+	case PolicyLoop, PolicyStop, PolicyHold:
+	case "":
+		d.Policy = PolicyLoop
+	default:
+		return fmt.Errorf("Policy %v not supported", d.Policy)
+	}
+
+	switch d.Format {
+
+	case FormatCSV:
+		d.records, err = readCSV(file, d)
+
+	case FormatJSONL:
+		d.records, err = readJSONL(file, d)
+
+	case FormatBinary:
+		d.records, err = readBinary(file, d.ChunkSize)
+
+	default:
+		return fmt.Errorf("Format %v not supported", d.Format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(d.records) == 0 {
+		return errors.New("Data source without records")
+	}
+
+	if d.Position < 0 || d.Position > len(d.records) {
+		d.Position = 0
+	}
+
+	return nil
+}
+
+// Len returns the number of records
+func (d *DataSource) Len() int {
+	return len(d.records)
+}
+
+// Exhausted is true when the stop policy has no more records
+func (d *DataSource) Exhausted() bool {
+	return d.Policy == PolicyStop && d.Position >= len(d.records)
+}
+
+// Next returns the record to send and moves to the following one
+func (d *DataSource) Next() (Record, bool) {
+
+	if len(d.records) == 0 {
+		return Record{}, false
+	}
+
+	if d.Position >= len(d.records) {
+
+		switch d.Policy {
+
+		case PolicyLoop:
+			d.Position = 0
+
+		case PolicyHold:
+			d.Position = len(d.records) - 1
+
+		default:
+			return Record{}, false
+		}
+
+	}
+
+	record := d.records[d.Position]
+	d.Position++
+
+	if d.SendHex {
+		record.Payload = []byte(hex.EncodeToString(record.Payload))
+	}
+
+	return record, true
+}
+
+// Interval returns the time until the next record, defaultInterval if the file has no timestamps
+func (d *DataSource) Interval(defaultInterval time.Duration) time.Duration {
+
+	if d.TimeColumn == "" || d.Position <= 0 || d.Position >= len(d.records) {
+		return defaultInterval
+	}
+
+	delta := d.records[d.Position].Time.Sub(d.records[d.Position-1].Time)
+	if delta <= 0 {
+		return defaultInterval
+	}
+
+	if d.Speed > 0 {
+		delta = time.Duration(float64(delta) / d.Speed)
+	}
+
+	return delta
+}
@@ -0,0 +1,261 @@
+package datasource
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// write returns the path of a temporary file with content
+func write(t *testing.T, name string, content string) string {
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func payloads(records []Record) []string {
+
+	var list []string
+	for _, r := range records {
+		list = append(list, string(r.Payload))
+	}
+
+	return list
+}
+
+func TestReaders(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		source  DataSource
+		content string
+		want    []string
+		times   []time.Time
+	}{
+		{
+			name:    "csv hex",
+			source:  DataSource{Format: FormatCSV, Column: "payload"},
+			content: "id, payload\n1, 6869\n2, 212a\n",
+			want:    []string{"hi", "!*"},
+		},
+		{
+			name:    "csv text with RFC 3339 times",
+			source:  DataSource{Format: FormatCSV, Column: "value", Encoding: EncodingText, TimeColumn: "time"},
+			content: "time,value\n2024-01-01T00:00:00Z,a\n2024-01-01T00:00:30Z,b\n",
+			want:    []string{"a", "b"},
+			times: []time.Time{
+				time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC),
+			},
+		},
+		{
+			name:    "jsonl base64 with unix ms",
+			source:  DataSource{Format: FormatJSONL, Column: "data", Encoding: EncodingBase64, TimeColumn: "ts", TimeLayout: "unixms"},
+			content: "{\"data\": \"aGk=\", \"ts\": 1704067200000}\n\n{\"data\": \"IWI=\", \"ts\": 1704067201500}\n",
+			want:    []string{"hi", "!b"},
+			times: []time.Time{
+				time.Unix(1704067200, 0),
+				time.Unix(1704067201, 500000000),
+			},
+		},
+		{
+			name:    "jsonl with a custom layout",
+			source:  DataSource{Format: FormatJSONL, Column: "p", TimeColumn: "t", TimeLayout: "2006-01-02 15:04"},
+			content: "{\"p\": \"41\", \"t\": \"2024-03-01 10:00\"}\n",
+			want:    []string{"A"},
+			times:   []time.Time{time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "binary chunks",
+			source:  DataSource{Format: FormatBinary, ChunkSize: 3},
+			content: "abcdefgh",
+			want:    []string{"abc", "def", "gh"},
+		},
+	}
+
+	for _, test := range tests {
+
+		var records []Record
+		var err error
+
+		r := strings.NewReader(test.content)
+
+		switch test.source.Format {
+		case FormatCSV:
+			records, err = readCSV(r, &test.source)
+		case FormatJSONL:
+			records, err = readJSONL(r, &test.source)
+		case FormatBinary:
+			records, err = readBinary(r, test.source.ChunkSize)
+		}
+
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if got := payloads(records); strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%v: payloads %q, want %q", test.name, got, test.want)
+		}
+
+		for i, want := range test.times {
+			if i < len(records) && !records[i].Time.Equal(want) {
+				t.Errorf("%v: time %v = %v, want %v", test.name, i, records[i].Time, want)
+			}
+		}
+	}
+}
+
+func TestReadersErrors(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		source  DataSource
+		content string
+	}{
+		{"csv column", DataSource{Format: FormatCSV, Column: "payload"}, "id,data\n1,00\n"},
+		{"csv time column", DataSource{Format: FormatCSV, Column: "data", TimeColumn: "time"}, "id,data\n1,00\n"},
+		{"csv hex", DataSource{Format: FormatCSV, Column: "data"}, "data\nzz\n"},
+		{"csv time", DataSource{Format: FormatCSV, Column: "data", TimeColumn: "t"}, "data,t\n00,yesterday\n"},
+		{"jsonl syntax", DataSource{Format: FormatJSONL, Column: "data"}, "{\"data\": \n"},
+		{"jsonl field", DataSource{Format: FormatJSONL, Column: "data"}, "{\"payload\": \"00\"}\n"},
+		{"jsonl encoding", DataSource{Format: FormatJSONL, Column: "data", Encoding: "ascii85"}, "{\"data\": \"00\"}\n"},
+		{"binary chunk size", DataSource{Format: FormatBinary}, "abc"},
+	}
+
+	for _, test := range tests {
+
+		var err error
+
+		r := strings.NewReader(test.content)
+
+		switch test.source.Format {
+		case FormatCSV:
+			_, err = readCSV(r, &test.source)
+		case FormatJSONL:
+			_, err = readJSONL(r, &test.source)
+		case FormatBinary:
+			_, err = readBinary(r, test.source.ChunkSize)
+		}
+
+		if err == nil {
+			t.Errorf("%v accepted", test.name)
+		}
+	}
+}
+
+func TestPolicies(t *testing.T) {
+
+	path := write(t, "data.csv", "payload\n01\n02\n03\n")
+
+	tests := []struct {
+		policy string
+		want   []string // payloads of 5 uplinks, "" none
+	}{
+		{PolicyLoop, []string{"01", "02", "03", "01", "02"}},
+		{"", []string{"01", "02", "03", "01", "02"}}, // loop by default
+		{PolicyStop, []string{"01", "02", "03", "", ""}},
+		{PolicyHold, []string{"01", "02", "03", "03", "03"}},
+	}
+
+	for _, test := range tests {
+
+		d := DataSource{Format: FormatCSV, Path: path, Column: "payload", Policy: test.policy, SendHex: true}
+		if err := d.Load(); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, want := range test.want {
+
+			record, ok := d.Next()
+			if got := string(record.Payload); ok != (want != "") || got != want {
+				t.Errorf("%v: uplink %v = %q %v, want %q", test.policy, i, got, ok, want)
+			}
+		}
+
+		if exhausted := d.Exhausted(); exhausted != (test.policy == PolicyStop) {
+			t.Errorf("%v: Exhausted() = %v", test.policy, exhausted)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	d := DataSource{Format: FormatBinary, Path: write(t, "data.bin", "\x01\x02\x03\x04"), ChunkSize: 2, Position: 7}
+	if err := d.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Len() != 2 || d.Position != 0 { // a position beyond the file restarts
+		t.Errorf("Len() = %v, Position = %v", d.Len(), d.Position)
+	}
+
+	if record, _ := d.Next(); !bytes.Equal(record.Payload, []byte{1, 2}) {
+		t.Errorf("Next() = % x", record.Payload)
+	}
+
+	invalid := []DataSource{
+		{Format: FormatCSV, Path: filepath.Join(t.TempDir(), "missing.csv"), Column: "payload"},
+		{Format: "xml", Path: d.Path},
+		{Format: FormatBinary, Path: d.Path, ChunkSize: 2, Policy: "rewind"},
+		{Format: FormatCSV, Path: write(t, "empty.csv", "payload\n"), Column: "payload"},
+	}
+
+	for _, source := range invalid {
+		if err := source.Load(); err == nil {
+			t.Errorf("Load(%+v) accepted", source)
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+
+	path := write(t, "data.csv", "t,payload\n0,01\n10,02\n10,03\n40,04\n")
+	def := time.Minute
+
+	tests := []struct {
+		speed float64
+		want  []time.Duration // after each of the first 4 records
+	}{
+		{0, []time.Duration{10 * time.Second, def, 30 * time.Second, def}}, // no delta, then the end
+		{10, []time.Duration{time.Second, def, 3 * time.Second, def}},
+	}
+
+	for _, test := range tests {
+
+		d := DataSource{Format: FormatCSV, Path: path, Column: "payload", TimeColumn: "t", TimeLayout: "unix", Speed: test.speed, Policy: PolicyStop}
+		if err := d.Load(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := d.Interval(def); got != def {
+			t.Errorf("speed %v: interval before the first record = %v", test.speed, got)
+		}
+
+		for i, want := range test.want {
+
+			d.Next()
+
+			if got := d.Interval(def); got != want {
+				t.Errorf("speed %v: interval after record %v = %v, want %v", test.speed, i, got, want)
+			}
+		}
+	}
+
+	d := DataSource{Format: FormatCSV, Path: path, Column: "payload"} // without times
+	if err := d.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Next()
+	if got := d.Interval(def); got != def {
+		t.Errorf("interval without times = %v, want %v", got, def)
+	}
+}
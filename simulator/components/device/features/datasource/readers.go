@@ -0,0 +1,209 @@
+package datasource
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func readCSV(r io.Reader, d *DataSource) ([]Record, error) {
+
+	var records []Record
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	indexPayload, ok := columns[d.Column]
+	if !ok {
+		return nil, fmt.Errorf("Column %v not found", d.Column)
+	}
+
+	indexTime := -1
+	if d.TimeColumn != "" {
+
+		indexTime, ok = columns[d.TimeColumn]
+		if !ok {
+			return nil, fmt.Errorf("Column %v not found", d.TimeColumn)
+		}
+
+	}
+
+	for line := 2; ; line++ {
+
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var record Record
+
+		record.Payload, err = decodePayload(row[indexPayload], d.Encoding)
+		if err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		}
+
+		if indexTime >= 0 {
+			record.Time, err = parseTime(row[indexTime], d.TimeLayout)
+			if err != nil {
+				return nil, fmt.Errorf("Line %v: %v", line, err)
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func readJSONL(r io.Reader, d *DataSource) ([]Record, error) {
+
+	var records []Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		}
+
+		var record Record
+		var err error
+
+		value, ok := row[d.Column]
+		if !ok {
+			return nil, fmt.Errorf("Line %v: field %v not found", line, d.Column)
+		}
+
+		record.Payload, err = decodePayload(fmt.Sprintf("%v", value), d.Encoding)
+		if err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		}
+
+		if d.TimeColumn != "" {
+
+			value, ok := row[d.TimeColumn]
+			if !ok {
+				return nil, fmt.Errorf("Line %v: field %v not found", line, d.TimeColumn)
+			}
+
+			text := fmt.Sprintf("%v", value)
+			if number, ok := value.(float64); ok {
+				text = strconv.FormatFloat(number, 'f', -1, 64)
+			}
+
+			record.Time, err = parseTime(text, d.TimeLayout)
+			if err != nil {
+				return nil, fmt.Errorf("Line %v: %v", line, err)
+			}
+
+		}
+
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+func readBinary(r io.Reader, chunkSize int) ([]Record, error) {
+
+	var records []Record
+
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("Invalid chunk size %v", chunkSize)
+	}
+
+	for {
+
+		chunk := make([]byte, chunkSize)
+
+		n, err := io.ReadFull(r, chunk)
+		if n > 0 {
+			records = append(records, Record{Payload: chunk[:n]})
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	return records, nil
+}
+
+func decodePayload(value string, encoding string) ([]byte, error) {
+
+	value = strings.TrimSpace(value)
+
+	switch encoding {
+
+	case EncodingHex, "":
+		return hex.DecodeString(value)
+
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(value)
+
+	case EncodingText:
+		return []byte(value), nil
+
+	}
+
+	return nil, fmt.Errorf("Encoding %v not supported", encoding)
+}
+
+func parseTime(value string, layout string) (time.Time, error) {
+
+	value = strings.TrimSpace(value)
+
+	switch layout {
+
+	case "unix", "unixms":
+
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if layout == "unixms" {
+			return time.Unix(0, int64(number*float64(time.Millisecond))), nil
+		}
+
+		return time.Unix(0, int64(number*float64(time.Second))), nil
+
+	case "":
+		return time.Parse(time.RFC3339, value)
+
+	}
+
+	return time.Parse(layout, value)
+}
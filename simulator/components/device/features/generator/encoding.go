@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	EncodingInt8      = "int8"
+	EncodingUint8     = "uint8"
+	EncodingInt16BE   = "int16be"
+	EncodingInt16LE   = "int16le"
+	EncodingUint16BE  = "uint16be"
+	EncodingUint16LE  = "uint16le"
+	EncodingInt32BE   = "int32be"
+	EncodingInt32LE   = "int32le"
+	EncodingUint32BE  = "uint32be"
+	EncodingUint32LE  = "uint32le"
+	EncodingFloat32BE = "float32be"
+	EncodingFloat32LE = "float32le"
+	EncodingBCD       = "bcd" // bcd<n> packs n bytes, 2 digits each (eg. bcd4)
+)
+
+//Encode converts a value in bytes
+func Encode(value float64, encoding string) ([]byte, error) {
+
+	rounded := math.Round(value)
+	encoding = strings.ToLower(encoding)
+
+	switch encoding {
+
+	case EncodingInt8:
+		return []byte{byte(int8(clamp(rounded, math.MinInt8, math.MaxInt8)))}, nil
+
+	case EncodingUint8, "":
+		return []byte{uint8(clamp(rounded, 0, math.MaxUint8))}, nil
+
+	case EncodingInt16BE, EncodingInt16LE:
+		v := uint16(int16(clamp(rounded, math.MinInt16, math.MaxInt16)))
+		return put16(v, encoding == EncodingInt16LE), nil
+
+	case EncodingUint16BE, EncodingUint16LE:
+		v := uint16(clamp(rounded, 0, math.MaxUint16))
+		return put16(v, encoding == EncodingUint16LE), nil
+
+	case EncodingInt32BE, EncodingInt32LE:
+		v := uint32(int32(clamp(rounded, math.MinInt32, math.MaxInt32)))
+		return put32(v, encoding == EncodingInt32LE), nil
+
+	case EncodingUint32BE, EncodingUint32LE:
+		v := uint32(clamp(rounded, 0, math.MaxUint32))
+		return put32(v, encoding == EncodingUint32LE), nil
+
+	case EncodingFloat32BE, EncodingFloat32LE:
+		return put32(math.Float32bits(float32(value)), encoding == EncodingFloat32LE), nil
+
+	}
+
+	if strings.HasPrefix(encoding, EncodingBCD) {
+
+		size := 1
+		if len(encoding) > len(EncodingBCD) {
+
+			n, err := strconv.Atoi(encoding[len(EncodingBCD):])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("Invalid encoding %v", encoding)
+			}
+			size = n
+
+		}
+
+		return bcd(rounded, size), nil
+	}
+
+	return nil, fmt.Errorf("Encoding %v not supported", encoding)
+}
+
+func clamp(value float64, min float64, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
+
+func put16(v uint16, littleEndian bool) []byte {
+
+	b := make([]byte, 2)
+	if littleEndian {
+		binary.LittleEndian.PutUint16(b, v)
+	} else {
+		binary.BigEndian.PutUint16(b, v)
+	}
+
+	return b
+}
+
+func put32(v uint32, littleEndian bool) []byte {
+
+	b := make([]byte, 4)
+	if littleEndian {
+		binary.LittleEndian.PutUint32(b, v)
+	} else {
+		binary.BigEndian.PutUint32(b, v)
+	}
+
+	return b
+}
+
+func bcd(value float64, size int) []byte {
+
+	b := make([]byte, size)
+	v := uint64(clamp(value, 0, math.Pow(10, float64(2*size))-1))
+
+	for i := size - 1; i >= 0; i-- {
+		low := v % 10
+		v /= 10
+		high := v % 10
+		v /= 10
+		b[i] = byte(high<<4 | low)
+	}
+
+	return b
+}
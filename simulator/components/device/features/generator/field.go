@@ -0,0 +1,151 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	SignalConstant = "constant"
+	SignalCounter  = "counter"
+	SignalUniform  = "uniform"
+	SignalNormal   = "normal"
+	SignalWalk     = "walk"     // bounded random walk
+	SignalSine     = "sine"     // periodic signal
+	SignalSeasonal = "seasonal" // daily signal, peak at Phase hours local time
+)
+
+//Field is a value of the payload
+type Field struct {
+	Name     string `json:"name"`
+	Signal   string `json:"signal"`
+	Encoding string `json:"encoding"`
+
+	Value  float64 `json:"value,omitempty"`  // constant
+	Start  float64 `json:"start,omitempty"`  // counter, walk
+	Step   float64 `json:"step,omitempty"`   // counter, walk (max step)
+	Min    float64 `json:"min,omitempty"`    // uniform, walk, counter (wrap)
+	Max    float64 `json:"max,omitempty"`    // uniform, walk, counter (wrap)
+	Mean   float64 `json:"mean,omitempty"`   // normal
+	StdDev float64 `json:"stdDev,omitempty"` // normal, sine and seasonal noise
+
+	Amplitude float64 `json:"amplitude,omitempty"` // sine, seasonal
+	Offset    float64 `json:"offset,omitempty"`    // sine, seasonal
+	Period    int     `json:"period,omitempty"`    // sine (s)
+	Phase     float64 `json:"phase,omitempty"`     // sine (s), seasonal (h)
+
+	Scale float64 `json:"scale,omitempty"` // value is multiplied before encoding (eg. 100 for 0.01 resolution)
+
+	current     float64
+	initialized bool
+}
+
+//Validate checks the parameters of the signal
+func (f *Field) Validate() error {
+
+	switch f.Signal {
+
+	case SignalConstant, SignalCounter, SignalUniform, SignalNormal, SignalSeasonal:
+
+	case SignalWalk:
+		if f.Max < f.Min {
+			return fmt.Errorf("Field %v: max must be greater than min", f.Name)
+		}
+
+	case SignalSine:
+		if f.Period <= 0 {
+			return fmt.Errorf("Field %v: period must be positive", f.Name)
+		}
+
+	default:
+		return fmt.Errorf("Field %v: signal %v not supported", f.Name, f.Signal)
+	}
+
+	if _, err := Encode(0, f.Encoding); err != nil {
+		return fmt.Errorf("Field %v: %v", f.Name, err)
+	}
+
+	return nil
+}
+
+//Next evaluates the signal at the given time
+func (f *Field) Next(now time.Time, random *rand.Rand) float64 {
+
+	switch f.Signal {
+
+	case SignalConstant:
+		f.current = f.Value
+
+	case SignalCounter:
+
+		if !f.initialized {
+			f.current = f.Start
+		} else {
+			f.current += f.step()
+		}
+
+		if f.Max > f.Min && f.current > f.Max {
+			f.current = f.Min
+		}
+
+	case SignalUniform:
+		f.current = f.Min + random.Float64()*(f.Max-f.Min)
+
+	case SignalNormal:
+		f.current = f.Mean + random.NormFloat64()*f.StdDev
+
+	case SignalWalk:
+
+		if !f.initialized {
+			f.current = f.Start
+		} else {
+			f.current += (random.Float64()*2 - 1) * f.step()
+		}
+
+		f.current = clamp(f.current, f.Min, f.Max)
+
+	case SignalSine:
+
+		seconds := float64(now.Unix()) + f.Phase
+		angle := 2 * math.Pi * seconds / float64(f.Period)
+
+		f.current = f.Offset + f.Amplitude*math.Sin(angle) + random.NormFloat64()*f.StdDev
+
+	case SignalSeasonal:
+
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		hours := now.Sub(midnight).Hours() - f.Phase
+		angle := 2 * math.Pi * hours / 24
+
+		f.current = f.Offset + f.Amplitude*math.Cos(angle) + random.NormFloat64()*f.StdDev
+	}
+
+	f.initialized = true
+
+	return f.current
+}
+
+//Bytes returns the encoded value of the last evaluation
+func (f *Field) Bytes() ([]byte, error) {
+	return Encode(f.scaled(), f.Encoding)
+}
+
+func (f *Field) scaled() float64 {
+
+	if f.Scale == 0 {
+		return f.current
+	}
+
+	return f.current * f.Scale
+}
+
+func (f *Field) step() float64 {
+
+	if f.Step == 0 {
+		return 1
+	}
+
+	return f.Step
+}
@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Generator builds a new payload at each uplink
+type Generator struct {
+	Fields []Field `json:"fields"`
+
+	// Template, if set, replaces the concatenation of fields.
+	// Fields are available by name, already scaled (eg. {{.temperature}}) and every encoding is a function
+	// returning the value in hex (eg. {{int16be .temperature}})
+	Template string `json:"template,omitempty"`
+	Hex      bool   `json:"hex,omitempty"` // template's output is hex encoded
+
+	tmpl   *template.Template
+	random *rand.Rand
+	values map[string]float64
+}
+
+// Setup validates the generator and compiles its template
+func (g *Generator) Setup() error {
+
+	g.random = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+
+	names := make(map[string]bool)
+	for i := range g.Fields {
+
+		if err := g.Fields[i].Validate(); err != nil {
+			return err
+		}
+
+		if names[g.Fields[i].Name] {
+			return fmt.Errorf("Field %v already defined", g.Fields[i].Name)
+		}
+		names[g.Fields[i].Name] = true
+
+	}
+
+	if g.Template == "" {
+
+		if len(g.Fields) == 0 {
+			return errors.New("Generator without fields")
+		}
+
+		g.tmpl = nil
+		return nil
+	}
+
+	tmpl, err := template.New("payload").Funcs(templateFuncs()).Parse(g.Template)
+	if err != nil {
+		return err
+	}
+
+	g.tmpl = tmpl
+
+	return nil
+}
+
+// Next evaluates all fields and returns the payload
+func (g *Generator) Next(now time.Time) ([]byte, error) {
+
+	if g.random == nil {
+		if err := g.Setup(); err != nil {
+			return nil, err
+		}
+	}
+
+	values := make(map[string]float64)
+	var payload []byte
+
+	for i := range g.Fields {
+
+		g.Fields[i].Next(now, g.random)
+		values[g.Fields[i].Name] = g.Fields[i].scaled()
+
+		if g.tmpl != nil {
+			continue
+		}
+
+		b, err := g.Fields[i].Bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		payload = append(payload, b...)
+	}
+
+	g.values = values
+
+	if g.tmpl == nil {
+		return payload, nil
+	}
+
+	var buf bytes.Buffer
+	if err := g.tmpl.Execute(&buf, values); err != nil {
+		return nil, err
+	}
+
+	if !g.Hex {
+		return buf.Bytes(), nil
+	}
+
+	return hex.DecodeString(strings.Join(strings.Fields(buf.String()), ""))
+}
+
+// Values returns the scaled values of the last payload
+func (g *Generator) Values() map[string]float64 {
+	return g.values
+}
+
+func templateFuncs() template.FuncMap {
+
+	funcs := template.FuncMap{
+		"scale": func(factor float64, value float64) float64 { return factor * value },
+		"hex":   func(s string) string { return hex.EncodeToString([]byte(s)) },
+	}
+
+	encodings := []string{EncodingInt8, EncodingUint8, EncodingInt16BE, EncodingInt16LE,
+		EncodingUint16BE, EncodingUint16LE, EncodingInt32BE, EncodingInt32LE,
+		EncodingUint32BE, EncodingUint32LE, EncodingFloat32BE, EncodingFloat32LE}
+
+	for _, encoding := range encodings {
+
+		enc := encoding
+		funcs[enc] = func(value float64) (string, error) {
+
+			b, err := Encode(value, enc)
+			if err != nil {
+				return "", err
+			}
+
+			return hex.EncodeToString(b), nil
+		}
+
+	}
+
+	funcs[EncodingBCD] = func(size int, value float64) (string, error) {
+
+		b, err := Encode(value, fmt.Sprintf("%v%v", EncodingBCD, size))
+		if err != nil {
+			return "", err
+		}
+
+		return hex.EncodeToString(b), nil
+	}
+
+	return funcs
+}
@@ -0,0 +1,177 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+
+	tests := []struct {
+		value    float64
+		encoding string
+		want     []byte
+	}{
+		{-2, EncodingInt8, []byte{0xfe}},
+		{300, EncodingUint8, []byte{0xff}}, // clamped
+		{-1, EncodingUint8, []byte{0x00}},
+		{12.6, "", []byte{0x0d}}, // rounded, uint8 by default
+		{-1234, EncodingInt16BE, []byte{0xfb, 0x2e}},
+		{-1234, EncodingInt16LE, []byte{0x2e, 0xfb}},
+		{0x1234, EncodingUint16BE, []byte{0x12, 0x34}},
+		{0x1234, "UINT16LE", []byte{0x34, 0x12}},
+		{-100000, EncodingInt32BE, []byte{0xff, 0xfe, 0x79, 0x60}},
+		{0x01020304, EncodingUint32LE, []byte{0x04, 0x03, 0x02, 0x01}},
+		{1.5, EncodingFloat32BE, []byte{0x3f, 0xc0, 0x00, 0x00}},
+		{1.5, EncodingFloat32LE, []byte{0x00, 0x00, 0xc0, 0x3f}},
+		{42, EncodingBCD, []byte{0x42}},
+		{12345678, "bcd4", []byte{0x12, 0x34, 0x56, 0x78}},
+		{123, "bcd2", []byte{0x01, 0x23}},
+		{1e9, "bcd2", []byte{0x99, 0x99}}, // clamped
+	}
+
+	for _, test := range tests {
+
+		got, err := Encode(test.value, test.encoding)
+		if err != nil {
+			t.Errorf("Encode(%v, %v): %v", test.value, test.encoding, err)
+			continue
+		}
+
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("Encode(%v, %v) = % x, want % x", test.value, test.encoding, got, test.want)
+		}
+	}
+
+	for _, encoding := range []string{"int64", "bcd0", "bcdx"} {
+		if _, err := Encode(0, encoding); err == nil {
+			t.Errorf("Encode(0, %v) accepted", encoding)
+		}
+	}
+}
+
+func TestFieldValidate(t *testing.T) {
+
+	tests := []struct {
+		field Field
+		valid bool
+	}{
+		{Field{Name: "c", Signal: SignalConstant, Encoding: EncodingUint8}, true},
+		{Field{Name: "w", Signal: SignalWalk, Min: 0, Max: 10}, true},
+		{Field{Name: "w", Signal: SignalWalk, Min: 10, Max: 0}, false},
+		{Field{Name: "s", Signal: SignalSine, Period: 60}, true},
+		{Field{Name: "s", Signal: SignalSine}, false},
+		{Field{Name: "x", Signal: "square"}, false},
+		{Field{Name: "e", Signal: SignalConstant, Encoding: "int64"}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.field.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", test.field, err, test.valid)
+		}
+	}
+}
+
+func TestGeneratorFields(t *testing.T) {
+
+	g := Generator{
+		Fields: []Field{
+			{Name: "counter", Signal: SignalCounter, Encoding: EncodingUint16BE, Start: 9, Step: 1, Min: 0, Max: 10},
+			{Name: "temperature", Signal: SignalConstant, Encoding: EncodingInt16BE, Value: -12.5, Scale: 10},
+		},
+	}
+
+	if err := g.Setup(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]byte{
+		{0x00, 0x09, 0xff, 0x83},
+		{0x00, 0x0a, 0xff, 0x83},
+		{0x00, 0x00, 0xff, 0x83}, // wrapped at max
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, w := range want {
+
+		got, err := g.Next(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, w) {
+			t.Errorf("payload %v = % x, want % x", i, got, w)
+		}
+	}
+
+	if v := g.Values()["temperature"]; v != -125 {
+		t.Errorf("temperature = %v, want the scaled value -125", v)
+	}
+}
+
+func TestGeneratorTemplate(t *testing.T) {
+
+	tests := []struct {
+		template string
+		hex      bool
+		want     []byte
+	}{
+		{`{{int16be .temperature}} {{uint8 .humidity}}`, true, []byte{0x00, 0xd7, 0x41}},
+		{`{{bcd 2 .humidity}}`, true, []byte{0x00, 0x65}},
+		{`{{uint16le (scale 2 .humidity)}}`, true, []byte{0x82, 0x00}},
+		{`T={{.temperature}}`, false, []byte("T=215")},
+	}
+
+	for _, test := range tests {
+
+		g := Generator{
+			Fields: []Field{
+				{Name: "temperature", Signal: SignalConstant, Value: 21.5, Scale: 10},
+				{Name: "humidity", Signal: SignalConstant, Value: 65},
+			},
+			Template: test.template,
+			Hex:      test.hex,
+		}
+
+		if err := g.Setup(); err != nil {
+			t.Errorf("%v: %v", test.template, err)
+			continue
+		}
+
+		got, err := g.Next(time.Now())
+		if err != nil {
+			t.Errorf("%v: %v", test.template, err)
+			continue
+		}
+
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("%v = % x, want % x", test.template, got, test.want)
+		}
+	}
+}
+
+func TestGeneratorSetup(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		generator Generator
+	}{
+		{"no fields", Generator{}},
+		{"duplicate field", Generator{Fields: []Field{
+			{Name: "a", Signal: SignalConstant},
+			{Name: "a", Signal: SignalCounter},
+		}}},
+		{"invalid template", Generator{
+			Fields:   []Field{{Name: "a", Signal: SignalConstant}},
+			Template: "{{int16be .a",
+		}},
+	}
+
+	for _, test := range tests {
+		if err := test.generator.Setup(); err == nil {
+			t.Errorf("%v accepted", test.name)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHistory is the number of downlinks kept by default
+	DefaultHistory = 50
+)
+
+// Downlink is an application downlink received by the device
+type Downlink struct {
+	Time      time.Time `json:"time"`
+	FPort     uint8     `json:"fport"`
+	Payload   []byte    `json:"payload"`
+	Confirmed bool      `json:"confirmed"`
+}
+
+// Handler stores the application downlinks and finds the reactions they trigger
+type Handler struct {
+	History   int        `json:"history"` // downlinks kept, DefaultHistory if 0
+	Reactions []Reaction `json:"reactions,omitempty"`
+
+	Toggled []byte `json:"-"` // payload replaced by togglePayload
+
+	received []Downlink
+	mutex    sync.Mutex
+}
+
+// Setup validates the reactions
+func (h *Handler) Setup() error {
+
+	for i := range h.Reactions {
+		if err := h.Reactions[i].validate(); err != nil {
+			return fmt.Errorf("Reaction %v: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Receive stores the downlink and returns the reactions it triggers
+func (h *Handler) Receive(downlink Downlink) []Reaction {
+
+	h.mutex.Lock()
+
+	size := h.History
+	if size <= 0 {
+		size = DefaultHistory
+	}
+
+	h.received = append(h.received, downlink)
+	if len(h.received) > size {
+		h.received = h.received[len(h.received)-size:]
+	}
+
+	h.mutex.Unlock()
+
+	var triggered []Reaction
+	for _, r := range h.Reactions {
+		if r.matches(downlink) {
+			triggered = append(triggered, r)
+		}
+	}
+
+	return triggered
+}
+
+// GetReceived returns a copy of the stored downlinks, oldest first
+func (h *Handler) GetReceived() []Downlink {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	received := make([]Downlink, len(h.received))
+	copy(received, h.received)
+
+	return received
+}
+
+// MarshalJSON of a received downlink
+func (d *Downlink) MarshalJSON() ([]byte, error) {
+
+	type Alias Downlink
+
+	return json.Marshal(&struct {
+		Payload string `json:"payload"`
+		*Alias
+	}{
+		Payload: hex.EncodeToString(d.Payload),
+		Alias:   (*Alias)(d),
+	})
+}
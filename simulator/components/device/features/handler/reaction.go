@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ActionSetInterval   = "setInterval"   // SendInterval = bytes[Start:Start+Length] (big endian) * Unit
+	ActionSetPayload    = "setPayload"    // Payload = bytes[Start:Start+Length], whole payload if Length is 0
+	ActionTogglePayload = "togglePayload" // swaps Payload and the reaction's payload
+	ActionEcho          = "echo"          // sends back the downlink's payload
+	ActionReboot        = "reboot"
+	ActionRejoin        = "rejoin"
+)
+
+// Match is a condition on a byte of the payload
+type Match struct {
+	Index int   `json:"index"`
+	Value uint8 `json:"value"`
+	Mask  uint8 `json:"mask"` // 0xFF if 0
+}
+
+// Reaction is an action executed when a downlink matches
+type Reaction struct {
+	FPort  uint8   `json:"fport"` // 0 any port
+	Match  []Match `json:"match,omitempty"`
+	Action string  `json:"action"`
+
+	Start   int    `json:"start,omitempty"`
+	Length  int    `json:"length,omitempty"`
+	Unit    int    `json:"unit,omitempty"`    // setInterval: seconds for each unit, 1 default
+	Payload string `json:"payload,omitempty"` // togglePayload: alternative payload (hex)
+}
+
+func (r *Reaction) validate() error {
+
+	switch r.Action {
+
+	case ActionSetInterval:
+		if r.Length <= 0 || r.Length > 4 {
+			return errors.New("setInterval requires a length from 1 to 4 bytes")
+		}
+
+	case ActionTogglePayload:
+		if _, err := hex.DecodeString(r.Payload); err != nil {
+			return fmt.Errorf("togglePayload requires an hex payload: %v", err)
+		}
+
+	case ActionSetPayload, ActionEcho, ActionReboot, ActionRejoin:
+
+	default:
+		return fmt.Errorf("Action %v not supported", r.Action)
+	}
+
+	if r.Start < 0 || r.Length < 0 {
+		return errors.New("Invalid range")
+	}
+
+	return nil
+}
+
+func (r *Reaction) matches(downlink Downlink) bool {
+
+	if r.FPort != 0 && r.FPort != downlink.FPort {
+		return false
+	}
+
+	for _, m := range r.Match {
+
+		if m.Index < 0 || m.Index >= len(downlink.Payload) {
+			return false
+		}
+
+		mask := m.Mask
+		if mask == 0 {
+			mask = 0xFF
+		}
+
+		if downlink.Payload[m.Index]&mask != m.Value&mask {
+			return false
+		}
+
+	}
+
+	return true
+}
+
+// Bytes returns the range of the payload used by the action
+func (r *Reaction) Bytes(payload []byte) ([]byte, error) {
+
+	if r.Length == 0 {
+
+		if r.Start > len(payload) {
+			return nil, errors.New("Payload too short")
+		}
+
+		return payload[r.Start:], nil
+	}
+
+	if r.Start+r.Length > len(payload) {
+		return nil, errors.New("Payload too short")
+	}
+
+	return payload[r.Start : r.Start+r.Length], nil
+}
+
+// Interval returns the send interval carried by the payload
+func (r *Reaction) Interval(payload []byte) (time.Duration, error) {
+
+	bytes, err := r.Bytes(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	value := 0
+	for _, b := range bytes {
+		value = value<<8 | int(b)
+	}
+
+	unit := r.Unit
+	if unit <= 0 {
+		unit = 1
+	}
+
+	if value == 0 {
+		return 0, errors.New("Send interval must be positive")
+	}
+
+	return time.Duration(value*unit) * time.Second, nil
+}
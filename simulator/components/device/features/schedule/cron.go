@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Cron is a parsed cron expression: minute hour day-of-month month day-of-week
+type Cron struct {
+	minute, hour, dom, month, dow uint64 // bitsets of the allowed values
+
+	anyDom, anyDow bool
+}
+
+// ParseCron parses a standard 5-fields expression or a descriptor (eg. @daily)
+func ParseCron(expr string) (*Cron, error) {
+
+	if d, ok := descriptors[strings.TrimSpace(expr)]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Cron %q: expected 5 fields", expr)
+	}
+
+	var c Cron
+	var err error
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := [5]*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+
+	for i, field := range fields {
+		if *sets[i], err = parseField(field, bounds[i][0], bounds[i][1]); err != nil {
+			return nil, fmt.Errorf("Cron %q: %v", expr, err)
+		}
+	}
+
+	if c.dow&(1<<7) != 0 { // 7 is sunday too
+		c.dow |= 1
+	}
+
+	c.anyDom = fields[2] == "*"
+	c.anyDow = fields[4] == "*"
+
+	return &c, nil
+}
+
+func parseField(field string, min int, max int) (uint64, error) {
+
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step %v", part)
+			}
+
+			step = s
+			part = part[:i]
+		}
+
+		low, high := min, max
+
+		if part != "*" {
+
+			bounds := strings.SplitN(part, "-", 2)
+
+			l, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %v", part)
+			}
+
+			low, high = l, l
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %v", part)
+				}
+			} else if step > 1 { // a/s means from a to max
+				high = max
+			}
+
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("value %v out of range [%v-%v]", part, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+
+	}
+
+	return set, nil
+}
+
+// Next returns the first activation strictly after t, in t's location
+func (c *Cron) Next(t time.Time) time.Time {
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	// like Vixie cron: if both are restricted, either matches
+	if !c.anyDom && !c.anyDow {
+		return dom || dow
+	}
+
+	return dom && dow
+}
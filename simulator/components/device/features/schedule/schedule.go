@@ -0,0 +1,191 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule contains the uplinks sent at absolute times or on events, besides the periodic ones
+type Schedule struct {
+	Entries []Entry `json:"entries"`
+	Alarms  []Alarm `json:"alarms"`
+
+	After []string `json:"after"` // names of the devices whose uplinks trigger one of this device
+
+	Location string `json:"location,omitempty"` // IANA time zone of cron and daily entries, local if empty
+
+	location *time.Location
+}
+
+// Entry is an uplink sent at absolute times
+type Entry struct {
+	Name string `json:"name"`
+
+	Cron  string     `json:"cron,omitempty"`  // minute hour day-of-month month day-of-week, or @daily, @hourly...
+	Daily string     `json:"daily,omitempty"` // hh:mm, every day
+	At    *time.Time `json:"at,omitempty"`    // one-shot, RFC3339
+
+	Uplink
+
+	cron *Cron
+	next time.Time
+}
+
+// Alarm sends an uplink when a generated value crosses a threshold
+type Alarm struct {
+	Field string   `json:"field"` // generator's field
+	Above *float64 `json:"above,omitempty"`
+	Below *float64 `json:"below,omitempty"`
+
+	Uplink
+
+	raised bool
+}
+
+// Uplink is the frame sent when an entry or an alarm fires
+type Uplink struct {
+	FPort     uint8  `json:"fport,omitempty"`   // 0: the device's FPort
+	Payload   string `json:"payload,omitempty"` // empty: the device's payload
+	Confirmed bool   `json:"confirmed"`
+}
+
+// Setup validates the schedule and computes the next activations after now, the time of the simulation
+func (s *Schedule) Setup(now time.Time) error {
+
+	s.location = time.Local
+	if s.Location != "" {
+
+		loc, err := time.LoadLocation(s.Location)
+		if err != nil {
+			return err
+		}
+
+		s.location = loc
+	}
+
+	now = now.In(s.location)
+
+	for i := range s.Entries {
+
+		e := &s.Entries[i]
+		e.cron = nil
+		e.next = time.Time{}
+
+		if e.FPort > 223 {
+			return fmt.Errorf("Entry %v: fport must be from 1 to 223", e.Name)
+		}
+
+		switch {
+
+		case e.Cron != "" && e.Daily == "" && e.At == nil:
+			c, err := ParseCron(e.Cron)
+			if err != nil {
+				return err
+			}
+			e.cron = c
+
+		case e.Daily != "" && e.Cron == "" && e.At == nil:
+			var hour, minute int
+			if _, err := fmt.Sscanf(e.Daily, "%d:%d", &hour, &minute); err != nil {
+				return fmt.Errorf("Entry %v: daily must be hh:mm", e.Name)
+			}
+
+			c, err := ParseCron(fmt.Sprintf("%d %d * * *", minute, hour))
+			if err != nil {
+				return fmt.Errorf("Entry %v: %v", e.Name, err)
+			}
+			e.cron = c
+
+		case e.At != nil && e.Cron == "" && e.Daily == "":
+			if e.At.After(now) { // already sent otherwise
+				e.next = *e.At
+			}
+			continue
+
+		default:
+			return fmt.Errorf("Entry %v: set one of cron, daily and at", e.Name)
+		}
+
+		e.next = e.cron.Next(now)
+	}
+
+	for i := range s.Alarms {
+
+		if s.Alarms[i].FPort > 223 {
+			return fmt.Errorf("Alarm on %v: fport must be from 1 to 223", s.Alarms[i].Field)
+		}
+
+		if s.Alarms[i].Above == nil && s.Alarms[i].Below == nil {
+			return fmt.Errorf("Alarm on %v without threshold", s.Alarms[i].Field)
+		}
+
+		s.Alarms[i].raised = false
+	}
+
+	return nil
+}
+
+// Next returns the earliest activation, zero if nothing is scheduled
+func (s *Schedule) Next() time.Time {
+
+	var next time.Time
+
+	for _, e := range s.Entries {
+		if !e.next.IsZero() && (next.IsZero() || e.next.Before(next)) {
+			next = e.next
+		}
+	}
+
+	return next
+}
+
+// Due returns the uplinks to send at now and moves their entries to the next activation
+func (s *Schedule) Due(now time.Time) []Uplink {
+
+	var uplinks []Uplink
+
+	for i := range s.Entries {
+
+		e := &s.Entries[i]
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+
+		uplinks = append(uplinks, e.Uplink)
+
+		if e.cron != nil {
+			e.next = e.cron.Next(now.In(s.location))
+		} else {
+			e.next = time.Time{}
+		}
+
+	}
+
+	return uplinks
+}
+
+// Check evaluates the alarms on the generated values and returns the uplinks of the ones just raised
+func (s *Schedule) Check(values map[string]float64) []Uplink {
+
+	var uplinks []Uplink
+
+	for i := range s.Alarms {
+
+		a := &s.Alarms[i]
+
+		value, ok := values[a.Field]
+		if !ok {
+			continue
+		}
+
+		active := (a.Above != nil && value > *a.Above) || (a.Below != nil && value < *a.Below)
+
+		if active && !a.raised { // only on the transition
+			uplinks = append(uplinks, a.Uplink)
+		}
+
+		a.raised = active
+	}
+
+	return uplinks
+}
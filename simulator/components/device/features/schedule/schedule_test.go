@@ -0,0 +1,197 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int, hour int, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestCronNext(t *testing.T) {
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", date(2024, 1, 1, 10, 7), date(2024, 1, 1, 10, 15)},
+		{"5/20 * * * *", date(2024, 1, 1, 10, 30), date(2024, 1, 1, 10, 45)},
+		{"0,30 8-9 * * *", date(2024, 1, 1, 9, 30), date(2024, 1, 2, 8, 0)},
+		{"@hourly", date(2024, 1, 1, 10, 0), date(2024, 1, 1, 11, 0)}, // strictly after
+		{"@daily", date(2024, 1, 1, 23, 59), date(2024, 1, 2, 0, 0)},
+		{"@yearly", date(2024, 12, 31, 23, 59), date(2025, 1, 1, 0, 0)},
+		{"0 9 * * 1-5", date(2024, 1, 5, 10, 0), date(2024, 1, 8, 9, 0)}, // friday to monday
+		{"0 0 * * 7", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},    // 7 is sunday
+		{"0 0 15 * 0", date(2024, 1, 2, 0, 0), date(2024, 1, 7, 0, 0)},   // day of month or of week
+		{"0 0 15 * 0", date(2024, 1, 8, 0, 0), date(2024, 1, 14, 0, 0)},
+		{"0 0 15 * 0", date(2024, 1, 14, 0, 0), date(2024, 1, 15, 0, 0)},
+		{"30 2 29 2 *", date(2024, 3, 1, 0, 0), date(2028, 2, 29, 2, 30)},
+		{"0 12 31 4 *", date(2024, 1, 1, 0, 0), time.Time{}}, // never
+	}
+
+	for _, test := range tests {
+
+		c, err := ParseCron(test.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", test.expr, err)
+			continue
+		}
+
+		if got := c.Next(test.from); !got.Equal(test.want) {
+			t.Errorf("%q after %v = %v, want %v", test.expr, test.from, got, test.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+
+	for _, expr := range []string{
+		"* * * *",
+		"@often",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) accepted", expr)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+
+	now := date(2024, 1, 1, 6, 0)
+	past, future := now.Add(-time.Hour), now.Add(90*time.Minute)
+
+	s := Schedule{
+		Entries: []Entry{
+			{Name: "report", Cron: "0 */4 * * *", Uplink: Uplink{FPort: 2}},
+			{Name: "morning", Daily: "08:30", Uplink: Uplink{FPort: 3}},
+			{Name: "once", At: &future, Uplink: Uplink{FPort: 4}},
+			{Name: "sent", At: &past, Uplink: Uplink{FPort: 5}},
+		},
+		Location: "UTC",
+	}
+
+	if err := s.Setup(now); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		next  time.Time
+		fport uint8
+	}{
+		{date(2024, 1, 1, 7, 30), 4},
+		{date(2024, 1, 1, 8, 0), 2},
+		{date(2024, 1, 1, 8, 30), 3},
+		{date(2024, 1, 1, 12, 0), 2},
+		{date(2024, 1, 1, 16, 0), 2},
+		{date(2024, 1, 1, 20, 0), 2},
+		{date(2024, 1, 2, 0, 0), 2},
+		{date(2024, 1, 2, 4, 0), 2},
+		{date(2024, 1, 2, 8, 0), 2},
+		{date(2024, 1, 2, 8, 30), 3},
+	}
+
+	for _, test := range tests {
+
+		next := s.Next()
+		if !next.Equal(test.next) {
+			t.Fatalf("Next() = %v, want %v", next, test.next)
+		}
+
+		if uplinks := s.Due(next.Add(-time.Second)); len(uplinks) != 0 {
+			t.Errorf("Due before %v = %v", next, uplinks)
+		}
+
+		uplinks := s.Due(next)
+		if len(uplinks) != 1 || uplinks[0].FPort != test.fport {
+			t.Errorf("Due(%v) = %+v, want fport %v", next, uplinks, test.fport)
+		}
+	}
+}
+
+func TestScheduleLocation(t *testing.T) {
+
+	s := Schedule{
+		Entries:  []Entry{{Name: "morning", Daily: "08:30"}},
+		Location: "Europe/Rome",
+	}
+
+	if err := s.Setup(date(2024, 7, 1, 0, 0)); err != nil {
+		t.Skip(err) // without the time zone database
+	}
+
+	if next := s.Next(); !next.Equal(date(2024, 7, 1, 6, 30)) { // summer time, UTC+2
+		t.Errorf("Next() = %v", next.UTC())
+	}
+}
+
+func TestScheduleSetupErrors(t *testing.T) {
+
+	at := date(2024, 1, 1, 0, 0)
+	above := 10.0
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+	}{
+		{"location", Schedule{Location: "Mars/Olympus"}},
+		{"no time", Schedule{Entries: []Entry{{Name: "e"}}}},
+		{"two times", Schedule{Entries: []Entry{{Name: "e", Cron: "@daily", At: &at}}}},
+		{"cron", Schedule{Entries: []Entry{{Name: "e", Cron: "0 0 * *"}}}},
+		{"daily", Schedule{Entries: []Entry{{Name: "e", Daily: "noon"}}}},
+		{"daily range", Schedule{Entries: []Entry{{Name: "e", Daily: "25:00"}}}},
+		{"fport", Schedule{Entries: []Entry{{Name: "e", Daily: "08:00", Uplink: Uplink{FPort: 224}}}}},
+		{"threshold", Schedule{Alarms: []Alarm{{Field: "temperature"}}}},
+		{"alarm fport", Schedule{Alarms: []Alarm{{Field: "temperature", Above: &above, Uplink: Uplink{FPort: 255}}}}},
+	}
+
+	for _, test := range tests {
+		if err := test.schedule.Setup(at); err == nil {
+			t.Errorf("%v accepted", test.name)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+
+	above, below := 30.0, 5.0
+
+	s := Schedule{Alarms: []Alarm{{Field: "temperature", Above: &above, Below: &below, Uplink: Uplink{FPort: 9}}}}
+	if err := s.Setup(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value float64
+		fired bool
+	}{
+		{20, false},
+		{31, true},
+		{35, false}, // still raised
+		{20, false},
+		{4, true},
+		{4, false},
+	}
+
+	for _, test := range tests {
+
+		uplinks := s.Check(map[string]float64{"temperature": test.value})
+		if fired := len(uplinks) == 1 && uplinks[0].FPort == 9; fired != test.fired || len(uplinks) > 1 {
+			t.Errorf("Check(%v) = %+v, want fired %v", test.value, uplinks, test.fired)
+		}
+	}
+
+	if uplinks := s.Check(map[string]float64{"humidity": 100}); len(uplinks) != 0 {
+		t.Errorf("Check of another field = %+v", uplinks)
+	}
+}
@@ -0,0 +1,160 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/codec"
+	gen "github.com/arslab/lwnsimulator/simulator/components/device/features/generator"
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+)
+
+// Stream is an independent periodic uplink of the device (eg. status on port 1, alarms on port 2)
+type Stream struct {
+	Name      string        `json:"name"`
+	FPort     uint8         `json:"fport"` // 1-223
+	Confirmed bool          `json:"confirmed"`
+	Interval  time.Duration `json:"interval"` // s
+
+	Payload   string         `json:"payload,omitempty"` // static; the device's payload if no source is set
+	Generator *gen.Generator `json:"generator,omitempty"`
+	Codec     *codec.Codec   `json:"codec,omitempty"`
+
+	next time.Time
+}
+
+// Setup validates the stream and starts its interval from now
+func (s *Stream) Setup(now time.Time) error {
+
+	if s.FPort == 0 || s.FPort > 223 {
+		return fmt.Errorf("Stream %v: fport must be from 1 to 223", s.Name)
+	}
+
+	if s.Interval <= 0 {
+		return fmt.Errorf("Stream %v: interval must be positive", s.Name)
+	}
+
+	sources := 0
+	for _, set := range []bool{s.Payload != "", s.Generator != nil, s.Codec != nil} {
+		if set {
+			sources++
+		}
+	}
+
+	if sources > 1 {
+		return fmt.Errorf("Stream %v: set only one of payload, generator and codec", s.Name)
+	}
+
+	if s.Generator != nil {
+		if err := s.Generator.Setup(); err != nil {
+			return fmt.Errorf("Stream %v: %v", s.Name, err)
+		}
+	}
+
+	if s.Codec != nil {
+		if err := s.Codec.Setup(); err != nil {
+			return fmt.Errorf("Stream %v: %v", s.Name, err)
+		}
+	}
+
+	s.next = now.Add(s.Interval)
+
+	return nil
+}
+
+// Next returns when the stream sends again
+func (s *Stream) Next() time.Time {
+	return s.next
+}
+
+// Due is true if the stream must send at now; it moves the stream to the next interval
+func (s *Stream) Due(now time.Time) bool {
+
+	if s.next.IsZero() || s.next.After(now) {
+		return false
+	}
+
+	s.next = s.next.Add(s.Interval)
+	if !s.next.After(now) { // late, drop the missed sends
+		s.next = now.Add(s.Interval)
+	}
+
+	return true
+}
+
+// Bytes returns the payload of the stream, nil to send the device's payload
+func (s *Stream) Bytes(now time.Time, location loc.Location) ([]byte, error) {
+
+	switch {
+
+	case s.Generator != nil:
+		return s.Generator.Next(now)
+
+	case s.Codec != nil:
+		return s.Codec.Encode(now, location)
+
+	case s.Payload != "":
+		return []byte(s.Payload), nil
+
+	}
+
+	return nil, nil
+}
+
+// Setup validates all streams, their first uplink follows now, the time of the simulation
+func Setup(streams []Stream, now time.Time) error {
+
+	ports := make(map[uint8]bool)
+
+	for i := range streams {
+
+		if err := streams[i].Setup(now); err != nil {
+			return err
+		}
+
+		if ports[streams[i].FPort] {
+			return errors.New("Each stream needs its own fport")
+		}
+		ports[streams[i].FPort] = true
+
+	}
+
+	return nil
+}
+
+// MarshalJSON of the stream
+func (s *Stream) MarshalJSON() ([]byte, error) {
+
+	type Alias Stream
+
+	return json.Marshal(&struct {
+		Interval int `json:"interval"`
+		*Alias
+	}{
+		Interval: int(s.Interval / time.Second),
+		Alias:    (*Alias)(s),
+	})
+}
+
+// UnmarshalJSON of the stream
+func (s *Stream) UnmarshalJSON(data []byte) error {
+
+	type Alias Stream
+
+	aux := &struct {
+		Interval int `json:"interval"`
+		*Alias
+	}{
+		Alias: (*Alias)(s),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	s.Interval = time.Duration(aux.Interval) * time.Second
+
+	return nil
+}
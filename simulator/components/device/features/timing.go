@@ -0,0 +1,129 @@
+package features
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/airtime"
+	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
+)
+
+const (
+	//MinPreambleSymbols is the number of preamble symbols the radio needs to lock on a downlink
+	MinPreambleSymbols = 6
+)
+
+//Timing models the device's crystal and the wake-up of its radio
+type Timing struct {
+	ClockDrift     float64       `json:"clockDrift"`     // crystal error (ppm), positive runs slow
+	WakeUpLatency  time.Duration `json:"wakeUpLatency"`  // radio start-up delay
+	WindowWidening float64       `json:"windowWidening"` // clock tolerance compensated by firmware (ppm)
+
+	DataRate func(uint8) (string, string) `json:"-"` // region's datarate lookup
+}
+
+//Reception describes the position of a downlink inside a receive window
+type Reception struct {
+	Open          time.Time     `json:"open"`
+	Close         time.Time     `json:"close"`
+	DownlinkStart time.Time     `json:"downlinkStart"`
+	PreambleOK    bool          `json:"preambleOK"`
+	Margin        time.Duration `json:"margin"` // negative when the preamble is missed
+}
+
+//Enabled is true when the device isn't a perfect clock
+func (t *Timing) Enabled() bool {
+	return t != nil && (t.ClockDrift != 0 || t.WakeUpLatency != 0 || t.WindowWidening != 0)
+}
+
+//Widening returns how much the window is opened earlier and closed later
+func (t *Timing) Widening(Delay time.Duration) time.Duration {
+	return time.Duration(float64(Delay) * math.Abs(t.WindowWidening) / 1000000)
+}
+
+//Schedule returns when the window really opens and how long it stays open
+func (t *Timing) Schedule(Delay time.Duration, DurationOpen time.Duration) (time.Duration, time.Duration) {
+
+	widening := t.Widening(Delay)
+
+	delay := time.Duration(float64(Delay)*(1+t.ClockDrift/1000000)) + t.WakeUpLatency - widening
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay, DurationOpen + 2*widening
+}
+
+//Evaluate checks if the downlink's preamble was inside the window long enough to be detected
+func (t *Timing) Evaluate(open time.Time, close time.Time, DataRate uint8, rDownlink *dl.ReceivedDownlink) Reception {
+
+	reception := Reception{
+		Open:          open,
+		Close:         close,
+		DownlinkStart: rDownlink.TransmissionTime(),
+		PreambleOK:    true,
+	}
+
+	if t.DataRate == nil {
+		return reception
+	}
+
+	_, datr := t.DataRate(DataRate)
+	tSym, err := airtime.SymbolTime(datr)
+	if err != nil { //FSK: no preamble constraint
+		return reception
+	}
+
+	// last instant the radio can wake up and still detect the preamble
+	latestOpen := reception.DownlinkStart.Add(time.Duration(float64(tSym) * (airtime.PreambleSymbols + airtime.SyncSymbols - MinPreambleSymbols)))
+	// the window must stay open until the minimum preamble is received
+	earliestClose := reception.DownlinkStart.Add(tSym * MinPreambleSymbols)
+
+	marginOpen := latestOpen.Sub(open)
+	marginClose := close.Sub(earliestClose)
+
+	reception.Margin = marginOpen
+	if marginClose < marginOpen {
+		reception.Margin = marginClose
+	}
+
+	reception.PreambleOK = reception.Margin >= 0
+
+	return reception
+}
+
+//MarshalJSON of device's timing
+func (t *Timing) MarshalJSON() ([]byte, error) {
+	type Alias Timing
+
+	return json.Marshal(&struct {
+		WakeUpLatency int `json:"wakeUpLatency"`
+		*Alias
+	}{
+		WakeUpLatency: int(t.WakeUpLatency / time.Millisecond),
+		Alias:         (*Alias)(t),
+	})
+
+}
+
+//UnmarshalJSON of device's timing
+func (t *Timing) UnmarshalJSON(data []byte) error {
+
+	type Alias Timing
+
+	aux := &struct {
+		WakeUpLatency int `json:"wakeUpLatency"`
+		*Alias
+	}{
+		Alias: (*Alias)(t),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	t.WakeUpLatency = time.Duration(aux.WakeUpLatency) * time.Millisecond
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package traffic
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	ModelFixed   = "fixed"   // SendInterval ± Jitter
+	ModelPoisson = "poisson" // exponential inter-arrival with mean SendInterval
+	ModelBursty  = "bursty"  // sends every SendInterval during On, silent during Off
+)
+
+// Traffic shapes the inter-arrival time of the periodic uplinks
+type Traffic struct {
+	Model string `json:"model"` // fixed default
+
+	Jitter time.Duration `json:"jitter"` // fixed, bursty: uniform in ±Jitter (s)
+
+	RandomOffset bool          `json:"randomOffset"` // random delay before the first transmission
+	MaxOffset    time.Duration `json:"maxOffset"`    // upper bound of the offset (s), SendInterval if 0
+
+	On  time.Duration `json:"on"`  // bursty: duration of the active period (s)
+	Off time.Duration `json:"off"` // bursty: duration of the silent period (s)
+
+	random     *rand.Rand
+	burstStart time.Time
+}
+
+// Setup validates the model
+func (t *Traffic) Setup() error {
+
+	t.random = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	t.burstStart = time.Time{}
+
+	switch t.Model {
+
+	case ModelFixed, ModelPoisson, "":
+
+	case ModelBursty:
+		if t.On <= 0 || t.Off < 0 {
+			return fmt.Errorf("Bursty model requires on > 0 and off >= 0")
+		}
+
+	default:
+		return fmt.Errorf("Traffic model %v not supported", t.Model)
+	}
+
+	if t.Jitter < 0 || t.MaxOffset < 0 {
+		return fmt.Errorf("Jitter and offset can't be negative")
+	}
+
+	return nil
+}
+
+// Offset returns the delay before the first transmission
+func (t *Traffic) Offset(interval time.Duration) time.Duration {
+
+	if !t.RandomOffset {
+		return 0
+	}
+
+	max := t.MaxOffset
+	if max == 0 {
+		max = interval
+	}
+
+	if max <= 0 {
+		return 0
+	}
+
+	return time.Duration(t.rand().Int63n(int64(max)))
+}
+
+// Interval returns the time until the next uplink, interval is the nominal SendInterval
+func (t *Traffic) Interval(interval time.Duration, now time.Time) time.Duration {
+
+	switch t.Model {
+
+	case ModelPoisson:
+		return time.Duration(t.rand().ExpFloat64() * float64(interval))
+
+	case ModelBursty:
+
+		if t.burstStart.IsZero() {
+			t.burstStart = now
+		}
+
+		next := now.Add(t.jitter(interval))
+		period := t.On + t.Off
+		elapsed := next.Sub(t.burstStart) % period
+
+		if elapsed >= t.On { // falls in the silent period, wait the next burst
+			next = next.Add(period - elapsed)
+		}
+
+		return next.Sub(now)
+	}
+
+	return t.jitter(interval)
+}
+
+func (t *Traffic) jitter(interval time.Duration) time.Duration {
+
+	if t.Jitter <= 0 {
+		return interval
+	}
+
+	delta := time.Duration(t.rand().Int63n(int64(2*t.Jitter))) - t.Jitter
+	if interval+delta <= 0 {
+		return interval
+	}
+
+	return interval + delta
+}
+
+func (t *Traffic) rand() *rand.Rand {
+
+	if t.random == nil {
+		t.random = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	}
+
+	return t.random
+}
+
+// MarshalJSON of device's traffic model
+func (t *Traffic) MarshalJSON() ([]byte, error) {
+
+	type Alias Traffic
+
+	return json.Marshal(&struct {
+		Jitter    float64 `json:"jitter"`
+		MaxOffset float64 `json:"maxOffset"`
+		On        float64 `json:"on"`
+		Off       float64 `json:"off"`
+		*Alias
+	}{
+		Jitter:    t.Jitter.Seconds(),
+		MaxOffset: t.MaxOffset.Seconds(),
+		On:        t.On.Seconds(),
+		Off:       t.Off.Seconds(),
+		Alias:     (*Alias)(t),
+	})
+}
+
+// UnmarshalJSON of device's traffic model
+func (t *Traffic) UnmarshalJSON(data []byte) error {
+
+	type Alias Traffic
+
+	aux := &struct {
+		Jitter    float64 `json:"jitter"`
+		MaxOffset float64 `json:"maxOffset"`
+		On        float64 `json:"on"`
+		Off       float64 `json:"off"`
+		*Alias
+	}{
+		Alias: (*Alias)(t),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	t.Jitter = time.Duration(aux.Jitter * float64(time.Second))
+	t.MaxOffset = time.Duration(aux.MaxOffset * float64(time.Second))
+	t.On = time.Duration(aux.On * float64(time.Second))
+	t.Off = time.Duration(aux.Off * float64(time.Second))
+
+	return nil
+}
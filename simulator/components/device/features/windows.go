@@ -2,6 +2,7 @@ package features
 
 import (
 	"encoding/json"
+	"sync/atomic"
 	"time"
 
 	c "github.com/arslab/lwnsimulator/simulator/components/device/features/channels"
@@ -20,43 +21,72 @@ type Window struct {
 	Delay        time.Duration `json:"delay"`
 	DurationOpen time.Duration `json:"durationOpen"`
 	DataRate     uint8         `json:"dataRate"`
+
+	Timing        *Timing   `json:"-"` // device's clock, nil is a perfect clock
+	LastReception Reception `json:"-"`
+	Received      time.Time `json:"-"` // reception of the last downlink, zero if none
 }
 
-//GetListeningFrequency get window's listening frequency
+//GetListeningFrequency get window's listening frequency, the RX2 of class C reads it while the device runs
 func (w *Window) GetListeningFrequency() uint32 {
-	return w.Channel.FrequencyDownlink
+	return atomic.LoadUint32(&w.Channel.FrequencyDownlink)
 }
 
 //SetListeningFrequency set window's listening frequency
 func (w *Window) SetListeningFrequency(freq uint32) {
-	w.Channel.FrequencyDownlink = freq
+	atomic.StoreUint32(&w.Channel.FrequencyDownlink, freq)
+}
+
+// SetChannel changes the channel of the window, the listening frequency with SetListeningFrequency
+func (w *Window) SetChannel(channel c.Channel) {
+
+	w.Channel.Active = channel.Active
+	w.Channel.EnableUplink = channel.EnableUplink
+	w.Channel.FrequencyUplink = channel.FrequencyUplink
+	w.Channel.MinDR = channel.MinDR
+	w.Channel.MaxDR = channel.MaxDR
+
+	w.SetListeningFrequency(channel.FrequencyDownlink)
 }
 
-func (w *Window) OpenWindow(Delay time.Duration, ReceivedDownlink *dl.ReceivedDownlink) *lorawan.PHYPayload {
+//Schedule returns when a window started now with Delay opens and closes: the device's clock
+//drift, the wake-up latency and the window widening move them. A Delay of 0 is the window's own
+func (w *Window) Schedule(now time.Time, Delay time.Duration) (time.Time, time.Time) {
 
 	if Delay == 0 {
 		Delay = w.Delay
 	}
 
-	timerWindow := time.NewTimer(Delay)
-	<-timerWindow.C //delay
-	timerWindow.Stop()
+	DurationOpen := w.DurationOpen
+	if w.Timing.Enabled() {
+		Delay, DurationOpen = w.Timing.Schedule(Delay, w.DurationOpen)
+	}
 
-	for {
+	open := now.Add(Delay)
 
-		go func(durate time.Duration, buf *dl.ReceivedDownlink) {
+	return open, open.Add(DurationOpen)
+}
 
-			timer := time.NewTimer(durate)
-			<-timer.C
-			timer.Stop()
+//Close takes the downlink received while the window was open, nil if none or if its preamble
+//wasn't inside the window long enough. A downlink closes the window before its time
+func (w *Window) Close(open time.Time, close time.Time, now time.Time, ReceivedDownlink *dl.ReceivedDownlink) *lorawan.PHYPayload {
 
-			buf.Signal()
+	phy := ReceivedDownlink.Take()
 
-		}(w.DurationOpen, ReceivedDownlink)
+	if phy != nil && w.Timing.Enabled() {
 
-		return ReceivedDownlink.Pull()
+		w.LastReception = w.Timing.Evaluate(open, close, w.DataRate, ReceivedDownlink)
+		if !w.LastReception.PreambleOK {
+			return nil
+		}
 
 	}
+
+	if phy != nil {
+		w.Received = now
+	}
+
+	return phy
 }
 
 //MarshalJSON of device's Receive window
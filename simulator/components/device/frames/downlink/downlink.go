@@ -12,6 +12,7 @@ type InformationDownlink struct {
 	FOptsReceived []lorawan.Payload `json:"-"`
 	ACK           bool              `json:"-"`
 	DataPayload   []byte            `json:"-"`
+	FPort         uint8             `json:"-"`
 	FPending      bool              `json:"-"`
 	DwellTime     lorawan.DwellTime `json:"-"`
 }
@@ -63,6 +64,8 @@ func GetDownlink(phy lorawan.PHYPayload, disableCounter bool, counter uint32, Nw
 
 	if macPL.FPort != nil {
 
+		downlink.FPort = *macPL.FPort
+
 		switch *macPL.FPort {
 
 		case uint8(0):
@@ -2,6 +2,7 @@ package downlink
 
 import (
 	"sync"
+	"time"
 
 	"github.com/brocaar/lorawan"
 )
@@ -9,56 +10,70 @@ import (
 type ReceivedDownlink struct {
 	Mutex    sync.Mutex
 	Downlink *lorawan.PHYPayload
-	Notify   *sync.Cond
 	IsOpen   bool
+	handler  func() // called once a downlink is stored
+
+	ReceivedAt time.Time // when the gateway forwarded the downlink
+	Tmst       *uint32   // concentrator timestamp requested by the network server
+	UplinkTime time.Time // when the last uplink was sent
+	UplinkTmst uint32    // concentrator timestamp of the last uplink
 }
 
-func (b *ReceivedDownlink) Push(data *lorawan.PHYPayload) {
+// Push stores the downlink forwarded by a gateway at received, the time of the simulation
+func (b *ReceivedDownlink) Push(data *lorawan.PHYPayload, tmst *uint32, received time.Time) {
 
 	if data == nil {
 		return
 	}
 
 	b.Mutex.Lock()
-	if b.IsOpen {
+
+	stored := b.IsOpen
+	if stored {
 
 		b.Downlink = data
-		b.Notify.Broadcast()
+		b.ReceivedAt = received
+		b.Tmst = tmst
 
 	}
 
+	handler := b.handler
+
 	b.Mutex.Unlock()
 
-}
+	if stored && handler != nil {
+		handler()
+	}
 
-func (b *ReceivedDownlink) Pull() *lorawan.PHYPayload {
+}
 
+// SetHandler sets the function called once a downlink is stored, outside the lock: the
+// downlink is an event of the device
+func (b *ReceivedDownlink) SetHandler(handler func()) {
 	b.Mutex.Lock()
+	b.handler = handler
+	b.Mutex.Unlock()
+}
 
-	defer b.Mutex.Unlock()
+// Take returns the downlink received, nil if none
+func (b *ReceivedDownlink) Take() *lorawan.PHYPayload {
 
-	if b.Downlink == nil {
-		b.Notify.Wait()
-	}
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
 
 	phy := b.Downlink
-
-	b.Downlink = nil //reset
+	b.Downlink = nil
 
 	return phy
-
 }
 
-func (b *ReceivedDownlink) Wait() {
-	b.Mutex.Lock()
-	b.Notify.Wait()
-	b.Mutex.Unlock()
-}
+// Pending is true if a downlink is waiting to be taken
+func (b *ReceivedDownlink) Pending() bool {
 
-func (b *ReceivedDownlink) Signal() {
 	b.Mutex.Lock()
-	b.Notify.Broadcast()
-	b.Mutex.Unlock()
+	defer b.Mutex.Unlock()
+
+	return b.Downlink != nil
 }
 
 func (b *ReceivedDownlink) Open() {
@@ -72,3 +87,26 @@ func (b *ReceivedDownlink) Close() {
 	b.IsOpen = false
 	b.Mutex.Unlock()
 }
+
+// SetReference stores the timestamps of the last uplink, used to place scheduled downlinks in time
+func (b *ReceivedDownlink) SetReference(sent time.Time, tmst uint32) {
+	b.Mutex.Lock()
+	b.UplinkTime = sent
+	b.UplinkTmst = tmst
+	b.Mutex.Unlock()
+}
+
+// TransmissionTime returns when the gateway starts transmitting the last downlink
+func (b *ReceivedDownlink) TransmissionTime() time.Time {
+
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+
+	if b.Tmst == nil || b.UplinkTime.IsZero() { //immediate
+		return b.ReceivedAt
+	}
+
+	offset := time.Duration(*b.Tmst-b.UplinkTmst) * time.Microsecond //concentrator counter wraps on uint32
+
+	return b.UplinkTime.Add(offset)
+}
@@ -5,6 +5,8 @@ import (
 )
 
 type InfoFrame struct {
-	MType   lorawan.MType
-	Payload lorawan.Payload
+	MType     lorawan.MType
+	FPort     uint8 // 0 device's FPort
+	Payload   lorawan.Payload
+	Triggered bool // sent without waiting the send interval
 }
@@ -21,6 +21,12 @@ type InfoUplink struct {
 
 func (up *InfoUplink) GetFrame(mtype lorawan.MType, payload lorawan.DataPayload,
 	devAddr lorawan.DevAddr, AppSKey, NwkSKey [16]byte, ack bool) ([]byte, error) {
+	return up.GetFrameOnPort(mtype, up.FPort, payload, devAddr, AppSKey, NwkSKey, ack)
+}
+
+// GetFrameOnPort creates the frame on FPort instead of the device's one
+func (up *InfoUplink) GetFrameOnPort(mtype lorawan.MType, FPort *uint8, payload lorawan.DataPayload,
+	devAddr lorawan.DevAddr, AppSKey, NwkSKey [16]byte, ack bool) ([]byte, error) {
 
 	FOpts := up.loadFOpts()
 
@@ -41,7 +47,7 @@ func (up *InfoUplink) GetFrame(mtype lorawan.MType, payload lorawan.DataPayload,
 				FCnt:  up.FCnt,
 				FOpts: FOpts,
 			},
-			FPort: up.FPort,
+			FPort: FPort,
 			FRMPayload: []lorawan.Payload{
 				&payload,
 			},
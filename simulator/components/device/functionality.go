@@ -6,99 +6,116 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-
 	"github.com/arslab/lwnsimulator/simulator/components/device/classes"
 	"github.com/arslab/lwnsimulator/simulator/components/device/features/adr"
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	rp "github.com/arslab/lwnsimulator/simulator/components/device/regional_parameters"
+	"github.com/arslab/lwnsimulator/simulator/events"
 	"github.com/arslab/lwnsimulator/simulator/util"
 	"github.com/brocaar/lorawan"
 )
 
-var (
-	uplinkCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "device_uplink_sent_total",
-		Help: "The total number of uplinks sent",
-	})
-	downlinkCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "device_downlink_received_total",
-		Help: "The total number of downlinks received",
-	})
-	ackTimeoutCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "device_ack_timetou_total",
-		Help: "The total number of ACK timeouts",
-	})
-)
-
-func (d *Device) Execute() {
-
-	var downlink *dl.InformationDownlink
-	var err error
-
-	err = nil
-	downlink = nil
+// Execute sends the uplinks, opens the receive windows and handles the downlink, then calls done
+func (d *Device) Execute(done func()) {
 
 	if d.Info.Status.DoSwitchChannel {
 		d.SwitchChannel()
 	}
 
 	uplinks := d.CreateUplink()
+	if len(uplinks) == 0 {
+		d.Print("Nothing to send", nil, util.PrintBoth)
+		done()
+		return
+	}
+
+	if d.Info.Status.Mode == util.Retransmission {
+		d.publish(events.TypeRetransmission, &events.Retransmission{
+			DevEUI:  d.Info.DevEUI,
+			FCnt:    d.lastFCnt(),
+			Attempt: int(d.Info.Status.CounterRepConfirmedDataUp),
+		})
+	}
+
 	for i := 0; i < len(uplinks); i++ {
 
-		data := d.SetInfo(uplinks[i], false)
-		d.Class.SendData(data)
+		if d.powerLost() {
+			done()
+			return
+		}
 
-		d.Print("Uplink sent", nil, util.PrintBoth)
-		uplinkCounter.Inc()
+		data := d.SetInfo(uplinks[i], false)
+		d.Class.SendData(data, d.now())
+		d.recordUplink(uplinks[i], data)
 	}
 
+	d.Resources.Triggers.Notify(d.Info.Name)
+
 	d.Print("Open RXs for "+strconv.Itoa(int(d.Info.RX[0].Channel.FrequencyDownlink))+
 		" and "+strconv.Itoa(int(d.Info.RX[1].Channel.FrequencyDownlink)), nil, util.PrintBoth)
 
-	phy := d.Class.ReceiveWindows(0, 0)
+	d.ReceiveWindows(0, 0, func(phy *lorawan.PHYPayload) {
+
+		if phy == nil {
 
-	if phy != nil {
+			d.Print("None downlinks Received", nil, util.PrintBoth)
 
-		d.Print("Downlink Received", nil, util.PrintBoth)
-		downlinkCounter.Inc()
+			d.Info.Status.DoSwitchChannel = true
+
+			// with the 1.0.4 timing handled arms eventRetransmit after RETRANSMIT_TIMEOUT,
+			// otherwise the ACK timeout is waited with after
+			if d.Info.Configuration.Backoff.Retransmission.Enabled {
+				d.recordAckTimeout()
+				d.ADRProcedure(func() { d.retransmission(nil, done) })
+				return
+			}
+
+			d.after(d.Info.Configuration.AckTimeout, func() {
+				d.recordAckTimeout()
+				d.ADRProcedure(func() { d.retransmission(nil, done) })
+			})
+			return
+		}
+
+		d.recordDownlink(*phy)
 
 		if d.Info.Status.Mode != util.Activation {
 			d.Info.Status.DoSwitchChannel = false
 		}
 
-		downlink, err = d.ProcessDownlink(*phy)
+		downlink, err := d.ProcessDownlink(*phy)
 		if err != nil {
 			d.Print("", err, util.PrintBoth)
+			done()
 			return
 		}
 
-		if downlink != nil { //downlink ricevuto
-
-			d.ExecuteMACCommand(*downlink)
-
-			if d.Info.Status.Mode != util.Retransmission {
-				d.FPendingProcedure(downlink)
-			}
+		adr := func() {
+			d.ADRProcedure(func() { d.retransmission(downlink, done) })
 		}
 
-	} else {
+		if downlink == nil {
+			adr()
+			return
+		}
 
-		d.Print("None downlinks Received", nil, util.PrintBoth)
+		d.ExecuteMACCommand(*downlink)
+		d.ExecuteApplicationPayload(*downlink)
 
-		d.Info.Status.DoSwitchChannel = true
+		if d.Info.Status.Mode != util.Retransmission {
+			d.FPendingProcedure(downlink, adr)
+			return
+		}
 
-		timerAckTimeout := time.NewTimer(d.Info.Configuration.AckTimeout)
-		<-timerAckTimeout.C
+		adr()
+	})
+}
 
-		d.Print("ACK Timeout", nil, util.PrintBoth)
-		ackTimeoutCounter.Inc()
-	}
+// retransmission updates the state of the uplink sent with the downlink received, nil if none
+func (d *Device) retransmission(downlink *dl.InformationDownlink, done func()) {
 
-	d.ADRProcedure()
+	defer done()
 
-	//retransmission
 	switch d.Info.Status.LastMType {
 
 	case lorawan.ConfirmedDataUp:
@@ -114,11 +131,13 @@ func (d *Device) Execute() {
 
 			d.Print("", err, util.PrintBoth)
 
-			d.UnJoined()
+			if !d.Info.Configuration.Backoff.Retransmission.Enabled { // 1.0.4 drops the uplink
+				d.UnJoined()
+			}
 
 		}
 
-		if d.Info.Status.Mode == util.Retransmission {
+		if d.Info.Status.Mode == util.Retransmission && !d.Info.Configuration.Backoff.Retransmission.Enabled {
 
 			d.Info.Status.DataRate = rp.DecrementDataRate(d.Info.Configuration.Region, d.Info.Status.DataRate)
 
@@ -131,91 +150,92 @@ func (d *Device) Execute() {
 			d.Print("", err, util.PrintBoth)
 		}
 	}
-
 }
 
-func (d *Device) FPendingProcedure(downlink *dl.InformationDownlink) {
+// FPendingProcedure opens the receive windows while the network has downlinks pending, then calls done
+func (d *Device) FPendingProcedure(downlink *dl.InformationDownlink, done func()) {
 
-	var err error
 	if !d.CanExecute() {
 		return
 	}
 
-	startProcedure := 0 //per la print finale
-
-	for downlink != nil {
+	started := false //per la print finale
 
-		if downlink.FPending {
+	var next func(downlink *dl.InformationDownlink)
 
-			d.Print("Fpending set", nil, util.PrintBoth)
+	finish := func() {
 
-			if startProcedure == 0 {
-				d.Info.Status.Mode = util.FPending
-				d.Print("Start FPending procedure", nil, util.PrintBoth)
-				startProcedure = 1
-			}
-
-			if downlink.MType == lorawan.UnconfirmedDataDown {
-				d.SendEmptyFrame()
-			}
-			//ack sent in resolveDownlinks ergo open Receive Windows
+		if started {
+			d.Print("FPending procedure finished", nil, util.PrintBoth)
+		}
 
-			d.Print("Open RXs", nil, util.PrintBoth)
-			phy := d.Class.ReceiveWindows(0, 0)
+		d.Info.Status.Mode = util.Normal
 
-			if !d.CanExecute() { //stop
-				return
-			}
+		done()
+	}
 
-			if phy != nil {
+	next = func(downlink *dl.InformationDownlink) {
 
-				d.Print("Downlink Received", nil, util.PrintBoth)
-				downlinkCounter.Inc()
+		if downlink == nil {
+			finish()
+			return
+		}
 
-				downlink, err = d.ProcessDownlink(*phy)
-				if err != nil {
-					d.Print("", err, util.PrintBoth)
+		if !downlink.FPending {
+			d.Print("Fpending unset", nil, util.PrintBoth)
+			finish()
+			return
+		}
 
-				}
+		d.Print("Fpending set", nil, util.PrintBoth)
 
-				if downlink != nil { //downlink ricevuto
+		if !started {
+			d.Info.Status.Mode = util.FPending
+			d.Print("Start FPending procedure", nil, util.PrintBoth)
+			started = true
+		}
 
-					d.ExecuteMACCommand(*downlink)
+		if downlink.MType == lorawan.UnconfirmedDataDown {
+			d.SendEmptyFrame()
+		}
+		//ack sent in resolveDownlinks ergo open Receive Windows
 
-				}
+		d.Print("Open RXs", nil, util.PrintBoth)
 
-			} else {
+		d.ReceiveWindows(0, 0, func(phy *lorawan.PHYPayload) {
 
-				downlink = nil
+			if phy == nil {
 
 				d.Print("None downlinks Received", nil, util.PrintBoth)
 
-				timerAckTimeout := time.NewTimer(d.Info.Configuration.AckTimeout)
-				<-timerAckTimeout.C
-
-				d.Print("ACK Timeout", nil, util.PrintBoth)
-				ackTimeoutCounter.Inc()
-
+				d.after(d.Info.Configuration.AckTimeout, func() {
+					d.recordAckTimeout()
+					d.ADRProcedure(func() { next(nil) })
+				})
+				return
 			}
 
-			d.ADRProcedure()
+			d.recordDownlink(*phy)
 
-		} else {
-			d.Print("Fpending unset", nil, util.PrintBoth)
-			break
-		}
+			downlink, err := d.ProcessDownlink(*phy)
+			if err != nil {
+				d.Print("", err, util.PrintBoth)
+			}
 
-	}
+			if downlink != nil { //downlink ricevuto
+				d.ExecuteMACCommand(*downlink)
+				d.ExecuteApplicationPayload(*downlink)
+			}
 
-	if startProcedure == 1 {
-		d.Print("FPending procedure finished", nil, util.PrintBoth)
+			d.ADRProcedure(func() { next(downlink) })
+		})
 	}
 
-	d.Info.Status.Mode = util.Normal
-
+	next(downlink)
 }
 
-func (d *Device) ADRProcedure() {
+// ADRProcedure applies the ADR backoff, an OTAA device losing the network joins again before done
+func (d *Device) ADRProcedure(done func()) {
 
 	dr, code := d.Info.Status.DataUplink.ADR.ADRProcedure(d.Info.Status.DataRate, d.Info.Configuration.Region, d.Info.Configuration.SupportedADR)
 
@@ -223,23 +243,27 @@ func (d *Device) ADRProcedure() {
 
 	case adr.CodeNoneError:
 		d.Info.Status.DataRate = dr
-		break
 
 	case adr.CodeADRFlagReqSet:
 		d.Print("SET ADRACKReq flag", nil, util.PrintBoth)
-		break
 
 	case adr.CodeUnjoined:
 		if UnJoined := d.UnJoined(); UnJoined {
 
-			d.OtaaActivation()
+			d.OtaaActivation(func() {
 
-			msg := d.Info.Status.DataUplink.ADR.Reset()
-			if msg != "" {
-				d.Print(msg, nil, util.PrintBoth)
-			}
+				msg := d.Info.Status.DataUplink.ADR.Reset()
+				if msg != "" {
+					d.Print(msg, nil, util.PrintBoth)
+				}
+
+				done()
+			})
+			return
 		}
 	}
+
+	done()
 }
 
 func (d *Device) SwitchChannel() {
@@ -375,6 +399,8 @@ func (d *Device) SwitchClass(class int) {
 		return
 	}
 
+	d.Class.CloseRX2() // the RX2 always open of class C
+
 	switch class {
 
 	case classes.ClassA:
@@ -390,7 +416,6 @@ func (d *Device) SwitchClass(class int) {
 
 		d.Class = classes.GetClass(classes.ClassC)
 		d.Class.Setup(&d.Info)
-		go d.DownlinkReceivedRX2ClassC()
 
 	default:
 		d.Print("Class not Supported", nil, util.PrintBoth)
@@ -400,6 +425,8 @@ func (d *Device) SwitchClass(class int) {
 	msg := fmt.Sprintf("Switch in class %v", d.Class.ToString())
 	d.Print(msg, nil, util.PrintBoth)
 
+	d.schedulePing()
+
 }
 
 // se il dispositivo non supporta OTAA non può essere unjoined
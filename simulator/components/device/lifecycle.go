@@ -0,0 +1,99 @@
+package device
+
+import (
+	"github.com/arslab/lwnsimulator/simulator/util"
+)
+
+// Reboot loses the state kept in RAM, the frame counters and the DevNonce are kept in NVM.
+// The device must be turned off: at the next start an OTAA device joins again
+func (d *Device) Reboot() {
+
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+
+	d.brownOut = false
+	d.loseRAM()
+
+	d.Info.Session = d.Info.GetNVMSession(d.now()) // saved with the device, it survives a restart
+}
+
+// FactoryReset clears the NVM too: the DevNonce counts again from 0, so the network server
+// rejects the joins it has already seen, and the frame counters of an ABP device start again
+// from 0. The device must be turned off
+func (d *Device) FactoryReset() {
+
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+
+	d.Info.Session = nil
+	d.brownOut = false
+	d.loseRAM()
+
+	d.Info.DevNonce = 0
+	d.Info.JoinNonce = 0
+	d.Info.Status.DataUplink.FCnt = 0
+	d.Info.Status.FCntDown = 0
+}
+
+// BrownOut cuts the power during the next transmission of the running device
+func (d *Device) BrownOut() {
+
+	d.Apply(func() {
+
+		d.Mutex.Lock()
+		d.brownOut = true
+		d.Mutex.Unlock()
+
+		d.Print("Brown-out at the next transmission", nil, util.PrintBoth)
+	})
+}
+
+// powerLost is true if a brown-out cuts the transmission in progress: the frame is lost,
+// the device restarts from the NVM of the last save
+func (d *Device) powerLost() bool {
+
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+
+	if !d.brownOut {
+		return false
+	}
+	d.brownOut = false
+
+	d.loseRAM()
+
+	session := d.Info.Session
+	if session != nil { // counters written at the last save, the uplinks after it are sent again with the same FCnt
+		d.Info.Status.DataUplink.FCnt = session.FCnt
+		d.Info.Status.FCntDown = session.FCntDown
+		d.Info.DevNonce = session.DevNonce
+	}
+
+	if session != nil && session.Joined {
+		d.Info.RestoreSession(session)
+		d.Info.Status.Mode = util.Normal
+	} else if d.UnJoined() {
+		d.Info.Status.Mode = util.Activation
+	}
+
+	d.Print("Brown-out: transmission lost, restarted from the last save", nil, util.PrintBoth)
+
+	return true
+}
+
+// loseRAM clears the state that doesn't survive a power loss
+func (d *Device) loseRAM() {
+
+	d.Info.Status.BufferUplinks = d.Info.Status.BufferUplinks[:0]
+	d.Info.Status.DataUplink.FOpts = d.Info.Status.DataUplink.FOpts[:0]
+	d.Info.Status.LastUplinks = nil
+	d.Info.Status.CounterRepConfirmedDataUp = 0
+	d.Info.Status.CounterRepUnConfirmedDataUp = 1
+	d.Info.Status.Mode = util.Normal
+
+	if d.Info.Configuration.SupportedOtaa {
+		d.Info.Status.Joined = false
+		d.Info.Status.Mode = util.Activation
+	}
+
+}
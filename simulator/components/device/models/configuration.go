@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/arslab/lwnsimulator/simulator/components/device/features"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/backoff"
 	"github.com/arslab/lwnsimulator/simulator/components/device/features/channels"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/traffic"
 	rp "github.com/arslab/lwnsimulator/simulator/components/device/regional_parameters"
 )
 
@@ -36,6 +39,21 @@ type Configuration struct {
 	NbRepConfirmedDataUp   int   `json:"nbRetransmission"` //Nb retrasmission of ConfirmedDataUp
 	NbRepUnconfirmedDataUp uint8 `json:"-"`                // Nb retrasmission of UnconfirmedDataUp
 	RSSI                   int16 `json:"rssi"`
+
+	Timing  features.Timing `json:"timing"`  // clock drift and RX windows accuracy
+	Traffic traffic.Traffic `json:"traffic"` // inter-arrival model of the uplinks
+	Backoff backoff.Policy  `json:"backoff"` // join duty cycle and retransmission timing
+}
+
+// MaxConfirmedRetransmissions returns the retransmissions of a ConfirmedDataUp without ACK:
+// nbRetransmission, or NbTrans - 1 with the LoRaWAN 1.0.4 retransmission
+func (c *Configuration) MaxConfirmedRetransmissions() int {
+
+	if c.Backoff.Retransmission.Enabled {
+		return int(c.NbRepUnconfirmedDataUp) - 1
+	}
+
+	return c.NbRepConfirmedDataUp
 }
 
 func (c *Configuration) MarshalJSON() ([]byte, error) {
@@ -29,6 +29,10 @@ type InformationDevice struct {
 	Location location.Location `json:"location"`
 	RX       []features.Window `json:"rxs"` //RX[0] = rx1 RX[1] = rx2
 
+	Session *Session `json:"session,omitempty"` // restored at the next start
+
+	Tags []string `json:"tags,omitempty"`
+
 	Forwarder        *f.Forwarder        `json:"-"`
 	ReceivedDownlink dl.ReceivedDownlink `json:"-"`
 }
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/features"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/channels"
+	"github.com/brocaar/lorawan"
+)
+
+// Session is the MAC state negotiated with the network server, restored at the next start
+type Session struct {
+	Joined    bool              `json:"joined"`
+	DevNonce  lorawan.DevNonce  `json:"devNonce"`
+	JoinNonce lorawan.JoinNonce `json:"joinNonce"`
+	NetID     lorawan.NetID     `json:"netID"`
+
+	DataRate uint8 `json:"dataRate"`
+	TXPower  uint8 `json:"txPower"`
+	NbTrans  uint8 `json:"nbTrans"`
+
+	Channels    []channels.Channel `json:"channels"`
+	RX          []features.Window  `json:"rxs"`
+	RX1DROffset uint8              `json:"rx1DROffset"`
+
+	UplinkDwellTime   lorawan.DwellTime `json:"uplinkDwellTime"`
+	DownlinkDwellTime lorawan.DwellTime `json:"downlinkDwellTime"`
+
+	ADR       bool `json:"adr"`
+	ADRACKCnt int8 `json:"adrAckCnt"`
+
+	FCnt     uint32 `json:"fcnt"` // counters in NVM at the save, restored only after a brown-out
+	FCntDown uint32 `json:"fcntDown"`
+
+	Saved time.Time `json:"saved"`
+}
+
+// GetSession returns a copy of the current MAC state saved at now, the time of the simulation
+func (d *InformationDevice) GetSession(now time.Time) *Session {
+
+	s := Session{
+		Joined:    d.Status.Joined,
+		DevNonce:  d.DevNonce,
+		JoinNonce: d.JoinNonce,
+		NetID:     d.NetID,
+
+		DataRate: d.Status.DataRate,
+		TXPower:  d.Status.TXPower,
+		NbTrans:  d.Configuration.NbRepUnconfirmedDataUp,
+
+		Channels:    append([]channels.Channel(nil), d.Configuration.Channels...),
+		RX:          append([]features.Window(nil), d.RX...),
+		RX1DROffset: d.Configuration.RX1DROffset,
+
+		UplinkDwellTime:   d.Status.DataUplink.DwellTime,
+		DownlinkDwellTime: d.Status.DataDownlink.DwellTime,
+
+		ADR:       d.Status.DataUplink.ADR.ADR,
+		ADRACKCnt: d.Status.DataUplink.ADR.ADRACKCnt,
+
+		FCnt:     d.Status.DataUplink.FCnt,
+		FCntDown: d.Status.FCntDown,
+
+		Saved: now,
+	}
+
+	return &s
+}
+
+// GetNVMSession returns a session without MAC state: the device joins again, the DevNonce
+// and the frame counters kept in NVM survive
+func (d *InformationDevice) GetNVMSession(now time.Time) *Session {
+
+	s := Session{
+		DevNonce: d.DevNonce,
+
+		FCnt:     d.Status.DataUplink.FCnt,
+		FCntDown: d.Status.FCntDown,
+
+		Saved: now,
+	}
+
+	return &s
+}
+
+// RestoreSession applies a saved MAC state, after the device's setup
+func (d *InformationDevice) RestoreSession(s *Session) {
+
+	d.Status.Joined = s.Joined
+	d.DevNonce = s.DevNonce
+	d.JoinNonce = s.JoinNonce
+	d.NetID = s.NetID
+
+	d.Status.DataRate = s.DataRate
+	d.Status.TXPower = s.TXPower
+	if s.NbTrans > 0 {
+		d.Configuration.NbRepUnconfirmedDataUp = s.NbTrans
+	}
+
+	if len(s.Channels) == len(d.Configuration.Channels) { // same region
+		copy(d.Configuration.Channels, s.Channels)
+	}
+
+	for i := range s.RX {
+		if i < len(d.RX) {
+			d.RX[i].SetChannel(s.RX[i].Channel)
+			d.RX[i].Delay = s.RX[i].Delay
+			d.RX[i].DataRate = s.RX[i].DataRate
+		}
+	}
+	d.Configuration.RX1DROffset = s.RX1DROffset
+
+	d.Status.DataUplink.DwellTime = s.UplinkDwellTime
+	d.Status.DataDownlink.DwellTime = s.DownlinkDwellTime
+
+	d.Status.DataUplink.ADR.ADR = s.ADR
+	d.Status.DataUplink.ADR.ADRACKCnt = s.ADRACKCnt
+}
@@ -6,6 +6,12 @@ import (
 
 	modelClass "github.com/arslab/lwnsimulator/simulator/components/device/classes/models_classes"
 	"github.com/arslab/lwnsimulator/simulator/components/device/features/channels"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/codec"
+	ds "github.com/arslab/lwnsimulator/simulator/components/device/features/datasource"
+	gen "github.com/arslab/lwnsimulator/simulator/components/device/features/generator"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/handler"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/schedule"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/stream"
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	up "github.com/arslab/lwnsimulator/simulator/components/device/frames/uplink"
 	mup "github.com/arslab/lwnsimulator/simulator/components/device/frames/uplink/models"
@@ -22,8 +28,16 @@ type Status struct {
 	Payload       lorawan.Payload `json:"payload"` // from UI
 	BufferUplinks []mup.InfoFrame `json:"-"`       // from socket
 
+	Generator  *gen.Generator `json:"generator,omitempty"`  // replaces Payload if set
+	Codec      *codec.Codec   `json:"codec,omitempty"`      // replaces Payload if set
+	DataSource *ds.DataSource `json:"dataSource,omitempty"` // replaces Payload if set
+
+	Schedule *schedule.Schedule `json:"schedule,omitempty"` // uplinks at absolute times and on events
+	Streams  []stream.Stream    `json:"streams,omitempty"`  // independent uplinks on their own FPort
+
 	DataDownlink dl.InformationDownlink `json:"-"`
 	FCntDown     uint32                 `json:"fcntDown"`
+	Downlinks    handler.Handler        `json:"downlinks"` // application downlinks and reactions
 
 	DataRate uint8 `json:"-"`
 	TXPower  uint8 `json:"-"`
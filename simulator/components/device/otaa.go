@@ -1,7 +1,7 @@
 package device
 
 import (
-	"math/rand"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -9,6 +9,7 @@ import (
 
 	act "github.com/arslab/lwnsimulator/simulator/components/device/activation"
 	"github.com/arslab/lwnsimulator/simulator/components/device/classes"
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/airtime"
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	"github.com/brocaar/lorawan"
 )
@@ -16,11 +17,23 @@ import (
 const (
 	JOINACCEPTDELAY1 = time.Duration(5 * time.Second)
 	JOINACCEPTDELAY2 = time.Duration(1 * time.Second)
+
+	JoinRequestSize = 23 // MHDR, JoinEUI, DevEUI, DevNonce and MIC
 )
 
-func (d *Device) OtaaActivation() {
+// OtaaActivation sends JOIN REQUESTs until the device is joined, then calls done. The receive
+// windows, the ACK timeout and the backoff between the JOIN REQUESTs are events of the scheduler
+func (d *Device) OtaaActivation(done func()) {
+
+	var first time.Time // first JOIN REQUEST of the activation
+	var join func()
 
-	for !d.Info.Status.Joined {
+	join = func() {
+
+		if d.Info.Status.Joined {
+			done()
+			return
+		}
 
 		d.Info.Status.Mode = util.Activation
 
@@ -30,51 +43,92 @@ func (d *Device) OtaaActivation() {
 
 		d.SwitchClass(classes.ClassA)
 
+		if d.powerLost() {
+			join()
+			return
+		}
+
+		sent := d.now()
+		if first.IsZero() {
+			first = sent
+		}
+
 		d.SendJoinRequest()
 
 		d.Print("Open RXs for "+strconv.Itoa(int(d.Info.RX[0].Channel.FrequencyDownlink))+
 			" and "+strconv.Itoa(int(d.Info.RX[1].Channel.FrequencyDownlink)), nil, util.PrintBoth)
 
-		phy := d.Class.ReceiveWindows(JOINACCEPTDELAY1, JOINACCEPTDELAY2)
-		if phy != nil {
+		d.ReceiveWindows(JOINACCEPTDELAY1, JOINACCEPTDELAY2, func(phy *lorawan.PHYPayload) {
+
+			retry := func() {
+
+				if d.Info.Status.Joined {
+
+					d.recordJoin(d.now().Sub(first))
+					d.Info.Status.Mode = util.Normal
+
+					done()
+					return
+				}
+
+				d.Print("Unjoined", nil, util.PrintBoth)
+
+				delay := d.joinBackoff(sent) - d.now().Sub(sent)
+				if delay <= 0 {
+					join()
+					return
+				}
+
+				d.Print(fmt.Sprintf("Next JOIN REQUEST in %v", delay.Round(time.Second)), nil, util.PrintBoth)
+
+				d.after(delay, join)
+			}
+
+			if phy == nil {
+				d.Print("None downlink received", nil, util.PrintBoth)
+				retry()
+				return
+			}
 
 			d.Print("Downlink received", nil, util.PrintBoth)
 
-			_, err := d.ProcessDownlink(*phy)
-			if err != nil {
-				d.Print("", err, util.PrintBoth)
+			if _, err := d.ProcessDownlink(*phy); err != nil {
 
-				timerAckTimeout := time.NewTimer(d.Info.Configuration.AckTimeout)
-				<-timerAckTimeout.C
+				d.Print("", err, util.PrintBoth)
 
-				d.Print("ACK Timeout", nil, util.PrintBoth)
+				d.after(d.Info.Configuration.AckTimeout, func() {
+					d.Print("ACK Timeout", nil, util.PrintBoth)
+					retry()
+				})
+				return
 			}
-		} else {
-			d.Print("None downlink received", nil, util.PrintBoth)
-		}
 
-		if d.Info.Status.Joined {
+			retry()
+		})
+	}
 
-			d.Print("Joined", nil, util.PrintBoth)
-			d.Info.Status.Mode = util.Normal
+	join()
+}
 
-			return
-		}
+// joinBackoff returns the wait between two JOIN REQUESTs, the duty cycle applies to their time on air
+func (d *Device) joinBackoff(sent time.Time) time.Duration {
 
-		d.Print("Unjoined", nil, util.PrintBoth)
+	_, datr := d.Info.Configuration.Region.GetDataRate(d.Info.Status.DataRate)
+	codr := d.Info.Configuration.Region.GetCodR(d.Info.Status.DataRate)
 
+	toa, err := airtime.TimeOnAir(datr, codr, JoinRequestSize)
+	if err != nil { // FSK, only the random delay
+		toa = 0
 	}
 
-	return
+	return d.Info.Configuration.Backoff.Join.Delay(sent, toa)
 }
 
+// CreateJoinRequest uses the DevNonce following the last one sent: LoRaWAN 1.0.4 counts
+// it up and a network server rejects a DevNonce not above the last it received
 func (d *Device) CreateJoinRequest() []byte {
 
-	rand.Seed(time.Now().UTC().UnixNano())
-	random := uint16(rand.Int())
-
-	DevNonce := lorawan.DevNonce(random)
-	d.Info.DevNonce = DevNonce
+	d.Info.DevNonce++
 
 	phy := lorawan.PHYPayload{
 		MHDR: lorawan.MHDR{
@@ -0,0 +1,176 @@
+package device
+
+import (
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/airtime"
+	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
+	"github.com/arslab/lwnsimulator/simulator/util"
+	"github.com/brocaar/lorawan"
+)
+
+// recordUplink publishes the frame just sent, decoded as the network server would
+func (d *Device) recordUplink(frame []byte, info pkt.RXPK) {
+
+	d.lastUplink = d.now()
+
+	var phy lorawan.PHYPayload
+	if err := phy.UnmarshalBinary(frame); err != nil {
+		d.Print("", err, util.PrintOnlyConsole)
+		return
+	}
+
+	up := events.Uplink{
+		DevEUI:    d.Info.DevEUI,
+		MType:     phy.MHDR.MType.String(),
+		DataRate:  d.Info.Status.DataRate,
+		Frequency: hz(info.Frequency),
+		Size:      len(frame),
+		Airtime:   timeOnAir(info, len(frame)),
+		Delivered: d.Info.Forwarder.Reach(d.Info.DevEUI) > 0,
+	}
+
+	if mac, ok := phy.MACPayload.(*lorawan.MACPayload); ok {
+		up.DevAddr = mac.FHDR.DevAddr
+		up.FCnt = mac.FHDR.FCnt
+		up.FPort = mac.FPort
+		up.ACK = mac.FHDR.FCtrl.ACK
+	}
+
+	d.publish(events.TypeUplink, &up)
+}
+
+// recordJoinRequest publishes the JOIN REQUEST just sent
+func (d *Device) recordJoinRequest(frame []byte, info pkt.RXPK) {
+
+	req := events.JoinRequest{
+		DevEUI:    d.Info.DevEUI,
+		JoinEUI:   d.Info.JoinEUI,
+		DevNonce:  uint16(d.Info.DevNonce),
+		DataRate:  d.Info.Status.DataRate,
+		Frequency: hz(info.Frequency),
+		Size:      len(frame),
+		Airtime:   timeOnAir(info, len(frame)),
+	}
+
+	d.publish(events.TypeJoinRequest, &req)
+}
+
+// recordDownlink publishes the downlink just received in the receive windows
+func (d *Device) recordDownlink(phy lorawan.PHYPayload) {
+
+	for i := range d.Info.RX {
+
+		received := d.Info.RX[i].Received
+		if received.IsZero() {
+			continue
+		}
+
+		window := events.WindowRX1
+		if i == 1 {
+			window = events.WindowRX2
+		}
+
+		d.publishDownlink(phy, window, received.Sub(d.lastUplink))
+		return
+	}
+}
+
+// publishDownlink publishes a downlink, the payload is still encrypted
+func (d *Device) publishDownlink(phy lorawan.PHYPayload, window string, latency time.Duration) {
+
+	down := events.Downlink{
+		DevEUI:  d.Info.DevEUI,
+		MType:   phy.MHDR.MType.String(),
+		Window:  window,
+		Latency: latency,
+	}
+
+	if frame, err := phy.MarshalBinary(); err == nil {
+		down.Size = len(frame)
+	}
+
+	if mac, ok := phy.MACPayload.(*lorawan.MACPayload); ok {
+		down.FCnt = mac.FHDR.FCnt
+		down.FPort = mac.FPort
+		down.ACK = mac.FHDR.FCtrl.ACK
+	}
+
+	d.publish(events.TypeDownlink, &down)
+}
+
+// recordDownlinkIn publishes a downlink received without uplink, in a ping slot of class B or
+// in the continuous RX2 of class C
+func (d *Device) recordDownlinkIn(window string, downlink dl.InformationDownlink) {
+
+	down := events.Downlink{
+		DevEUI: d.Info.DevEUI,
+		MType:  downlink.MType.String(),
+		ACK:    downlink.ACK,
+		Window: window,
+	}
+
+	if len(downlink.DataPayload) > 0 {
+		fport := downlink.FPort
+		down.FPort = &fport
+	}
+
+	d.publish(events.TypeDownlink, &down)
+}
+
+// recordJoin publishes the join accepted, latency from the first JOIN REQUEST
+func (d *Device) recordJoin(latency time.Duration) {
+
+	d.publish(events.TypeJoinAccept, &events.JoinAccept{
+		DevEUI:  d.Info.DevEUI,
+		DevAddr: d.Info.DevAddr,
+		Latency: latency,
+	})
+}
+
+// recordAckTimeout publishes the receive windows closed without downlink
+func (d *Device) recordAckTimeout() {
+
+	d.publish(events.TypeAckTimeout, &events.AckTimeout{
+		DevEUI: d.Info.DevEUI,
+		FCnt:   d.lastFCnt(),
+	})
+}
+
+// lastFCnt returns the counter of the last uplink, sent again in retransmission
+func (d *Device) lastFCnt() uint32 {
+	return (d.Info.Status.DataUplink.FCnt + util.MAXFCNTGAP - 1) % util.MAXFCNTGAP
+}
+
+// publish sends an event of the device on the bus
+func (d *Device) publish(eventType string, data interface{}) {
+
+	if d.Resources == nil { // not set up yet
+		return
+	}
+
+	d.Resources.Events.Publish(events.Event{
+		Type:   eventType,
+		Time:   d.now(),
+		Source: events.SourceDevice,
+		Id:     d.Id,
+		Name:   d.Info.Name,
+		Data:   data,
+	})
+}
+
+func timeOnAir(info pkt.RXPK, size int) time.Duration {
+
+	toa, err := airtime.TimeOnAir(info.DatR, info.CodR, size)
+	if err != nil { // FSK
+		return 0
+	}
+
+	return toa
+}
+
+func hz(mhz float64) uint32 {
+	return uint32(mhz*1000000 + 0.5)
+}
@@ -0,0 +1,172 @@
+package device
+
+import (
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/features/schedule"
+	mup "github.com/arslab/lwnsimulator/simulator/components/device/frames/uplink/models"
+	"github.com/arslab/lwnsimulator/simulator/util"
+	"github.com/brocaar/lorawan"
+)
+
+// TriggerUplink sends an uplink now, without waiting the send interval.
+// An empty payload sends the device's payload
+func (d *Device) TriggerUplink(mtype lorawan.MType, fport uint8, payload string) {
+
+	if payload != "" {
+		d.Apply(func() {
+			d.queueUplink(schedule.Uplink{
+				FPort:     fport,
+				Payload:   payload,
+				Confirmed: mtype == lorawan.ConfirmedDataUp,
+			})
+		})
+	}
+
+	d.wakeUp()
+}
+
+// wakeUp sends the queued uplinks without waiting the send interval, a pending wake up is enough
+func (d *Device) wakeUp() {
+	d.dispatch(eventWake)
+}
+
+// scheduleWakeUp schedules the next absolute-time or stream uplink
+func (d *Device) scheduleWakeUp() {
+
+	var next time.Time
+
+	if d.Info.Status.Schedule != nil {
+		next = d.Info.Status.Schedule.Next()
+	}
+
+	for i := range d.Info.Status.Streams {
+		n := d.Info.Status.Streams[i].Next()
+		if !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+
+	d.schedule(&d.scheduleTask, next, eventScheduled)
+}
+
+// queueScheduled appends the uplinks due at now to the buffer
+func (d *Device) queueScheduled(now time.Time) {
+
+	if d.Info.Status.Schedule != nil {
+
+		uplinks := d.Info.Status.Schedule.Due(now)
+
+		for _, uplink := range uplinks {
+			d.queueUplink(uplink)
+		}
+
+		if len(uplinks) > 0 {
+			d.Print("Scheduled uplink", nil, util.PrintBoth)
+		}
+
+	}
+
+	for i := range d.Info.Status.Streams {
+
+		s := &d.Info.Status.Streams[i]
+		if !s.Due(now) {
+			continue
+		}
+
+		bytes, err := s.Bytes(now, d.Info.Location)
+		if err != nil {
+			d.Print("", err, util.PrintBoth)
+			continue
+		}
+
+		mtype := lorawan.UnconfirmedDataUp
+		if s.Confirmed {
+			mtype = lorawan.ConfirmedDataUp
+		}
+
+		var payload lorawan.Payload = &lorawan.DataPayload{Bytes: bytes}
+		if bytes == nil {
+			if payload = d.generatePayload(); payload == nil {
+				continue
+			}
+		}
+
+		d.Info.Status.BufferUplinks = append(d.Info.Status.BufferUplinks, mup.InfoFrame{
+			MType:     mtype,
+			FPort:     s.FPort,
+			Payload:   payload,
+			Triggered: true,
+		})
+
+		d.Print("Stream "+s.Name+" queued", nil, util.PrintOnlyConsole)
+	}
+
+}
+
+// checkAlarms queues the uplinks of the alarms raised by the last generated values
+func (d *Device) checkAlarms() {
+
+	if d.Info.Status.Schedule == nil || d.Info.Status.Generator == nil {
+		return
+	}
+
+	uplinks := d.Info.Status.Schedule.Check(d.Info.Status.Generator.Values())
+
+	for _, uplink := range uplinks {
+		d.queueUplink(uplink)
+	}
+
+	if len(uplinks) > 0 {
+		d.Print("Alarm raised", nil, util.PrintBoth)
+		d.wakeUp()
+	}
+
+}
+
+func (d *Device) queueUplink(uplink schedule.Uplink) {
+
+	info := mup.InfoFrame{
+		MType:     lorawan.UnconfirmedDataUp,
+		FPort:     uplink.FPort,
+		Triggered: true,
+	}
+
+	if uplink.Confirmed {
+		info.MType = lorawan.ConfirmedDataUp
+	}
+
+	if uplink.Payload != "" {
+		info.Payload = &lorawan.DataPayload{Bytes: []byte(uplink.Payload)}
+	} else if info.Payload = d.generatePayload(); info.Payload == nil {
+		return
+	}
+
+	d.Info.Status.BufferUplinks = append(d.Info.Status.BufferUplinks, info)
+}
+
+// pendingTriggered is true if the next queued uplink doesn't wait the send interval.
+// Retransmissions and joins keep their own timing
+func (d *Device) pendingTriggered() bool {
+
+	if !d.Info.Status.Joined || d.Info.Status.Mode != util.Normal {
+		return false
+	}
+
+	return len(d.Info.Status.BufferUplinks) > 0 && d.Info.Status.BufferUplinks[0].Triggered
+}
+
+// subscribe registers the device to the uplinks of the devices in Schedule.After
+func (d *Device) subscribe() {
+
+	d.Resources.Triggers.Unsubscribe(d.Id)
+
+	if d.Info.Status.Schedule == nil {
+		return
+	}
+
+	for _, name := range d.Info.Status.Schedule.After {
+		d.Resources.Triggers.Subscribe(name, d.Id, d.wakeUp)
+	}
+
+}
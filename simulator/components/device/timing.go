@@ -0,0 +1,48 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/features"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/arslab/lwnsimulator/simulator/util"
+)
+
+// reportReceptions reports the downlinks lost by the timing model of the device in the receive windows
+func (d *Device) reportReceptions() {
+
+	for i, window := range []string{events.WindowRX1, events.WindowRX2} {
+		if i < len(d.Info.RX) {
+			d.reportReception(window, d.Info.RX[i].LastReception)
+		}
+	}
+}
+
+// reportReception reports the position of the downlink in the window, if the timing model is enabled:
+// a missed downlink is published, the metrics count it
+func (d *Device) reportReception(window string, reception features.Reception) {
+
+	if !d.Info.Configuration.Timing.Enabled() {
+		return
+	}
+
+	if reception.Open.IsZero() { //no downlink in this window
+		return
+	}
+
+	if reception.PreambleOK {
+
+		msg := fmt.Sprintf("Downlink locked in %v with a margin of %v", strings.ToUpper(window), reception.Margin)
+		d.Print(msg, nil, util.PrintOnlyConsole)
+
+	} else {
+
+		d.publish(events.TypeDownlinkMissed, &events.DownlinkMissed{
+			DevEUI: d.Info.DevEUI,
+			Window: window,
+			Margin: reception.Margin,
+		})
+
+	}
+}
@@ -15,6 +15,7 @@ func (d *Device) CreateUplink() [][]byte {
 
 	var mtype lorawan.MType
 	var payload lorawan.Payload
+	fport := d.Info.Status.DataUplink.FPort
 	var DataPayload []lorawan.DataPayload
 	var frames [][]byte
 
@@ -43,6 +44,10 @@ func (d *Device) CreateUplink() [][]byte {
 
 			mtype = d.Info.Status.BufferUplinks[0].MType
 			payload = d.Info.Status.BufferUplinks[0].Payload
+			if d.Info.Status.BufferUplinks[0].FPort != 0 {
+				port := d.Info.Status.BufferUplinks[0].FPort
+				fport = &port
+			}
 
 			switch len(d.Info.Status.BufferUplinks) {
 			case 1:
@@ -55,13 +60,17 @@ func (d *Device) CreateUplink() [][]byte {
 
 		} else {
 			mtype = d.Info.Status.MType
-			payload = d.Info.Status.Payload
+			payload = d.generatePayload()
 		}
 
 		d.Info.Status.LastMType = mtype
 
 	}
 
+	if payload == nil { //data source exhausted
+		return frames
+	}
+
 	m, n := d.Info.Configuration.Region.GetPayloadSize(d.Info.Status.DataRate, d.Info.Status.DataUplink.DwellTime)
 
 	if d.Info.Configuration.SupportedFragment { //frammentazione
@@ -87,10 +96,10 @@ func (d *Device) CreateUplink() [][]byte {
 		alignedPayload := DataPayload[i]
 
 		if d.Info.Status.AlignCurrentTime {
-			alignedPayload = alignWithCurrentTime(alignedPayload)
+			alignedPayload = alignWithCurrentTime(alignedPayload, d.now())
 		}
 
-		frame, err := d.Info.Status.DataUplink.GetFrame(mtype, alignedPayload, d.Info.DevAddr, d.Info.AppSKey, d.Info.NwkSKey, false)
+		frame, err := d.Info.Status.DataUplink.GetFrameOnPort(mtype, fport, alignedPayload, d.Info.DevAddr, d.Info.AppSKey, d.Info.NwkSKey, false)
 		if err != nil {
 			d.Print("", err, util.PrintBoth)
 			continue
@@ -104,8 +113,46 @@ func (d *Device) CreateUplink() [][]byte {
 	return frames
 }
 
-func alignWithCurrentTime(payload lorawan.DataPayload) lorawan.DataPayload {
-	now := time.Now()
+func (d *Device) generatePayload() lorawan.Payload {
+
+	var bytes []byte
+	var err error
+
+	switch {
+
+	case d.Info.Status.DataSource != nil:
+
+		record, ok := d.Info.Status.DataSource.Next()
+		if !ok {
+			return nil
+		}
+		bytes = record.Payload
+
+	case d.Info.Status.Generator != nil:
+		bytes, err = d.Info.Status.Generator.Next(d.now())
+		if err == nil {
+			d.checkAlarms()
+		}
+
+	case d.Info.Status.Codec != nil:
+		bytes, err = d.Info.Status.Codec.Encode(d.now(), d.Info.Location)
+
+	default:
+		return d.Info.Status.Payload
+
+	}
+
+	if err != nil {
+		d.Print("", err, util.PrintBoth)
+		return d.Info.Status.Payload
+	}
+
+	return &lorawan.DataPayload{
+		Bytes: bytes,
+	}
+}
+
+func alignWithCurrentTime(payload lorawan.DataPayload, now time.Time) lorawan.DataPayload {
 	currentTime := now.UnixMilli() / 1000
 	currentTimeBytes := make([]byte, 4)
 	binary.BigEndian.PutUint32(currentTimeBytes, uint32(currentTime))
@@ -150,9 +197,8 @@ func (d *Device) SendEmptyFrame() {
 	emptyFrame := d.CreateEmptyFrame()
 	info := d.SetInfo(emptyFrame, false)
 
-	d.Class.SendData(info)
-
-	d.Print("Empty Frame sent", nil, util.PrintBoth)
+	d.Class.SendData(info, d.now())
+	d.recordUplink(emptyFrame, info)
 }
 
 func (d *Device) SendAck() {
@@ -160,9 +206,8 @@ func (d *Device) SendAck() {
 	ack := d.CreateACK()
 	info := d.SetInfo(ack, false)
 
-	d.Class.SendData(info)
-
-	d.Print("ACK sent", nil, util.PrintBoth)
+	d.Class.SendData(info, d.now())
+	d.recordUplink(ack, info)
 }
 
 func (d *Device) SendJoinRequest() {
@@ -170,6 +215,6 @@ func (d *Device) SendJoinRequest() {
 	JoinRequest := d.CreateJoinRequest()
 	info := d.SetInfo(JoinRequest, true)
 
-	d.Class.SendData(info)
-	d.Print("JOIN REQUEST sent", nil, util.PrintBoth)
+	d.Class.SendData(info, d.now())
+	d.recordJoinRequest(JoinRequest, info)
 }
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/arslab/lwnsimulator/simulator/components/device/classes"
 	"github.com/arslab/lwnsimulator/simulator/components/device/features/channels"
 	"github.com/arslab/lwnsimulator/simulator/util"
 )
@@ -15,16 +14,6 @@ func (d *Device) CanExecute() bool {
 	d.Mutex.Lock()
 
 	if d.State == util.Stopped {
-
-		if d.Class.GetClass() == classes.ClassC {
-
-			d.Class.CloseRX2()
-
-			d.Info.Status.InfoClassC.WakeUpDevice()
-			d.Info.Status.InfoClassC.WakeUpClass()
-
-		}
-
 		return false
 	}
 
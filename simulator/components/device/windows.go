@@ -0,0 +1,157 @@
+package device
+
+import (
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/components/device/features"
+	"github.com/brocaar/lorawan"
+)
+
+// window is the receive window open, closed by its task or by the downlink received
+type window struct {
+	rx        *features.Window
+	index     int
+	open      time.Time
+	close     time.Time
+	listening uint32 // frequency registered to the forwarder
+
+	delays []time.Duration // of the windows of the uplink, none for a ping slot
+	done   func(*lorawan.PHYPayload)
+}
+
+// after continues the transaction with next once delay has elapsed: meanwhile the device has
+// no goroutine, and its events other than the downlinks wait the end of the transaction
+func (d *Device) after(delay time.Duration, next func()) {
+
+	d.Mutex.Lock()
+	d.waiting = next
+	d.Mutex.Unlock()
+
+	d.resumeAt = d.now().Add(delay)
+
+	d.schedule(&d.resumeTask, d.resumeAt, eventResume)
+}
+
+// resume runs the continuation of the transaction, a resume of a continuation replaced meanwhile is dropped
+func (d *Device) resume() {
+
+	if d.waiting == nil || d.now().Before(d.resumeAt) {
+		return
+	}
+
+	next := d.waiting
+
+	d.Mutex.Lock()
+	d.waiting = nil
+	d.Mutex.Unlock()
+
+	next()
+}
+
+// stopWaiting drops the continuation of the transaction
+func (d *Device) stopWaiting() {
+
+	d.Mutex.Lock()
+	d.waiting = nil
+	d.Mutex.Unlock()
+
+	d.schedule(&d.resumeTask, time.Time{}, eventResume)
+}
+
+// ReceiveWindows opens the receive windows of the class one after the other, then calls done
+// with the downlink received, nil if none. The opening and the closing of the windows are
+// events of the scheduler, a downlink received closes the window at once
+func (d *Device) ReceiveWindows(delayRX1 time.Duration, delayRX2 time.Duration, done func(*lorawan.PHYPayload)) {
+
+	for i := range d.Info.RX {
+		d.Info.RX[i].LastReception = features.Reception{}
+		d.Info.RX[i].Received = time.Time{}
+	}
+
+	d.Class.CloseRX2()
+
+	delays := []time.Duration{delayRX1, delayRX2}[:d.Class.Windows()]
+
+	d.openWindow(0, delays, func(phy *lorawan.PHYPayload) {
+
+		d.Class.OpenRX2()
+		d.reportReceptions()
+
+		done(phy)
+	})
+}
+
+// openWindow waits the opening of the window i, then listens until it closes
+func (d *Device) openWindow(i int, delays []time.Duration, done func(*lorawan.PHYPayload)) {
+
+	open, close := d.Info.RX[i].Schedule(d.now(), delays[i])
+
+	d.after(open.Sub(d.now()), func() {
+
+		w := &window{
+			rx:        &d.Info.RX[i],
+			index:     i,
+			open:      open,
+			close:     close,
+			listening: d.Info.RX[i].GetListeningFrequency(),
+			delays:    delays,
+			done:      done,
+		}
+
+		d.Info.Forwarder.Register(w.listening, d.Info.DevEUI, &d.Info.ReceivedDownlink)
+		d.rx = w
+
+		d.after(close.Sub(d.now()), d.closeWindow)
+	})
+}
+
+// closeWindow takes the downlink received, without it the next window of the uplink is opened
+func (d *Device) closeWindow() {
+
+	w := d.rx
+	d.rx = nil
+
+	d.stopWaiting() // closed by a downlink before its time
+
+	d.Info.Forwarder.UnRegister(w.listening, d.Info.DevEUI)
+
+	phy := w.rx.Close(w.open, w.close, d.now(), &d.Info.ReceivedDownlink)
+
+	if phy == nil && w.index+1 < len(w.delays) {
+		d.openWindow(w.index+1, w.delays, w.done)
+		return
+	}
+
+	w.done(phy)
+}
+
+// receiveDownlink handles a downlink received: it closes the receive window open, otherwise it's
+// a downlink of the RX2 always open of class C
+func (d *Device) receiveDownlink() {
+
+	if d.rx != nil {
+
+		if d.Info.ReceivedDownlink.Pending() { // else taken at the closing already
+			d.closeWindow()
+		}
+
+		return
+	}
+
+	d.receiveClassC()
+}
+
+// closeWindows releases the window open and the continuation of a device turned off
+func (d *Device) closeWindows() {
+
+	if d.rx != nil {
+		d.Info.Forwarder.UnRegister(d.rx.listening, d.Info.DevEUI)
+		d.rx = nil
+	}
+
+	d.ping = nil
+
+	d.Mutex.Lock()
+	d.waiting = nil
+	d.Mutex.Unlock()
+}
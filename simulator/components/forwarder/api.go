@@ -3,7 +3,6 @@ package forwarder
 import (
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	m "github.com/arslab/lwnsimulator/simulator/components/forwarder/models"
-	"github.com/arslab/lwnsimulator/simulator/resources/communication/buffer"
 	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
 	"github.com/brocaar/lorawan"
 )
@@ -11,156 +10,259 @@ import (
 func Setup() *Forwarder {
 
 	f := Forwarder{
-		DevToGw:  make(map[lorawan.EUI64]map[lorawan.EUI64]*buffer.BufferUplink),            //1[devEUI] 2 [macAddress]
-		GwtoDev:  make(map[uint32]map[lorawan.EUI64]map[lorawan.EUI64]*dl.ReceivedDownlink), //1[fre1] 2 [macAddress] 3[devEUI]
-		Devices:  make(map[lorawan.EUI64]m.InfoDevice),
-		Gateways: make(map[lorawan.EUI64]m.InfoGateway),
+		devices:     make(map[lorawan.EUI64]*deviceRoute),  //[devEUI]
+		gateways:    make(map[lorawan.EUI64]*gatewayRoute), //[macAddress]
+		deviceGrid:  newGrid(),
+		gatewayGrid: newGrid(),
 	}
 
 	return &f
 
 }
 
+// AddDevice adds the device or updates its position and range: only the gateways
+// in the cells around it are evaluated
 func (f *Forwarder) AddDevice(d m.InfoDevice) {
 
-	f.Mutex.Lock()
-	defer f.Mutex.Unlock()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 
-	f.Devices[d.DevEUI] = d
+	route, ok := f.devices[d.DevEUI]
+	if !ok {
+		route = &deviceRoute{
+			gateways:  make(map[lorawan.EUI64]*gatewayRoute),
+			listening: make(map[uint32]*dl.ReceivedDownlink),
+		}
+		f.devices[d.DevEUI] = route
+	}
 
-	inner := make(map[lorawan.EUI64]*buffer.BufferUplink)
-	f.DevToGw[d.DevEUI] = inner
+	route.info = d
+	f.deviceGrid.insert(d.DevEUI, d.Location)
 
-	for _, g := range f.Gateways {
+	if d.Range/1000.0 > f.maxRange {
+		f.maxRange = d.Range / 1000.0
+	}
 
-		if inRange(d, g) {
-			f.DevToGw[d.DevEUI][g.MACAddress] = g.Buffer
+	reachable := make(map[lorawan.EUI64]*gatewayRoute)
+	for _, mac := range f.gatewayGrid.near(d.Location, d.Range/1000.0) {
+		if g := f.gateways[mac]; inRange(d, g.info) {
+			reachable[mac] = g
 		}
+	}
 
+	for mac, g := range route.gateways {
+		if _, ok := reachable[mac]; !ok {
+			unlink(route, g)
+		}
+	}
+
+	for mac, g := range reachable {
+		if _, ok := route.gateways[mac]; !ok {
+			link(route, g)
+		}
 	}
 
 }
 
+// AddGateway adds the gateway or updates it: only the devices in the cells within
+// the largest range are evaluated
 func (f *Forwarder) AddGateway(g m.InfoGateway) {
 
-	f.Mutex.Lock()
-	defer f.Mutex.Unlock()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	route, ok := f.gateways[g.MACAddress]
+	if !ok {
+		route = &gatewayRoute{
+			devices:   make(map[lorawan.EUI64]*deviceRoute),
+			listeners: make(map[uint32]map[lorawan.EUI64]*dl.ReceivedDownlink),
+		}
+		f.gateways[g.MACAddress] = route
+	}
 
-	f.Gateways[g.MACAddress] = g
+	route.info = g
+	f.gatewayGrid.insert(g.MACAddress, g.Location)
 
-	for _, d := range f.Devices {
+	reachable := make(map[lorawan.EUI64]*deviceRoute)
+	for _, devEUI := range f.deviceGrid.near(g.Location, f.maxRange) {
+		if d := f.devices[devEUI]; inRange(d.info, g) {
+			reachable[devEUI] = d
+		}
+	}
 
-		if inRange(d, g) {
-			f.DevToGw[d.DevEUI][g.MACAddress] = g.Buffer
+	for devEUI, d := range route.devices {
+		if _, ok := reachable[devEUI]; !ok {
+			unlink(d, route)
 		}
+	}
 
+	for devEUI, d := range reachable {
+		if _, ok := route.devices[devEUI]; !ok {
+			link(d, route)
+		}
 	}
 }
 
 func (f *Forwarder) DeleteDevice(DevEUI lorawan.EUI64) {
 
-	f.Mutex.Lock()
-	defer f.Mutex.Unlock()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	route, ok := f.devices[DevEUI]
+	if !ok {
+		return
+	}
 
-	for key := range f.DevToGw[DevEUI] {
-		delete(f.DevToGw[DevEUI], key)
+	for _, g := range route.gateways {
+		unlink(route, g)
 	}
 
-	delete(f.DevToGw, DevEUI)
-	delete(f.Devices, DevEUI)
+	f.deviceGrid.remove(DevEUI)
+	delete(f.devices, DevEUI)
 
 }
 
 func (f *Forwarder) DeleteGateway(g m.InfoGateway) {
 
-	f.Mutex.Lock()
-	defer f.Mutex.Unlock()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 
-	for _, d := range f.Devices {
-		delete(f.DevToGw[d.DevEUI], g.MACAddress)
+	route, ok := f.gateways[g.MACAddress]
+	if !ok {
+		return
+	}
+
+	for _, d := range route.devices {
+		unlink(d, route)
 	}
 
-	delete(f.Gateways, g.MACAddress)
+	f.gatewayGrid.remove(g.MACAddress)
+	delete(f.gateways, g.MACAddress)
 
 }
 
+// UpdateDevice moves the device, the downlinks it's listening to follow it
 func (f *Forwarder) UpdateDevice(d m.InfoDevice) {
 	f.AddDevice(d)
 }
 
+func (f *Forwarder) UpdateGateway(g m.InfoGateway) {
+	f.AddGateway(g)
+}
+
 func (f *Forwarder) Register(freq uint32, devEUI lorawan.EUI64, rDownlink *dl.ReceivedDownlink) {
 
-	f.Mutex.Lock()
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
 
-	inner, ok := f.GwtoDev[freq]
+	route, ok := f.devices[devEUI]
 	if !ok {
-		inner = make(map[lorawan.EUI64]map[lorawan.EUI64]*dl.ReceivedDownlink)
-		f.GwtoDev[freq] = inner
+		return
 	}
 
-	for key := range f.DevToGw[devEUI] {
+	rDownlink.Open()
 
-		inner, ok := f.GwtoDev[freq][key]
-		if !ok {
-			inner = make(map[lorawan.EUI64]*dl.ReceivedDownlink)
-			f.GwtoDev[freq][key] = inner
-		}
-
-		rDownlink.Open()
-		f.GwtoDev[freq][key][devEUI] = rDownlink
+	route.mutex.Lock()
+	route.listening[freq] = rDownlink
+	route.mutex.Unlock()
 
+	for _, g := range route.gateways {
+		g.listen(freq, devEUI, rDownlink)
 	}
 
-	f.Mutex.Unlock()
-
 }
 
 func (f *Forwarder) UnRegister(freq uint32, devEUI lorawan.EUI64) {
 
-	f.Mutex.Lock()
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
 
-	for key := range f.DevToGw[devEUI] {
-
-		_, ok := f.GwtoDev[freq][key][devEUI]
-		if ok {
+	route, ok := f.devices[devEUI]
+	if !ok {
+		return
+	}
 
-			f.GwtoDev[freq][key][devEUI].Close()
-			delete(f.GwtoDev[freq][key], devEUI)
+	route.mutex.Lock()
+	rDownlink, ok := route.listening[freq]
+	delete(route.listening, freq)
+	route.mutex.Unlock()
 
-		}
+	if !ok {
+		return
+	}
 
+	for _, g := range route.gateways {
+		g.unlisten(freq, devEUI)
 	}
 
-	f.Mutex.Unlock()
+	rDownlink.Close()
 
 }
 
-func (f *Forwarder) Uplink(data pkt.RXPK, DevEUI lorawan.EUI64) {
+// Uplink returns the concentrator timestamp assigned to the uplink
+func (f *Forwarder) Uplink(data pkt.RXPK, DevEUI lorawan.EUI64) uint32 {
 
-	f.Mutex.Lock()
+	rxpk := createPacket(data, f.now())
 
-	rxpk := createPacket(data)
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
 
-	for _, up := range f.DevToGw[DevEUI] {
-		up.Push(rxpk)
+	if route, ok := f.devices[DevEUI]; ok {
+		for _, g := range route.gateways {
+			g.info.Buffer.Push(rxpk)
+		}
 	}
 
-	f.Mutex.Unlock()
+	return rxpk.Tmst
+}
+
+// Reach returns the gateways in range of the device
+func (f *Forwarder) Reach(DevEUI lorawan.EUI64) int {
+
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
 
+	route, ok := f.devices[DevEUI]
+	if !ok {
+		return 0
+	}
+
+	return len(route.gateways)
 }
 
-func (f *Forwarder) Downlink(data *lorawan.PHYPayload, freq uint32, macAddress lorawan.EUI64) {
+func (f *Forwarder) Downlink(data *lorawan.PHYPayload, freq uint32, macAddress lorawan.EUI64, tmst *uint32) {
+
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	g, ok := f.gateways[macAddress]
+	if !ok {
+		return
+	}
+
+	received := f.now()
 
-	f.Mutex.Lock()
+	g.mutex.Lock()
 
-	for _, dl := range f.GwtoDev[freq][macAddress] {
-		dl.Push(data)
+	for _, dl := range g.listeners[freq] {
+		dl.Push(data, tmst, received)
 	}
 
-	f.Mutex.Unlock()
+	g.mutex.Unlock()
 
 }
 
+// Reset drops the downlink registrations left by the stopped devices, the routes are kept
 func (f *Forwarder) Reset() {
-	f = Setup()
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, d := range f.devices {
+		d.listening = make(map[uint32]*dl.ReceivedDownlink)
+	}
+
+	for _, g := range f.gateways {
+		g.listeners = make(map[uint32]map[lorawan.EUI64]*dl.ReceivedDownlink)
+	}
 }
@@ -4,28 +4,51 @@ import (
 	"sync"
 	"time"
 
+	"github.com/arslab/lwnsimulator/simulator/clock"
 	dl "github.com/arslab/lwnsimulator/simulator/components/device/frames/downlink"
 	m "github.com/arslab/lwnsimulator/simulator/components/forwarder/models"
-	"github.com/arslab/lwnsimulator/simulator/resources/communication/buffer"
 	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
 	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
 	"github.com/brocaar/lorawan"
 )
 
+// Forwarder routes the frames between the devices and the gateways in range.
+// The topology is read-locked by the uplinks and the downlinks, which run in parallel;
+// the downlink listeners of each gateway have a lock of their own
 type Forwarder struct {
-	DevToGw  map[lorawan.EUI64]map[lorawan.EUI64]*buffer.BufferUplink            // populates in setup and can update itself
-	GwtoDev  map[uint32]map[lorawan.EUI64]map[lorawan.EUI64]*dl.ReceivedDownlink // populates with register/unRegister
-	Devices  map[lorawan.EUI64]m.InfoDevice
-	Gateways map[lorawan.EUI64]m.InfoGateway
-	Mutex    sync.Mutex
+	devices     map[lorawan.EUI64]*deviceRoute
+	gateways    map[lorawan.EUI64]*gatewayRoute
+	deviceGrid  *grid
+	gatewayGrid *grid
+	maxRange    float64 // km, the largest range of the devices added
+	mutex       sync.RWMutex
+
+	Clock clock.Clock // time of the packets, nil is the wall clock
+}
+
+// deviceRoute is a device with the gateways in its range
+type deviceRoute struct {
+	info     m.InfoDevice
+	gateways map[lorawan.EUI64]*gatewayRoute
+
+	listening map[uint32]*dl.ReceivedDownlink // frequencies registered, kept when the routes change
+	mutex     sync.Mutex
+}
+
+// gatewayRoute is a gateway with the devices in range and the ones listening to its downlinks
+type gatewayRoute struct {
+	info    m.InfoGateway
+	devices map[lorawan.EUI64]*deviceRoute
+
+	listeners map[uint32]map[lorawan.EUI64]*dl.ReceivedDownlink // 1[freq] 2[devEUI]
+	mutex     sync.Mutex
 }
 
 // GPS offset compensates for the drift between UTC and GPS time
 const GPSOffset = 18000
 
-func createPacket(info pkt.RXPK) pkt.RXPK {
+func createPacket(info pkt.RXPK, tnow time.Time) pkt.RXPK {
 
-	tnow := time.Now()
 	offset, _ := time.Parse(time.RFC3339, "1980-01-06T00:00:00Z")
 	tmms := tnow.UnixMilli() - offset.UnixMilli() + GPSOffset
 
@@ -33,7 +56,7 @@ func createPacket(info pkt.RXPK) pkt.RXPK {
 
 		Time:      tnow.Format(time.RFC3339),
 		Tmms:      &tmms,
-		Tmst:      uint32(tnow.Unix()),
+		Tmst:      uint32(tnow.UnixMicro()), // internal concentrator counter (µs)
 		Channel:   info.Channel,
 		RFCH:      0,
 		Frequency: info.Frequency,
@@ -51,6 +74,15 @@ func createPacket(info pkt.RXPK) pkt.RXPK {
 	return rxpk
 }
 
+func (f *Forwarder) now() time.Time {
+
+	if f.Clock == nil {
+		return time.Now()
+	}
+
+	return f.Clock.Now()
+}
+
 func inRange(d m.InfoDevice, g m.InfoGateway) bool {
 
 	distance := loc.GetDistance(d.Location.Latitude, d.Location.Longitude,
@@ -62,3 +94,47 @@ func inRange(d m.InfoDevice, g m.InfoGateway) bool {
 
 	return false
 }
+
+// link connects a device and a gateway in range, called with the topology locked
+func link(d *deviceRoute, g *gatewayRoute) {
+
+	d.gateways[g.info.MACAddress] = g
+	g.devices[d.info.DevEUI] = d
+
+	for freq, rDownlink := range d.listening {
+		g.listen(freq, d.info.DevEUI, rDownlink)
+	}
+}
+
+// unlink disconnects a device and a gateway, called with the topology locked
+func unlink(d *deviceRoute, g *gatewayRoute) {
+
+	delete(d.gateways, g.info.MACAddress)
+	delete(g.devices, d.info.DevEUI)
+
+	for freq := range d.listening {
+		g.unlisten(freq, d.info.DevEUI)
+	}
+}
+
+func (g *gatewayRoute) listen(freq uint32, devEUI lorawan.EUI64, rDownlink *dl.ReceivedDownlink) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	inner, ok := g.listeners[freq]
+	if !ok {
+		inner = make(map[lorawan.EUI64]*dl.ReceivedDownlink)
+		g.listeners[freq] = inner
+	}
+
+	inner[devEUI] = rDownlink
+}
+
+func (g *gatewayRoute) unlisten(freq uint32, devEUI lorawan.EUI64) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	delete(g.listeners[freq], devEUI)
+}
@@ -0,0 +1,122 @@
+package forwarder
+
+import (
+	"math"
+
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+	"github.com/brocaar/lorawan"
+)
+
+const (
+	// CellSize is the side of the cells of the spatial index, in degrees (about 11 km of latitude)
+	CellSize = 0.1
+
+	kmPerDegree = loc.RADIUS * math.Pi / 180
+)
+
+type cell struct {
+	lat int
+	lon int
+}
+
+// grid indexes the components by position: a reachability query visits only the cells
+// around the point instead of all the components
+type grid struct {
+	cells     map[cell]map[lorawan.EUI64]struct{}
+	positions map[lorawan.EUI64]cell
+}
+
+func newGrid() *grid {
+	return &grid{
+		cells:     make(map[cell]map[lorawan.EUI64]struct{}),
+		positions: make(map[lorawan.EUI64]cell),
+	}
+}
+
+func cellOf(l loc.Location) cell {
+	return cell{
+		lat: int(math.Floor(l.Latitude / CellSize)),
+		lon: int(math.Floor(l.Longitude / CellSize)),
+	}
+}
+
+// insert adds the component or moves it to its new position
+func (g *grid) insert(eui lorawan.EUI64, l loc.Location) {
+
+	g.remove(eui)
+
+	c := cellOf(l)
+
+	inner, ok := g.cells[c]
+	if !ok {
+		inner = make(map[lorawan.EUI64]struct{})
+		g.cells[c] = inner
+	}
+
+	inner[eui] = struct{}{}
+	g.positions[eui] = c
+}
+
+func (g *grid) remove(eui lorawan.EUI64) {
+
+	c, ok := g.positions[eui]
+	if !ok {
+		return
+	}
+
+	delete(g.cells[c], eui)
+	if len(g.cells[c]) == 0 {
+		delete(g.cells, c)
+	}
+
+	delete(g.positions, eui)
+}
+
+// near returns the components in the cells within km of l, a superset of the ones in range
+func (g *grid) near(l loc.Location, km float64) []lorawan.EUI64 {
+
+	dLat := km / kmPerDegree
+
+	// longitude degrees shrink with the latitude: near the poles and around the
+	// antimeridian all the cells are candidates
+	cos := math.Cos(loc.Radians(math.Min(math.Abs(l.Latitude)+dLat, 90)))
+	all := cos < 0.01
+
+	var dLon float64
+	if !all {
+		dLon = dLat / cos
+		all = l.Longitude-dLon < -180 || l.Longitude+dLon > 180
+	}
+
+	min := cellOf(loc.Location{Latitude: l.Latitude - dLat, Longitude: l.Longitude - dLon})
+	max := cellOf(loc.Location{Latitude: l.Latitude + dLat, Longitude: l.Longitude + dLon})
+
+	var result []lorawan.EUI64
+
+	// a wide range visits the occupied cells, fewer than the ones it covers
+	if all || (max.lat-min.lat+1)*(max.lon-min.lon+1) > len(g.cells) {
+
+		for c, inner := range g.cells {
+
+			if c.lat < min.lat || c.lat > max.lat || (!all && (c.lon < min.lon || c.lon > max.lon)) {
+				continue
+			}
+
+			for eui := range inner {
+				result = append(result, eui)
+			}
+		}
+
+		return result
+	}
+
+	for lat := min.lat; lat <= max.lat; lat++ {
+		for lon := min.lon; lon <= max.lon; lon++ {
+			for eui := range g.cells[cell{lat: lat, lon: lon}] {
+				result = append(result, eui)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,155 @@
+package forwarder
+
+import (
+	"math/rand"
+	"testing"
+
+	m "github.com/arslab/lwnsimulator/simulator/components/forwarder/models"
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+	"github.com/brocaar/lorawan"
+)
+
+func eui(n int) lorawan.EUI64 {
+	return lorawan.EUI64{0, 0, 0, 0, 0, 0, byte(n >> 8), byte(n)}
+}
+
+func device(n int, latitude float64, longitude float64, km float64) m.InfoDevice {
+	return m.InfoDevice{DevEUI: eui(n), Location: loc.Location{Latitude: latitude, Longitude: longitude}, Range: km * 1000}
+}
+
+func gateway(n int, latitude float64, longitude float64) m.InfoGateway {
+	return m.InfoGateway{MACAddress: eui(0x8000 + n), Location: loc.Location{Latitude: latitude, Longitude: longitude}}
+}
+
+// checkRoutes compares the routes of every device with a brute-force range check
+func checkRoutes(t *testing.T, f *Forwarder, step string) {
+
+	t.Helper()
+
+	for devEUI, d := range f.devices {
+
+		for mac, g := range f.gateways {
+
+			_, routed := d.gateways[mac]
+			if want := inRange(d.info, g.info); routed != want {
+				t.Errorf("%v: device %v, gateway %v routed %v, in range %v", step, devEUI, mac, routed, want)
+			}
+
+			if _, back := g.devices[devEUI]; back != routed {
+				t.Errorf("%v: gateway %v routes device %v %v, the device %v", step, mac, devEUI, back, routed)
+			}
+		}
+	}
+}
+
+func TestGridRoutes(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+
+	point := func() (float64, float64) {
+		return 45 + random.Float64(), 7 + random.Float64() // about 110 x 80 km
+	}
+
+	f := Setup()
+
+	for i := 0; i < 100; i++ {
+		lat, lng := point()
+		f.AddGateway(gateway(i, lat, lng))
+	}
+
+	for i := 0; i < 300; i++ {
+		lat, lng := point()
+		f.AddDevice(device(i, lat, lng, 1+random.Float64()*20))
+	}
+
+	checkRoutes(t, f, "devices added")
+
+	for i := 100; i < 150; i++ { // the devices are evaluated from the gateways
+		lat, lng := point()
+		f.AddGateway(gateway(i, lat, lng))
+	}
+
+	checkRoutes(t, f, "gateways added")
+
+	for i := 0; i < 300; i += 3 {
+		lat, lng := point()
+		f.UpdateDevice(device(i, lat, lng, 1+random.Float64()*20))
+	}
+
+	for i := 0; i < 150; i += 5 {
+		lat, lng := point()
+		f.UpdateGateway(gateway(i, lat, lng))
+	}
+
+	checkRoutes(t, f, "components moved")
+
+	for i := 0; i < 150; i += 2 {
+		f.DeleteGateway(gateway(i, 0, 0))
+	}
+
+	checkRoutes(t, f, "gateways deleted")
+}
+
+func TestGridBoundaries(t *testing.T) {
+
+	f := Setup()
+
+	d := device(1, 45.0999, 7.0999, 1) // in the corner of its cell
+
+	f.AddGateway(gateway(1, 45.1001, 7.0999)) // north cell
+	f.AddGateway(gateway(2, 45.0999, 7.1001)) // east cell
+	f.AddGateway(gateway(3, 45.1001, 7.1001)) // north-east cell
+	f.AddGateway(gateway(4, 45.0999, 7.12))   // east cell, out of range
+	f.AddDevice(d)
+
+	if n := f.Reach(d.DevEUI); n != 3 {
+		t.Errorf("gateways across the cell boundaries = %v, want 3", n)
+	}
+	checkRoutes(t, f, "boundaries")
+
+	d.Location = loc.Location{Latitude: 45.0999, Longitude: 7.1199} // next cell, only gateway 4 in range
+	f.UpdateDevice(d)
+
+	if n := f.Reach(d.DevEUI); n != 1 {
+		t.Errorf("gateways after the move = %v, want 1", n)
+	}
+	checkRoutes(t, f, "device moved")
+
+	f.UpdateGateway(gateway(4, 45.5, 7.5)) // far away
+	if n := f.Reach(d.DevEUI); n != 0 {
+		t.Errorf("gateways after the gateway moved = %v, want 0", n)
+	}
+	checkRoutes(t, f, "gateway moved")
+
+	f.UpdateGateway(gateway(4, 45.1001, 7.1195)) // back in range, from another cell
+	if n := f.Reach(d.DevEUI); n != 1 {
+		t.Errorf("gateways after the gateway moved back = %v, want 1", n)
+	}
+	checkRoutes(t, f, "gateway moved back")
+
+	if _, ok := f.gatewayGrid.positions[gateway(4, 0, 0).MACAddress]; !ok || len(f.gatewayGrid.cells[cellOf(loc.Location{Latitude: 45.5, Longitude: 7.5})]) != 0 {
+		t.Errorf("the old cell of the moved gateway isn't empty")
+	}
+}
+
+func TestGridAntimeridianAndPole(t *testing.T) {
+
+	f := Setup()
+
+	f.AddGateway(gateway(1, 10, -179.99))
+	f.AddGateway(gateway(2, 89.99, 0))
+	f.AddGateway(gateway(3, 10, 179.5)) // out of range
+
+	f.AddDevice(device(1, 10, 179.99, 5)) // 2.2 km across the antimeridian
+	f.AddDevice(device(2, 89.99, 150, 5)) // 1.1 km across the pole
+
+	if n := f.Reach(eui(1)); n != 1 {
+		t.Errorf("gateways across the antimeridian = %v, want 1", n)
+	}
+
+	if n := f.Reach(eui(2)); n != 1 {
+		t.Errorf("gateways across the pole = %v, want 1", n)
+	}
+
+	checkRoutes(t, f, "antimeridian and pole")
+}
@@ -1,13 +1,16 @@
 package gateway
 
 import (
+	"context"
 	"sync"
 
 	f "github.com/arslab/lwnsimulator/simulator/components/forwarder"
 	c "github.com/arslab/lwnsimulator/simulator/console"
+	"github.com/arslab/lwnsimulator/simulator/events"
 	res "github.com/arslab/lwnsimulator/simulator/resources"
 	"github.com/arslab/lwnsimulator/simulator/resources/communication/buffer"
 	"github.com/arslab/lwnsimulator/simulator/resources/communication/udp"
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
 	"github.com/arslab/lwnsimulator/simulator/util"
 )
 
@@ -36,7 +39,12 @@ func (g *Gateway) TurnON() {
 
 	var err error
 
+	g.ctx, g.cancel = context.WithCancel(g.Resources.Context)
+	g.done = make(chan struct{})
+
+	g.mutex.Lock()
 	g.State = util.Running
+	g.mutex.Unlock()
 
 	//udp
 	if g.Info.TypeGateway { //real
@@ -60,17 +68,55 @@ func (g *Gateway) TurnON() {
 	}
 
 	g.Print("Turn ON", nil, util.PrintBoth)
+	g.publish(events.TypeState, &events.State{On: true})
 }
 
 func (g *Gateway) TurnOFF() {
 
+	if g.State == util.Stopped {
+		return
+	}
+
+	g.mutex.Lock()
 	g.State = util.Stopped
+	g.mutex.Unlock()
+
+	g.cancel() //signal to keep alive
 
 	g.BufferUplink.Signal()   //signal to sender
 	g.Info.Connection.Close() //signal to receiver
 
 }
 
+// turnedOff signals the gateway has been turned off once the receiver returns
+func (g *Gateway) turnedOff() {
+
+	g.publish(events.TypeState, &events.State{On: false})
+
+	close(g.done)
+}
+
+// Done is closed when the gateway has been turned off, it's closed if never turned on
+func (g *Gateway) Done() <-chan struct{} {
+
+	if g.done == nil {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+
+	return g.done
+}
+
+// ChangeLocation moves the gateway, also while running
+func (g *Gateway) ChangeLocation(l loc.Location) {
+
+	g.mutex.Lock()
+	g.Info.Location = l
+	g.mutex.Unlock()
+
+}
+
 func (g *Gateway) IsOn() bool {
 
 	if g.State == util.Running {
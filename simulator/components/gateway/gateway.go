@@ -1,16 +1,20 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	f "github.com/arslab/lwnsimulator/simulator/components/forwarder"
 	"github.com/arslab/lwnsimulator/simulator/components/gateway/models"
 	c "github.com/arslab/lwnsimulator/simulator/console"
+	"github.com/arslab/lwnsimulator/simulator/events"
 	res "github.com/arslab/lwnsimulator/simulator/resources"
 	"github.com/arslab/lwnsimulator/simulator/resources/communication/buffer"
 	"github.com/arslab/lwnsimulator/simulator/util"
 	"github.com/arslab/lwnsimulator/socket"
+	"github.com/brocaar/lorawan"
 )
 
 type Gateway struct {
@@ -26,10 +30,19 @@ type Gateway struct {
 
 	BufferUplink buffer.BufferUplink `json:"-"`
 	Console      c.Console           `json:"-"`
+
+	ctx    context.Context // cancelled by TurnOFF and by the stop of the run
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the receiver has exited
+
+	mutex sync.Mutex // state and location read by the goroutines of the gateway
 }
 
 func (g *Gateway) CanExecute() bool {
 
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
 	if g.State == util.Stopped {
 		return false
 	}
@@ -40,7 +53,7 @@ func (g *Gateway) CanExecute() bool {
 
 func (g *Gateway) Print(content string, err error, printType int) {
 
-	now := time.Now()
+	now := g.now()
 	message := ""
 	messageLog := ""
 	event := socket.EventGw
@@ -52,6 +65,8 @@ func (g *Gateway) Print(content string, err error, printType int) {
 		message = fmt.Sprintf("[ %s ] GW[%s] [ERROR]: %s", now.Format(time.Stamp), g.Info.Name, err)
 		messageLog = fmt.Sprintf("GW[%s] [ERROR]: %s", g.Info.Name, err)
 		event = socket.EventError
+
+		g.publish(events.TypeError, &events.Error{Message: err.Error()})
 	}
 
 	data := socket.ConsoleLog{
@@ -69,3 +84,55 @@ func (g *Gateway) Print(content string, err error, printType int) {
 		g.Console.PrintLog(messageLog)
 	}
 }
+
+// now returns the time of the simulation
+func (g *Gateway) now() time.Time {
+
+	if g.Resources == nil || g.Resources.Clock == nil { // not set up yet
+		return time.Now()
+	}
+
+	return g.Resources.Clock.Now()
+}
+
+// publish sends an event of the gateway on the bus
+func (g *Gateway) publish(eventType string, data interface{}) {
+
+	if g.Resources == nil { // not set up yet
+		return
+	}
+
+	g.Resources.Events.Publish(events.Event{
+		Type:   eventType,
+		Time:   g.now(),
+		Source: events.SourceGateway,
+		Id:     g.Id,
+		Name:   g.Info.Name,
+		Data:   data,
+	})
+}
+
+// publishPushData publishes an uplink forwarded to the network server, size of the UDP packet
+func (g *Gateway) publishPushData(size int, forwarded bool) {
+
+	g.publish(events.TypePushData, &events.PushData{
+		MACAddress: g.Info.MACAddress,
+		Size:       size,
+		Forwarded:  forwarded,
+	})
+}
+
+// publishPullResp publishes a downlink received from the network server
+func (g *Gateway) publishPullResp(phy *lorawan.PHYPayload, frequency uint32) {
+
+	resp := events.PullResp{
+		MACAddress: g.Info.MACAddress,
+		Frequency:  frequency,
+	}
+
+	if frame, err := phy.MarshalBinary(); err == nil {
+		resp.Size = len(frame)
+	}
+
+	g.publish(events.TypePullResp, &resp)
+}
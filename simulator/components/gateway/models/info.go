@@ -21,6 +21,7 @@ type InfoGateway struct {
 	AddrIP        string        `json:"ip"`
 	Port          string        `json:"port"`
 	BridgeAddress *string       `json:"-"` //is a pointer
+	Tags          []string      `json:"tags,omitempty"`
 }
 
 func (g *InfoGateway) MarshalJSON() ([]byte, error) {
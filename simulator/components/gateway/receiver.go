@@ -5,33 +5,17 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/arslab/lwnsimulator/simulator/events"
 	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
 	"github.com/arslab/lwnsimulator/simulator/resources/communication/udp"
 	"github.com/arslab/lwnsimulator/simulator/util"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-)
-
-var (
-	pushAckCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "gateway_push_ack_total",
-		Help: "The total number of gateway PUSH ACK",
-	})
-	pullAckCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "gateway_pull_ack_total",
-		Help: "The total number of gateway PULL ACK",
-	})
-	pullRespCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "gateway_pull_resp_total",
-		Help: "The total number of gateway PULL RESP",
-	})
 )
 
 func (g *Gateway) Receiver() {
 
 	ReceiveBuffer := make([]byte, 1024)
 
-	defer g.Resources.ExitGroup.Done()
+	defer g.turnedOff()
 
 	for {
 		var n int
@@ -91,21 +75,20 @@ func (g *Gateway) Receiver() {
 			continue
 		}
 
-		time.Sleep(time.Second) //sync le print
-
-		msg := fmt.Sprintf("%v received", pkt.PacketToString(receivedPack[3]))
-		g.Print(msg, nil, util.PrintBoth)
+		if !g.Resources.Clock.Sleep(time.Second, g.ctx.Done()) { //sync le print
+			g.Print("Turn OFF", nil, util.PrintBoth)
+			return
+		}
 
 		typepkt := pkt.GetTypePacket(receivedPack)
 		switch *typepkt {
 
 		case pkt.TypePushAck:
 			g.Stat.ACKR++
-			pushAckCounter.Inc()
+			g.publish(events.TypePushAck, &events.Packet{MACAddress: g.Info.MACAddress})
 
 		case pkt.TypePullAck:
-			pullAckCounter.Inc()
-			break
+			g.publish(events.TypePullAck, &events.Packet{MACAddress: g.Info.MACAddress})
 
 		case pkt.TypePullResp:
 
@@ -115,11 +98,11 @@ func (g *Gateway) Receiver() {
 				continue
 			}
 
-			g.Forwarder.Downlink(phy, *freq, g.Info.MACAddress)
+			g.publishPullResp(phy, *freq)
 
-			g.Stat.RXFW++
+			g.Forwarder.Downlink(phy, *freq, g.Info.MACAddress, pkt.GetTmstPullResp(receivedPack))
 
-			pullRespCounter.Inc()
+			g.Stat.RXFW++
 
 			//TX ACK
 			packet, err := pkt.CreatePacket(pkt.TypeTxAck, g.Info.MACAddress, pkt.Stat{}, nil, pkt.GetTokenFromPullResp(receivedPack))
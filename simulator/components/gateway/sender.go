@@ -5,25 +5,13 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/arslab/lwnsimulator/simulator/events"
 	pkt "github.com/arslab/lwnsimulator/simulator/resources/communication/packets"
 	"github.com/arslab/lwnsimulator/simulator/resources/communication/udp"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/arslab/lwnsimulator/simulator/util"
 )
 
-var (
-	pushDataCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "gateway_data_sent_total",
-		Help: "The total number of gateway PUSH DATA",
-	})
-	pullDataCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "gateway_pull_data_total",
-		Help: "The total number of gateway PULL DATA",
-	})
-)
-
 func (g *Gateway) SenderVirtual() {
 
 	defer g.Print("Sender Turn OFF", nil, util.PrintOnlyConsole)
@@ -47,14 +35,13 @@ func (g *Gateway) SenderVirtual() {
 		}
 
 		_, err = udp.SendDataUDP(g.Info.Connection, packet)
+		g.publishPushData(len(packet), err == nil)
+
 		if err != nil {
 
 			msg := fmt.Sprintf("Unable to send data to %v, it may be off", *g.Info.BridgeAddress)
 			g.Print("", errors.New(msg), util.PrintBoth)
 
-		} else {
-			g.Print("PUSH DATA send", nil, util.PrintBoth)
-			pushDataCounter.Inc()
 		}
 
 	}
@@ -82,16 +69,13 @@ func (g *Gateway) SenderReal() {
 		}
 
 		_, err = udp.SendDataUDP(g.Info.Connection, packet)
+		g.publishPushData(len(packet), err == nil)
+
 		if err != nil {
 
 			msg := fmt.Sprintf("Unable to send data to %v, it may be off", *g.Info.BridgeAddress)
 			g.Print("", errors.New(msg), util.PrintBoth)
 
-		} else {
-			msg := fmt.Sprintf("Forward PUSH DATA to %v:%v", g.Info.AddrIP, g.Info.Port)
-			g.Print(msg, nil, util.PrintBoth)
-
-			pushDataCounter.Inc()
 		}
 
 	}
@@ -112,11 +96,15 @@ func (g *Gateway) sendPullData() error {
 
 func (g *Gateway) createPacket(info pkt.RXPK) ([]byte, error) {
 
+	g.mutex.Lock()
+	location := g.Info.Location
+	g.mutex.Unlock()
+
 	stat := pkt.Stat{
 		Time: pkt.GetTime(),
-		Lati: g.Info.Location.Latitude,
-		Long: g.Info.Location.Longitude,
-		Alti: g.Info.Location.Altitude,
+		Lati: location.Latitude,
+		Long: location.Longitude,
+		Alti: location.Altitude,
 		RXNb: g.Stat.RXNb,
 		RXOK: g.Stat.RXOK,
 		RXFW: g.Stat.RXFW,
@@ -135,6 +123,7 @@ func (g *Gateway) createPacket(info pkt.RXPK) ([]byte, error) {
 func (g *Gateway) KeepAlive() {
 
 	tickerKeepAlive := time.NewTicker(g.Info.KeepAlive)
+	defer tickerKeepAlive.Stop()
 
 	for {
 		if !g.CanExecute() {
@@ -147,13 +136,16 @@ func (g *Gateway) KeepAlive() {
 			if err != nil {
 				g.Print("", err, util.PrintBoth)
 			} else {
-				g.Print("PULL DATA send", nil, util.PrintBoth)
-				pullDataCounter.Inc()
+				g.publish(events.TypePullData, &events.Packet{MACAddress: g.Info.MACAddress})
 			}
 
 		}
 
-		<-tickerKeepAlive.C
+		select {
+		case <-tickerKeepAlive.C:
+		case <-g.ctx.Done():
+			return
+		}
 	}
 
 }
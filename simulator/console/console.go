@@ -2,24 +2,70 @@ package console
 
 import (
 	"log"
+	"sync"
 
 	socketio "github.com/googollee/go-socket.io"
 )
 
+// Console prints the logs and sends them to the web sockets of the dashboards. The copies
+// of a console share the web sockets, a new connection reaches the components already running
 type Console struct {
-	WebSocket socketio.Conn
+	sockets *webSockets
+}
+
+type webSockets struct {
+	conns map[string]socketio.Conn
+	mutex sync.RWMutex
+}
+
+// New returns a console without web sockets
+func New() Console {
+	return Console{
+		sockets: &webSockets{conns: make(map[string]socketio.Conn)},
+	}
 }
 
 func (c *Console) PrintLog(message string) {
 	log.Println(message)
 }
 
+// PrintSocket emits the event to every dashboard connected
 func (c *Console) PrintSocket(eventName string, data ...interface{}) {
-	if c.WebSocket != nil {
-		c.WebSocket.Emit(eventName, data...)
+
+	if c.sockets == nil {
+		return
+	}
+
+	c.sockets.mutex.RLock()
+
+	conns := make([]socketio.Conn, 0, len(c.sockets.conns))
+	for _, conn := range c.sockets.conns {
+		conns = append(conns, conn)
+	}
+
+	c.sockets.mutex.RUnlock()
+
+	for _, conn := range conns {
+		conn.Emit(eventName, data...)
 	}
 }
 
-func (c *Console) SetupWebSocket(WebSocket *socketio.Conn) {
-	c.WebSocket = *WebSocket
+// AddWebSocket adds a dashboard, the others keep receiving the events
+func (c *Console) AddWebSocket(WebSocket *socketio.Conn) {
+
+	conn := *WebSocket
+
+	c.sockets.mutex.Lock()
+	c.sockets.conns[conn.ID()] = conn
+	c.sockets.mutex.Unlock()
+}
+
+// RemoveWebSocket removes a dashboard disconnected
+func (c *Console) RemoveWebSocket(WebSocket *socketio.Conn) {
+
+	conn := *WebSocket
+
+	c.sockets.mutex.Lock()
+	delete(c.sockets.conns, conn.ID())
+	c.sockets.mutex.Unlock()
 }
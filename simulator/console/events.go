@@ -0,0 +1,125 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/arslab/lwnsimulator/socket"
+)
+
+// Consume prints the frames of the devices and the gateways published on the bus, the
+// components print the other messages themselves
+func (c *Console) Consume(e events.Event) {
+
+	content := describe(e)
+	if content == "" {
+		return
+	}
+
+	prefix := "DEV"
+	event := socket.EventDev
+
+	if e.Source == events.SourceGateway {
+		prefix = "GW"
+		event = socket.EventGw
+	}
+
+	data := socket.ConsoleLog{
+		Name: e.Name,
+		Msg:  fmt.Sprintf("[ %s ] %s[%s]: %s", e.Time.Format(time.Stamp), prefix, e.Name, content),
+	}
+
+	c.PrintSocket(event, data)
+	c.PrintLog(fmt.Sprintf("%s[%s]: %s", prefix, e.Name, content))
+}
+
+// describe returns the human-readable message of the frame, empty for the other events
+func describe(e events.Event) string {
+
+	switch data := e.Data.(type) {
+
+	case *events.Uplink:
+		return "Uplink sent: " + strings.Join(append([]string{data.MType},
+			frame(data.FCnt, data.FPort, data.ACK, data.Size, data.DataRate, data.Frequency, data.Airtime)...), ", ")
+
+	case *events.Downlink:
+		return "Downlink received in " + strings.ToUpper(data.Window) + ": " + strings.Join(append([]string{data.MType},
+			frame(data.FCnt, data.FPort, data.ACK, data.Size, 0, 0, 0)...), ", ")
+
+	case *events.JoinRequest:
+		return fmt.Sprintf("JOIN REQUEST sent: DevNonce %v, DR%v, %.3f MHz, %v bytes, ToA %v",
+			data.DevNonce, data.DataRate, mhz(data.Frequency), data.Size, data.Airtime)
+
+	case *events.JoinAccept:
+		return fmt.Sprintf("Joined: DevAddr %v, after %v", data.DevAddr, data.Latency.Round(time.Millisecond))
+
+	case *events.MACCommand:
+		if data.Direction == events.DirectionUplink {
+			return "MAC command out: " + data.CID + " queued for the next uplink"
+		}
+		return "MAC command in: " + data.CID
+
+	case *events.Retransmission:
+		return fmt.Sprintf("Retransmission %v of FCnt %v", data.Attempt, data.FCnt)
+
+	case *events.AckTimeout:
+		return "ACK Timeout"
+
+	case *events.DownlinkMissed:
+		return fmt.Sprintf("Downlink missed in %v: preamble outside the window by %v", strings.ToUpper(data.Window), -data.Margin)
+
+	case *events.PushData:
+		if !data.Forwarded { // the gateway prints the error
+			return ""
+		}
+		return fmt.Sprintf("PUSH DATA sent: %v bytes", data.Size)
+
+	case *events.PullResp:
+		return fmt.Sprintf("PULL RESP received: %.3f MHz, %v bytes", mhz(data.Frequency), data.Size)
+
+	case *events.Packet:
+		switch e.Type {
+		case events.TypePullData:
+			return "PULL DATA sent"
+		case events.TypePushAck:
+			return "PUSH ACK received"
+		case events.TypePullAck:
+			return "PULL ACK received"
+		}
+
+	}
+
+	return ""
+}
+
+// frame lists the fields of a data frame, the radio ones only if set
+func frame(fcnt uint32, fport *uint8, ack bool, size int, dataRate uint8, frequency uint32, airtime time.Duration) []string {
+
+	fields := []string{fmt.Sprintf("FCnt %v", fcnt)}
+
+	if fport != nil {
+		fields = append(fields, fmt.Sprintf("FPort %v", *fport))
+	}
+
+	if frequency != 0 {
+		fields = append(fields, fmt.Sprintf("DR%v", dataRate), fmt.Sprintf("%.3f MHz", mhz(frequency)))
+	}
+
+	fields = append(fields, fmt.Sprintf("%v bytes", size))
+
+	if airtime != 0 {
+		fields = append(fields, fmt.Sprintf("ToA %v", airtime))
+	}
+
+	if ack {
+		fields = append(fields, "ACK")
+	}
+
+	return fields
+}
+
+func mhz(frequency uint32) float64 {
+	return float64(frequency) / 1000000
+}
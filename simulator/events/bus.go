@@ -0,0 +1,102 @@
+package events
+
+import (
+	"sort"
+	"sync"
+)
+
+// Consumer handles the events of the bus in the goroutine of the publisher, it mustn't block
+type Consumer interface {
+	Consume(e Event)
+}
+
+// ConsumerFunc is a function used as consumer
+type ConsumerFunc func(e Event)
+
+func (f ConsumerFunc) Consume(e Event) {
+	f(e)
+}
+
+// Bus delivers the events to the consumers of the simulator (console, metrics, report...)
+// and to the subscribers of the hub, the external sinks
+type Bus struct {
+	mutex     sync.RWMutex
+	consumers map[string]Consumer
+	names     []string // delivery order
+	hub       *Hub
+}
+
+// NewBus returns a bus without consumers
+func NewBus() *Bus {
+	return &Bus{
+		consumers: make(map[string]Consumer),
+		hub:       NewHub(),
+	}
+}
+
+// Attach adds the consumer or replaces the one with the same name
+func (b *Bus) Attach(name string, consumer Consumer) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.consumers[name]; !ok {
+		b.names = append(b.names, name)
+		sort.Strings(b.names)
+	}
+
+	b.consumers[name] = consumer
+}
+
+// Detach removes the consumer
+func (b *Bus) Detach(name string) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.consumers[name]; !ok {
+		return
+	}
+
+	delete(b.consumers, name)
+
+	for i, n := range b.names {
+		if n == name {
+			b.names = append(b.names[:i], b.names[i+1:]...)
+			break
+		}
+	}
+}
+
+// Publish delivers the event to the consumers, then to the subscribers. A nil bus discards it
+func (b *Bus) Publish(e Event) {
+
+	if b == nil {
+		return
+	}
+
+	b.mutex.RLock()
+
+	consumers := make([]Consumer, len(b.names))
+	for i, name := range b.names {
+		consumers[i] = b.consumers[name]
+	}
+
+	b.mutex.RUnlock()
+
+	for _, c := range consumers {
+		c.Consume(e)
+	}
+
+	b.hub.Publish(e)
+}
+
+// Subscribe adds a subscriber of the hub
+func (b *Bus) Subscribe(filter Filter) *Subscription {
+	return b.hub.Subscribe(filter)
+}
+
+// Unsubscribe removes a subscriber of the hub
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.hub.Unsubscribe(sub)
+}
@@ -0,0 +1,330 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/brocaar/lorawan"
+)
+
+const (
+	TypeUplink         = "uplink"
+	TypeDownlink       = "downlink"
+	TypeJoinRequest    = "joinRequest"
+	TypeJoinAccept     = "joinAccept"
+	TypeMACCommand     = "macCommand"
+	TypeRetransmission = "retransmission"
+	TypeAckTimeout     = "ackTimeout"
+	TypeDownlinkMissed = "downlinkMissed"
+	TypePushData       = "pushData"
+	TypePushAck        = "pushAck"
+	TypePullData       = "pullData"
+	TypePullAck        = "pullAck"
+	TypePullResp       = "pullResp"
+	TypeState          = "state"
+	TypeError          = "error"
+)
+
+const (
+	SourceDevice    = "device"
+	SourceGateway   = "gateway"
+	SourceSimulator = "simulator"
+)
+
+const (
+	DirectionUplink   = "uplink"   // queued by the device for the next uplink
+	DirectionDownlink = "downlink" // received from the network server
+)
+
+const (
+	WindowRX1    = "rx1"
+	WindowRX2    = "rx2"
+	WindowPing   = "ping"
+	WindowClassC = "classC"
+)
+
+// Event is a structured event of the simulation, Data is a pointer to the struct of the type
+type Event struct {
+	Type   string      `json:"type"`
+	Time   time.Time   `json:"time"`   // time of the simulation
+	Source string      `json:"source"` // device, gateway or simulator
+	Id     int         `json:"id"`     // device or gateway, 0 for the simulator
+	Name   string      `json:"name"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// Uplink is a data frame sent by a device
+type Uplink struct {
+	DevEUI    lorawan.EUI64   `json:"devEUI"`
+	DevAddr   lorawan.DevAddr `json:"devAddr"`
+	MType     string          `json:"mtype"`
+	FCnt      uint32          `json:"fcnt"`
+	FPort     *uint8          `json:"fport,omitempty"` // nil without payload
+	ACK       bool            `json:"ack"`             // ACK of a confirmed downlink
+	DataRate  uint8           `json:"dataRate"`
+	Frequency uint32          `json:"frequency"` // Hz
+	Size      int             `json:"size"`      // bytes of the PHY payload
+	Airtime   time.Duration   `json:"airtime"`
+	Delivered bool            `json:"delivered"` // in range of at least a gateway
+}
+
+// Downlink is a data frame received by a device
+type Downlink struct {
+	DevEUI  lorawan.EUI64 `json:"devEUI"`
+	MType   string        `json:"mtype"`
+	FCnt    uint32        `json:"fcnt"`
+	FPort   *uint8        `json:"fport,omitempty"`
+	ACK     bool          `json:"ack"` // ACK of a confirmed uplink
+	Size    int           `json:"size"`
+	Window  string        `json:"window"`  // rx1, rx2, ping or classC
+	Latency time.Duration `json:"latency"` // since the uplink, 0 in class C
+}
+
+// JoinRequest is a JOIN REQUEST sent by a device
+type JoinRequest struct {
+	DevEUI    lorawan.EUI64 `json:"devEUI"`
+	JoinEUI   lorawan.EUI64 `json:"joinEUI"`
+	DevNonce  uint16        `json:"devNonce"`
+	DataRate  uint8         `json:"dataRate"`
+	Frequency uint32        `json:"frequency"`
+	Size      int           `json:"size"`
+	Airtime   time.Duration `json:"airtime"`
+}
+
+// JoinAccept is a JOIN ACCEPT accepted by a device
+type JoinAccept struct {
+	DevEUI  lorawan.EUI64   `json:"devEUI"`
+	DevAddr lorawan.DevAddr `json:"devAddr"`
+	Latency time.Duration   `json:"latency"` // since the first JOIN REQUEST
+}
+
+// MACCommand is a MAC command received or queued by a device
+type MACCommand struct {
+	DevEUI    lorawan.EUI64 `json:"devEUI"`
+	CID       string        `json:"cid"`
+	Direction string        `json:"direction"` // uplink or downlink
+}
+
+// Retransmission is a confirmed uplink sent again without ACK
+type Retransmission struct {
+	DevEUI  lorawan.EUI64 `json:"devEUI"`
+	FCnt    uint32        `json:"fcnt"`
+	Attempt int           `json:"attempt"` // 1 at the first retransmission
+}
+
+// AckTimeout is the end of the receive windows without downlink
+type AckTimeout struct {
+	DevEUI lorawan.EUI64 `json:"devEUI"`
+	FCnt   uint32        `json:"fcnt"`
+}
+
+// DownlinkMissed is a downlink lost by the timing model: its preamble wasn't in the receive window
+type DownlinkMissed struct {
+	DevEUI lorawan.EUI64 `json:"devEUI"`
+	Window string        `json:"window"` // rx1, rx2 or ping
+	Margin time.Duration `json:"margin"` // negative, the preamble outside the window
+}
+
+// PushData is an uplink forwarded by a gateway to the network server
+type PushData struct {
+	MACAddress lorawan.EUI64 `json:"macAddress"`
+	Size       int           `json:"size"`      // bytes of the UDP packet
+	Forwarded  bool          `json:"forwarded"` // false if the network server is unreachable
+}
+
+// PullResp is a downlink received by a gateway from the network server
+type PullResp struct {
+	MACAddress lorawan.EUI64 `json:"macAddress"`
+	Frequency  uint32        `json:"frequency"`
+	Size       int           `json:"size"` // bytes of the PHY payload
+}
+
+// Packet is a PULL DATA, PUSH ACK or PULL ACK of a gateway
+type Packet struct {
+	MACAddress lorawan.EUI64 `json:"macAddress"`
+}
+
+// State is a device, a gateway or the simulation turned on or off
+type State struct {
+	On bool `json:"on"`
+}
+
+// Error is an error of a component
+type Error struct {
+	Message string `json:"message"`
+}
+
+// MarshalJSON of the uplink, airtime in seconds
+func (u *Uplink) MarshalJSON() ([]byte, error) {
+
+	type Alias Uplink
+
+	return json.Marshal(&struct {
+		Airtime float64 `json:"airtime"`
+		*Alias
+	}{
+		Airtime: u.Airtime.Seconds(),
+		Alias:   (*Alias)(u),
+	})
+}
+
+// UnmarshalJSON of the uplink
+func (u *Uplink) UnmarshalJSON(data []byte) error {
+
+	type Alias Uplink
+
+	aux := &struct {
+		Airtime float64 `json:"airtime"`
+		*Alias
+	}{
+		Alias: (*Alias)(u),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	u.Airtime = time.Duration(aux.Airtime * float64(time.Second))
+
+	return nil
+}
+
+// MarshalJSON of the downlink, latency in seconds
+func (d *Downlink) MarshalJSON() ([]byte, error) {
+
+	type Alias Downlink
+
+	return json.Marshal(&struct {
+		Latency float64 `json:"latency"`
+		*Alias
+	}{
+		Latency: d.Latency.Seconds(),
+		Alias:   (*Alias)(d),
+	})
+}
+
+// UnmarshalJSON of the downlink
+func (d *Downlink) UnmarshalJSON(data []byte) error {
+
+	type Alias Downlink
+
+	aux := &struct {
+		Latency float64 `json:"latency"`
+		*Alias
+	}{
+		Alias: (*Alias)(d),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	d.Latency = time.Duration(aux.Latency * float64(time.Second))
+
+	return nil
+}
+
+// MarshalJSON of the missed downlink, margin in seconds
+func (d *DownlinkMissed) MarshalJSON() ([]byte, error) {
+
+	type Alias DownlinkMissed
+
+	return json.Marshal(&struct {
+		Margin float64 `json:"margin"`
+		*Alias
+	}{
+		Margin: d.Margin.Seconds(),
+		Alias:  (*Alias)(d),
+	})
+}
+
+// UnmarshalJSON of the missed downlink
+func (d *DownlinkMissed) UnmarshalJSON(data []byte) error {
+
+	type Alias DownlinkMissed
+
+	aux := &struct {
+		Margin float64 `json:"margin"`
+		*Alias
+	}{
+		Alias: (*Alias)(d),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	d.Margin = time.Duration(aux.Margin * float64(time.Second))
+
+	return nil
+}
+
+// MarshalJSON of the join request, airtime in seconds
+func (j *JoinRequest) MarshalJSON() ([]byte, error) {
+
+	type Alias JoinRequest
+
+	return json.Marshal(&struct {
+		Airtime float64 `json:"airtime"`
+		*Alias
+	}{
+		Airtime: j.Airtime.Seconds(),
+		Alias:   (*Alias)(j),
+	})
+}
+
+// UnmarshalJSON of the join request
+func (j *JoinRequest) UnmarshalJSON(data []byte) error {
+
+	type Alias JoinRequest
+
+	aux := &struct {
+		Airtime float64 `json:"airtime"`
+		*Alias
+	}{
+		Alias: (*Alias)(j),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	j.Airtime = time.Duration(aux.Airtime * float64(time.Second))
+
+	return nil
+}
+
+// MarshalJSON of the join accept, latency in seconds
+func (j *JoinAccept) MarshalJSON() ([]byte, error) {
+
+	type Alias JoinAccept
+
+	return json.Marshal(&struct {
+		Latency float64 `json:"latency"`
+		*Alias
+	}{
+		Latency: j.Latency.Seconds(),
+		Alias:   (*Alias)(j),
+	})
+}
+
+// UnmarshalJSON of the join accept
+func (j *JoinAccept) UnmarshalJSON(data []byte) error {
+
+	type Alias JoinAccept
+
+	aux := &struct {
+		Latency float64 `json:"latency"`
+		*Alias
+	}{
+		Alias: (*Alias)(j),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	j.Latency = time.Duration(aux.Latency * float64(time.Second))
+
+	return nil
+}
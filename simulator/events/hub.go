@@ -0,0 +1,147 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SubscriberBuffer is the number of events queued for a subscriber, a slow subscriber
+// loses the events beyond it instead of slowing down the simulation
+const SubscriberBuffer = 1024
+
+// Filter selects the events of a subscriber, an empty list matches everything
+type Filter struct {
+	Devices  []int    `json:"devices,omitempty"`
+	Gateways []int    `json:"gateways,omitempty"`
+	Types    []string `json:"types,omitempty"`
+}
+
+// Hub broadcasts the events to many subscribers
+type Hub struct {
+	mutex       sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events matching its filter on C, closed by Unsubscribe
+type Subscription struct {
+	C <-chan Event
+
+	events  chan Event
+	filter  Filter
+	dropped uint64
+}
+
+// NewHub returns a hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscribe adds a subscriber
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+
+	events := make(chan Event, SubscriberBuffer)
+
+	sub := &Subscription{
+		C:      events,
+		events: events,
+		filter: filter,
+	}
+
+	h.mutex.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mutex.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes the subscriber and closes its channel
+func (h *Hub) Unsubscribe(sub *Subscription) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+// Publish sends the event to the subscribers without waiting for them
+func (h *Hub) Publish(e Event) {
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for sub := range h.subscribers {
+
+		if !sub.filter.Match(e) {
+			continue
+		}
+
+		select {
+		case sub.events <- e:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers
+func (h *Hub) Subscribers() int {
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return len(h.subscribers)
+}
+
+// Dropped returns the events lost because the subscriber was too slow
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Match is true if the event passes the filter. Filtering by device or gateway
+// excludes the events of the simulator
+func (f *Filter) Match(e Event) bool {
+
+	if len(f.Types) > 0 && !containsString(f.Types, e.Type) {
+		return false
+	}
+
+	if len(f.Devices) == 0 && len(f.Gateways) == 0 {
+		return true
+	}
+
+	switch e.Source {
+	case SourceDevice:
+		return containsInt(f.Devices, e.Id)
+	case SourceGateway:
+		return containsInt(f.Gateways, e.Id)
+	}
+
+	return false
+}
+
+func containsString(list []string, value string) bool {
+
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInt(list []int, value int) bool {
+
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
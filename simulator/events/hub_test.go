@@ -0,0 +1,175 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+
+	uplink := Event{Type: TypeUplink, Source: SourceDevice, Id: 1}
+	pushData := Event{Type: TypePushData, Source: SourceGateway, Id: 1}
+	state := Event{Type: TypeState, Source: SourceSimulator}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		event  Event
+		match  bool
+	}{
+		{"empty", Filter{}, uplink, true},
+		{"empty, simulator", Filter{}, state, true},
+		{"type", Filter{Types: []string{TypeUplink, TypeDownlink}}, uplink, true},
+		{"other type", Filter{Types: []string{TypeDownlink}}, uplink, false},
+		{"device", Filter{Devices: []int{2, 1}}, uplink, true},
+		{"other device", Filter{Devices: []int{2}}, uplink, false},
+		{"device, not a gateway with its id", Filter{Devices: []int{1}}, pushData, false},
+		{"gateway", Filter{Gateways: []int{1}}, pushData, true},
+		{"gateway, simulator", Filter{Gateways: []int{1}}, state, false},
+		{"device and type", Filter{Devices: []int{1}, Types: []string{TypeDownlink}}, uplink, false},
+	}
+
+	for _, test := range tests {
+		if got := test.filter.Match(test.event); got != test.match {
+			t.Errorf("%v: Match = %v, want %v", test.name, got, test.match)
+		}
+	}
+}
+
+func TestHubFilter(t *testing.T) {
+
+	h := NewHub()
+
+	filtered := h.Subscribe(Filter{Devices: []int{1}, Types: []string{TypeUplink}})
+	all := h.Subscribe(Filter{})
+
+	published := []Event{
+		{Type: TypeUplink, Source: SourceDevice, Id: 1, Name: "a"},
+		{Type: TypeDownlink, Source: SourceDevice, Id: 1},
+		{Type: TypeUplink, Source: SourceDevice, Id: 2},
+		{Type: TypeUplink, Source: SourceGateway, Id: 1},
+		{Type: TypeState, Source: SourceSimulator},
+		{Type: TypeUplink, Source: SourceDevice, Id: 1, Name: "b"},
+	}
+
+	for _, e := range published {
+		h.Publish(e)
+	}
+
+	h.Unsubscribe(filtered)
+	h.Unsubscribe(all)
+
+	var names []string
+	for e := range filtered.C {
+		names = append(names, e.Name)
+	}
+
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("filtered subscriber received %v, want [a b]", names)
+	}
+
+	n := 0
+	for range all.C {
+		n++
+	}
+
+	if n != len(published) {
+		t.Errorf("subscriber without filter received %v events, want %v", n, len(published))
+	}
+
+	if h.Subscribers() != 0 {
+		t.Errorf("Subscribers() = %v after Unsubscribe", h.Subscribers())
+	}
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+
+	h := NewHub()
+
+	blocked := h.Subscribe(Filter{}) // never read
+	reader := h.Subscribe(Filter{})
+
+	events := SubscriberBuffer + 100
+
+	var received int
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+
+		defer wg.Done()
+
+		for range reader.C {
+			received++
+		}
+	}()
+
+	published := make(chan struct{})
+	go func() {
+
+		for i := 0; i < events; i++ {
+			h.Publish(Event{Type: TypeUplink, Source: SourceDevice, Id: 1})
+		}
+
+		close(published)
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked by a subscriber that doesn't read")
+	}
+
+	if d := blocked.Dropped(); d != uint64(events-SubscriberBuffer) {
+		t.Errorf("Dropped() = %v, want %v", d, events-SubscriberBuffer)
+	}
+
+	h.Unsubscribe(reader)
+	wg.Wait()
+
+	if received+int(reader.Dropped()) != events {
+		t.Errorf("reader received %v and dropped %v of %v events", received, reader.Dropped(), events)
+	}
+
+	h.Unsubscribe(blocked)
+	h.Unsubscribe(blocked) // a second time is ignored
+}
+
+func TestHubConcurrent(t *testing.T) {
+
+	h := NewHub()
+
+	var wg sync.WaitGroup
+
+	for p := 0; p < 4; p++ {
+
+		wg.Add(1)
+		go func() {
+
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				h.Publish(Event{Type: TypeUplink, Source: SourceDevice, Id: i % 3})
+			}
+		}()
+	}
+
+	for s := 0; s < 4; s++ {
+
+		wg.Add(1)
+		go func(s int) {
+
+			defer wg.Done()
+
+			for i := 0; i < 50; i++ {
+				sub := h.Subscribe(Filter{Devices: []int{s % 3}})
+				h.Unsubscribe(sub)
+				for range sub.C {
+				}
+			}
+		}(s)
+	}
+
+	wg.Wait()
+}
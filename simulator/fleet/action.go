@@ -0,0 +1,56 @@
+package fleet
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	ActionReboot       = "reboot"        // power-cycle: RAM state lost, NVM counters kept, OTAA devices join again
+	ActionFactoryReset = "factory-reset" // DevNonce, frame counters and session reset
+	ActionBrownOut     = "brown-out"     // power lost during the next transmission, NVM rolled back to the last save
+)
+
+// Action is a lifecycle action applied to the selected devices, now or later
+type Action struct {
+	Id     int    `json:"id"` // assigned when scheduled
+	Action string `json:"action"`
+	Selection
+
+	At    *time.Time `json:"at,omitempty"`    // RFC3339
+	Delay float64    `json:"delay,omitempty"` // seconds from now
+}
+
+// Validate checks the request
+func (a *Action) Validate() error {
+
+	switch a.Action {
+	case ActionReboot, ActionFactoryReset, ActionBrownOut:
+	default:
+		return errors.New("Action must be reboot, factory-reset or brown-out")
+	}
+
+	if a.Delay < 0 {
+		return errors.New("Delay must be positive")
+	}
+
+	if a.At != nil && a.Delay > 0 {
+		return errors.New("Set either at or delay")
+	}
+
+	if a.Selection.Empty() {
+		return errors.New("Action without devices")
+	}
+
+	return nil
+}
+
+// Time returns when the action is due
+func (a *Action) Time(now time.Time) time.Time {
+
+	if a.At != nil {
+		return *a.At
+	}
+
+	return now.Add(time.Duration(a.Delay * float64(time.Second)))
+}
@@ -0,0 +1,118 @@
+package fleet
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/brocaar/lorawan"
+)
+
+// MaxCount is the maximum number of devices created with one request
+const MaxCount = 100000
+
+// Create instantiates Count devices from a template
+type Create struct {
+	Template string          `json:"template"`
+	Override json.RawMessage `json:"override,omitempty"` // partial device merged over the template
+	Count    int             `json:"count"`
+
+	Name       string `json:"name"`       // pattern, {n} is the index and {devEUI} the DevEUI (eg. meter-{n})
+	FirstIndex int    `json:"firstIndex"` // {n} of the first device
+
+	DevEUI  string `json:"devEUI"`            // first DevEUI of the range (hex)
+	DevAddr string `json:"devAddr,omitempty"` // ABP: first DevAddr of the range (hex), random if empty
+
+	Keys    string `json:"keys"`              // random, derived or fixed
+	RootKey string `json:"rootKey,omitempty"` // derived, fixed (hex)
+
+	Placement Placement `json:"placement"` // template's location if empty
+	Active    bool      `json:"active"`
+}
+
+// Selection identifies existing devices or gateways by id, name prefix, tags or group
+type Selection struct {
+	Ids    []int    `json:"ids,omitempty"`
+	Prefix string   `json:"prefix,omitempty"`
+	Tags   []string `json:"tags,omitempty"`  // components with all the tags
+	Group  string   `json:"group,omitempty"` // members of the group, resolved by the simulator
+}
+
+// Update merges Patch over every selected device
+type Update struct {
+	Selection
+	Patch json.RawMessage `json:"patch"`
+}
+
+// Delete removes every selected device
+type Delete struct {
+	Selection
+}
+
+// Result is the outcome of the bulk operation on one device
+type Result struct {
+	Id     int    `json:"id"`
+	Name   string `json:"name"`
+	Code   int    `json:"code"`
+	Status string `json:"status"`
+}
+
+// Validate checks the request
+func (c *Create) Validate() error {
+
+	if c.Count <= 0 || c.Count > MaxCount {
+		return errors.New("Count must be from 1 to " + strconv.Itoa(MaxCount))
+	}
+
+	if !strings.Contains(c.Name, "{n}") && !strings.Contains(c.Name, "{devEUI}") {
+		return errors.New("Name pattern must contain {n} or {devEUI}")
+	}
+
+	first, err := ParseEUI(c.DevEUI)
+	if err != nil {
+		return err
+	}
+
+	if first+uint64(c.Count-1) < first {
+		return errors.New("DevEUI range overflows")
+	}
+
+	if c.DevAddr != "" {
+		if _, err := ParseDevAddr(c.DevAddr); err != nil {
+			return err
+		}
+	}
+
+	return c.Placement.Validate()
+}
+
+// DeviceName returns the name of the index-th device
+func (c *Create) DeviceName(index int, DevEUI lorawan.EUI64) string {
+
+	return strings.NewReplacer(
+		"{n}", strconv.Itoa(c.FirstIndex+index),
+		"{devEUI}", DevEUI.String(),
+	).Replace(c.Name)
+}
+
+// Empty is true if the selection matches no device
+func (s *Selection) Empty() bool {
+	return len(s.Ids) == 0 && s.Prefix == "" && len(s.Tags) == 0 && s.Group == ""
+}
+
+// Match is true if the component is selected by id, prefix or tags
+func (s *Selection) Match(Id int, Name string, Tags []string) bool {
+
+	for _, id := range s.Ids {
+		if id == Id {
+			return true
+		}
+	}
+
+	if s.Prefix != "" && strings.HasPrefix(Name, s.Prefix) {
+		return true
+	}
+
+	return len(s.Tags) > 0 && HasTags(Tags, s.Tags)
+}
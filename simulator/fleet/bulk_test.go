@@ -0,0 +1,56 @@
+package fleet
+
+import (
+	"testing"
+)
+
+func TestCreateValidate(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		create Create
+		valid  bool
+	}{
+		{"index", Create{Count: 10, Name: "meter-{n}", DevEUI: "0000000000000001"}, true},
+		{"DevEUI", Create{Count: 10, Name: "{devEUI}", DevEUI: "0000000000000001"}, true},
+		{"last DevEUI", Create{Count: 1, Name: "meter-{n}", DevEUI: "ffffffffffffffff"}, true},
+		{"range to the last DevEUI", Create{Count: 2, Name: "meter-{n}", DevEUI: "fffffffffffffffe"}, true},
+		{"range overflow", Create{Count: 2, Name: "meter-{n}", DevEUI: "ffffffffffffffff"}, false},
+		{"long range overflow", Create{Count: MaxCount, Name: "meter-{n}", DevEUI: "ffffffffffff0000"}, false},
+		{"no count", Create{Name: "meter-{n}", DevEUI: "0000000000000001"}, false},
+		{"too many", Create{Count: MaxCount + 1, Name: "meter-{n}", DevEUI: "0000000000000001"}, false},
+		{"fixed name", Create{Count: 2, Name: "meter", DevEUI: "0000000000000001"}, false},
+		{"invalid DevEUI", Create{Count: 1, Name: "meter-{n}", DevEUI: "00000001"}, false},
+		{"invalid DevAddr", Create{Count: 1, Name: "meter-{n}", DevEUI: "0000000000000001", DevAddr: "zz"}, false},
+		{"placement", Create{Count: 1, Name: "meter-{n}", DevEUI: "0000000000000001", Placement: Placement{Polygon: lShape[:2]}}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.create.Validate(); (err == nil) != test.valid {
+			t.Errorf("%v: Validate() = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestDeviceName(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		first   int
+		index   int
+		want    string
+	}{
+		{"meter-{n}", 0, 0, "meter-0"},
+		{"meter-{n}", 100, 5, "meter-105"},
+		{"{devEUI}", 0, 0, "00000000000000ff"},
+		{"{n}-{devEUI}-{n}", 1, 1, "2-00000000000000ff-2"},
+	}
+
+	for _, test := range tests {
+
+		c := Create{Name: test.pattern, FirstIndex: test.first}
+		if got := c.DeviceName(test.index, EUI(0xff)); got != test.want {
+			t.Errorf("DeviceName(%q, %v) = %v, want %v", test.pattern, test.index, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package fleet
+
+import (
+	"errors"
+)
+
+const (
+	CommandTurnOn     = "turn-on"     // devices, gateways
+	CommandTurnOff    = "turn-off"    // devices, gateways
+	CommandPayload    = "payload"     // devices: MType and Payload
+	CommandInterval   = "interval"    // devices: Interval
+	CommandMACCommand = "mac-command" // devices: CID and Periodicity
+	CommandMove       = "move"        // devices, gateways: Latitude, Longitude and Altitude
+	CommandReboot     = "reboot"      // devices
+)
+
+// Command is applied to every selected device or gateway
+type Command struct {
+	Selection
+	Command string `json:"command"`
+
+	MType   string `json:"mtype,omitempty"` // ConfirmedDataUp or UnconfirmedDataUp
+	Payload string `json:"payload,omitempty"`
+
+	Interval float64 `json:"interval,omitempty"` // seconds
+
+	CID         string `json:"cid,omitempty"` // DeviceTimeReq, LinkCheckReq or PingSlotInfoReq
+	Periodicity uint8  `json:"periodicity,omitempty"`
+
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	Altitude  int32   `json:"altitude,omitempty"`
+}
+
+// Validate checks the command, gateway is true for the commands to gateways
+func (c *Command) Validate(gateway bool) error {
+
+	if c.Selection.Empty() {
+		return errors.New("Command without targets")
+	}
+
+	switch c.Command {
+
+	case CommandTurnOn, CommandTurnOff, CommandMove:
+
+	case CommandPayload, CommandMACCommand, CommandReboot:
+		if gateway {
+			return errors.New("Command " + c.Command + " not supported by gateways")
+		}
+
+	case CommandInterval:
+		if gateway {
+			return errors.New("Command " + c.Command + " not supported by gateways")
+		}
+
+		if c.Interval <= 0 {
+			return errors.New("Interval must be greater than 0")
+		}
+
+	default:
+		return errors.New("Command " + c.Command + " not supported")
+
+	}
+
+	return nil
+}
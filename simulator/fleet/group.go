@@ -0,0 +1,70 @@
+package fleet
+
+import (
+	"errors"
+	"strings"
+)
+
+// Group is a named set of devices and gateways, persisted in groups.json
+type Group struct {
+	Name     string    `json:"name"`
+	Devices  Selection `json:"devices"`
+	Gateways Selection `json:"gateways"`
+}
+
+// Validate checks the group
+func (g *Group) Validate() error {
+
+	if strings.TrimSpace(g.Name) == "" {
+		return errors.New("Group without name")
+	}
+
+	if g.Devices.Group != "" || g.Gateways.Group != "" {
+		return errors.New("A group can't contain another group")
+	}
+
+	if g.Devices.Empty() && g.Gateways.Empty() {
+		return errors.New("Group without members")
+	}
+
+	return nil
+}
+
+// HasTags is true if tags contains all the required ones
+func HasTags(tags []string, required []string) bool {
+
+	for _, r := range required {
+
+		found := false
+		for _, t := range tags {
+			if t == r {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NormalizeTags trims the tags and removes the empty and duplicated ones
+func NormalizeTags(tags []string) []string {
+
+	var normalized []string
+
+	for _, t := range tags {
+
+		t = strings.TrimSpace(t)
+		if t == "" || HasTags(normalized, []string{t}) {
+			continue
+		}
+
+		normalized = append(normalized, t)
+	}
+
+	return normalized
+}
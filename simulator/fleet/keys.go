@@ -0,0 +1,150 @@
+package fleet
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+
+	"github.com/brocaar/lorawan"
+)
+
+const (
+	KeysRandom  = "random"  // random keys for each device
+	KeysDerived = "derived" // AES128(RootKey, DevEUI | tag): reproducible on the network server
+	KeysFixed   = "fixed"   // RootKey for all devices
+)
+
+const (
+	tagAppKey = iota + 1
+	tagNwkSKey
+	tagAppSKey
+)
+
+// ParseEUI parses the first EUI of a range
+func ParseEUI(s string) (uint64, error) {
+
+	var eui lorawan.EUI64
+	if err := eui.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("Invalid DevEUI %v", s)
+	}
+
+	return binary.BigEndian.Uint64(eui[:]), nil
+}
+
+// EUI returns the EUI64 of value
+func EUI(value uint64) lorawan.EUI64 {
+
+	var eui lorawan.EUI64
+	binary.BigEndian.PutUint64(eui[:], value)
+
+	return eui
+}
+
+// DevAddr returns the DevAddr of value
+func DevAddr(value uint32) lorawan.DevAddr {
+
+	var addr lorawan.DevAddr
+	binary.BigEndian.PutUint32(addr[:], value)
+
+	return addr
+}
+
+// ParseDevAddr parses the first DevAddr of a range
+func ParseDevAddr(s string) (uint32, error) {
+
+	var addr lorawan.DevAddr
+	if err := addr.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("Invalid DevAddr %v", s)
+	}
+
+	return binary.BigEndian.Uint32(addr[:]), nil
+}
+
+// ParseKey parses a 128 bits hex key
+func ParseKey(s string) ([16]byte, error) {
+
+	var key [16]byte
+
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 16 {
+		return key, fmt.Errorf("Invalid key %v", s)
+	}
+
+	copy(key[:], b)
+
+	return key, nil
+}
+
+// Keys generates the keys of the devices
+type Keys struct {
+	Mode string
+	Root [16]byte
+
+	random *rand.Rand
+}
+
+// NewKeys validates mode and root key
+func NewKeys(mode string, root string, random *rand.Rand) (*Keys, error) {
+
+	k := Keys{
+		Mode:   mode,
+		random: random,
+	}
+
+	switch mode {
+
+	case KeysRandom, "":
+		k.Mode = KeysRandom
+		return &k, nil
+
+	case KeysDerived, KeysFixed:
+		key, err := ParseKey(root)
+		if err != nil {
+			return nil, err
+		}
+		k.Root = key
+
+	default:
+		return nil, fmt.Errorf("Keys %v not supported", mode)
+	}
+
+	return &k, nil
+}
+
+// key returns the key identified by tag for the device DevEUI
+func (k *Keys) key(DevEUI lorawan.EUI64, tag byte) [16]byte {
+
+	var key [16]byte
+
+	switch k.Mode {
+
+	case KeysFixed:
+		return k.Root
+
+	case KeysDerived:
+		var block [16]byte
+		copy(block[:], DevEUI[:])
+		block[15] = tag
+
+		cipher, _ := aes.NewCipher(k.Root[:])
+		cipher.Encrypt(key[:], block[:])
+
+	default:
+		k.random.Read(key[:])
+
+	}
+
+	return key
+}
+
+// AppKey of the device DevEUI
+func (k *Keys) AppKey(DevEUI lorawan.EUI64) [16]byte {
+	return k.key(DevEUI, tagAppKey)
+}
+
+// SessionKeys returns NwkSKey and AppSKey of an ABP device
+func (k *Keys) SessionKeys(DevEUI lorawan.EUI64) ([16]byte, [16]byte) {
+	return k.key(DevEUI, tagNwkSKey), k.key(DevEUI, tagAppSKey)
+}
@@ -0,0 +1,100 @@
+package fleet
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+)
+
+// MaxPlacementAttempts bounds the points drawn in the bounding box of a polygon
+const MaxPlacementAttempts = 1000
+
+// Placement positions the new devices
+type Placement struct {
+	Polygon []loc.Location `json:"polygon,omitempty"` // uniformly inside the polygon
+	Gateway *int           `json:"gateway,omitempty"` // uniformly around the gateway
+	Radius  float64        `json:"radius,omitempty"`  // km around the gateway
+}
+
+// Validate checks the placement
+func (p *Placement) Validate() error {
+
+	if len(p.Polygon) > 0 && p.Gateway != nil {
+		return errors.New("Set only one of polygon and gateway")
+	}
+
+	if len(p.Polygon) > 0 && len(p.Polygon) < 3 {
+		return errors.New("Polygon requires at least 3 vertices")
+	}
+
+	if p.Gateway != nil && p.Radius <= 0 {
+		return errors.New("Radius must be positive")
+	}
+
+	return nil
+}
+
+// Around returns a point uniformly distributed in the circle of radius km around center
+func Around(center loc.Location, radius float64, random *rand.Rand) loc.Location {
+
+	distance := radius * math.Sqrt(random.Float64())
+	bearing := 2 * math.Pi * random.Float64()
+
+	dLat := distance * math.Cos(bearing) / loc.RADIUS * 180 / math.Pi
+	dLng := distance * math.Sin(bearing) / loc.RADIUS * 180 / math.Pi / math.Cos(loc.Radians(center.Latitude))
+
+	return loc.Location{
+		Latitude:  center.Latitude + dLat,
+		Longitude: center.Longitude + dLng,
+		Altitude:  center.Altitude,
+	}
+}
+
+// Inside returns a point uniformly distributed in the polygon, an error if none is found: the
+// polygon is degenerate or too thin in its bounding box
+func Inside(polygon []loc.Location, random *rand.Rand) (loc.Location, error) {
+
+	minLat, maxLat := polygon[0].Latitude, polygon[0].Latitude
+	minLng, maxLng := polygon[0].Longitude, polygon[0].Longitude
+
+	for _, v := range polygon[1:] {
+		minLat, maxLat = math.Min(minLat, v.Latitude), math.Max(maxLat, v.Latitude)
+		minLng, maxLng = math.Min(minLng, v.Longitude), math.Max(maxLng, v.Longitude)
+	}
+
+	point := loc.Location{Altitude: polygon[0].Altitude}
+
+	for i := 0; i < MaxPlacementAttempts; i++ { // rejection sampling on the bounding box
+
+		point.Latitude = minLat + random.Float64()*(maxLat-minLat)
+		point.Longitude = minLng + random.Float64()*(maxLng-minLng)
+
+		if contains(polygon, point) {
+			return point, nil
+		}
+
+	}
+
+	return loc.Location{}, errors.New("No point found inside the polygon, check its vertices")
+}
+
+// contains uses ray casting
+func contains(polygon []loc.Location, point loc.Location) bool {
+
+	in := false
+
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+
+		a, b := polygon[i], polygon[j]
+
+		if (a.Latitude > point.Latitude) != (b.Latitude > point.Latitude) &&
+			point.Longitude < (b.Longitude-a.Longitude)*(point.Latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			in = !in
+		}
+
+	}
+
+	return in
+}
@@ -0,0 +1,146 @@
+package fleet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	loc "github.com/arslab/lwnsimulator/simulator/resources/location"
+)
+
+// lShape is the unit squares [0,2)x[0,1) and [0,1)x[1,2), (latitude, longitude)
+var lShape = []loc.Location{
+	{Latitude: 0, Longitude: 0},
+	{Latitude: 0, Longitude: 2},
+	{Latitude: 1, Longitude: 2},
+	{Latitude: 1, Longitude: 1},
+	{Latitude: 2, Longitude: 1},
+	{Latitude: 2, Longitude: 0},
+}
+
+func TestContains(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		inside    bool
+	}{
+		{"corner", 0.5, 0.5, true},
+		{"east arm", 0.5, 1.5, true},
+		{"north arm", 1.5, 0.5, true},
+		{"notch", 1.5, 1.5, false},
+		{"south", -0.5, 0.5, false},
+		{"north", 2.5, 0.5, false},
+		{"west", 0.5, -0.5, false},
+		{"east", 0.5, 2.5, false},
+	}
+
+	for _, test := range tests {
+
+		point := loc.Location{Latitude: test.latitude, Longitude: test.longitude}
+		if got := contains(lShape, point); got != test.inside {
+			t.Errorf("%v: contains(%v, %v) = %v", test.name, test.latitude, test.longitude, got)
+		}
+	}
+}
+
+func TestInside(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+
+	var arms [3]int // corner, east arm, north arm
+
+	for i := 0; i < 3000; i++ {
+
+		p, err := Inside(lShape, random)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case p.Latitude < 0 || p.Longitude < 0 || p.Latitude >= 2 || p.Longitude >= 2,
+			p.Latitude >= 1 && p.Longitude >= 1:
+			t.Fatalf("point %+v outside the polygon", p)
+		case p.Latitude >= 1:
+			arms[2]++
+		case p.Longitude >= 1:
+			arms[1]++
+		default:
+			arms[0]++
+		}
+	}
+
+	for i, n := range arms { // uniform: a third each
+		if n < 800 || n > 1200 {
+			t.Errorf("square %v has %v points of 3000", i, n)
+		}
+	}
+
+	degenerate := [][]loc.Location{
+		{{Latitude: 0, Longitude: 0}, {Latitude: 1, Longitude: 1}, {Latitude: 2, Longitude: 2}}, // collinear
+		{{Latitude: 45, Longitude: 7}, {Latitude: 45, Longitude: 7}, {Latitude: 45, Longitude: 7}},
+	}
+
+	for _, polygon := range degenerate {
+		if p, err := Inside(polygon, random); err == nil {
+			t.Errorf("Inside(%v) = %+v, want an error", polygon, p)
+		}
+	}
+}
+
+func TestAround(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+
+	center := loc.Location{Latitude: 60, Longitude: 10, Altitude: 30}
+	radius := 5.0 // km
+
+	var farthest, eastWest float64
+
+	for i := 0; i < 3000; i++ {
+
+		p := Around(center, radius, random)
+		if p.Altitude != center.Altitude {
+			t.Fatalf("altitude %v, want the center's", p.Altitude)
+		}
+
+		distance := loc.GetDistance(center.Latitude, center.Longitude, p.Latitude, p.Longitude)
+		if distance > radius*1.001 {
+			t.Fatalf("point %+v at %v km, radius %v", p, distance, radius)
+		}
+
+		farthest = math.Max(farthest, distance)
+		eastWest = math.Max(eastWest, loc.GetDistance(center.Latitude, center.Longitude, center.Latitude, p.Longitude))
+	}
+
+	// at 60° a degree of longitude is half a degree of latitude, without the correction
+	// the circle would be an ellipse 2.5 km wide
+	if farthest < 0.95*radius || eastWest < 0.95*radius {
+		t.Errorf("farthest point at %v km, %v km east or west, radius %v", farthest, eastWest, radius)
+	}
+}
+
+func TestPlacementValidate(t *testing.T) {
+
+	gateway := 1
+
+	tests := []struct {
+		name      string
+		placement Placement
+		valid     bool
+	}{
+		{"empty", Placement{}, true},
+		{"polygon", Placement{Polygon: lShape}, true},
+		{"gateway", Placement{Gateway: &gateway, Radius: 2}, true},
+		{"two vertices", Placement{Polygon: lShape[:2]}, false},
+		{"both", Placement{Polygon: lShape, Gateway: &gateway, Radius: 2}, false},
+		{"radius", Placement{Gateway: &gateway}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.placement.Validate(); (err == nil) != test.valid {
+			t.Errorf("%v: Validate() = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
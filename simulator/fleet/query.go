@@ -0,0 +1,115 @@
+package fleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brocaar/lorawan"
+)
+
+const (
+	DefaultLimit = 100  // page size when the query doesn't set one
+	MaxLimit     = 1000 // largest page
+)
+
+const (
+	SortId      = "id"
+	SortName    = "name"
+	SortAddress = "address" // DevEUI of the devices, MAC address of the gateways
+)
+
+// Query filters, sorts and paginates a listing of devices or gateways
+type Query struct {
+	Selection
+	Active *bool  `json:"active,omitempty"`
+	Sort   string `json:"sort,omitempty"` // id, name or address, descending with the - prefix
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+// Entry is the part of a component the listing is sorted by
+type Entry struct {
+	Id      int
+	Name    string
+	Address lorawan.EUI64
+}
+
+// Page is a slice of a listing
+type Page struct {
+	Items  []json.RawMessage `json:"items"`
+	Total  int               `json:"total"` // components matching the query
+	Offset int               `json:"offset"`
+	Limit  int               `json:"limit"`
+}
+
+// Validate checks the query and sets the default limit
+func (q *Query) Validate() error {
+
+	if q.Offset < 0 {
+		return errors.New("Offset can't be negative")
+	}
+
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		return errors.New("Limit must be from 1 to " + strconv.Itoa(MaxLimit))
+	}
+
+	switch strings.TrimPrefix(q.Sort, "-") {
+	case "", SortId, SortName, SortAddress:
+	default:
+		return errors.New("Unknown sort field " + q.Sort)
+	}
+
+	return nil
+}
+
+// Filtered is true if the component is excluded by the active filter
+func (q *Query) Filtered(Active bool) bool {
+	return q.Active != nil && *q.Active != Active
+}
+
+// Paginate sorts the entries and returns the ids of the requested page
+func (q *Query) Paginate(entries []Entry) []int {
+
+	field := strings.TrimPrefix(q.Sort, "-")
+	desc := strings.HasPrefix(q.Sort, "-")
+
+	sort.Slice(entries, func(i, j int) bool {
+
+		a, b := entries[i], entries[j]
+		if desc {
+			a, b = b, a
+		}
+
+		switch field {
+
+		case SortName:
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+
+		case SortAddress:
+			if c := bytes.Compare(a.Address[:], b.Address[:]); c != 0 {
+				return c < 0
+			}
+
+		}
+
+		return a.Id < b.Id
+	})
+
+	var ids []int
+
+	for i := q.Offset; i < len(entries) && len(ids) < q.Limit; i++ {
+		ids = append(ids, entries[i].Id)
+	}
+
+	return ids
+}
@@ -0,0 +1,66 @@
+package fleet
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Template is a named partial device, in the JSON format of the devices (eg. {"info":{"configuration":{...}}})
+type Template struct {
+	Name   string          `json:"name"`
+	Device json.RawMessage `json:"device"`
+}
+
+// Validate checks the template is a JSON object
+func (t *Template) Validate() error {
+
+	if t.Name == "" {
+		return errors.New("Template without name")
+	}
+
+	var device map[string]interface{}
+	if err := json.Unmarshal(t.Device, &device); err != nil {
+		return errors.New("Template's device must be a JSON object")
+	}
+
+	return nil
+}
+
+// Merge applies patch over base: objects are merged recursively, other values replaced
+func Merge(base []byte, patch []byte) ([]byte, error) {
+
+	var b, p interface{}
+
+	if len(base) > 0 {
+		if err := json.Unmarshal(base, &b); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(patch) > 0 {
+		if err := json.Unmarshal(patch, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(merge(b, p))
+}
+
+func merge(base interface{}, patch interface{}) interface{} {
+
+	if patch == nil {
+		return base
+	}
+
+	b, okB := base.(map[string]interface{})
+	p, okP := patch.(map[string]interface{})
+	if !okB || !okP {
+		return patch
+	}
+
+	for key, value := range p {
+		b[key] = merge(b[key], value)
+	}
+
+	return b
+}
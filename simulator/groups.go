@@ -0,0 +1,113 @@
+package simulator
+
+import (
+	"log"
+	"os"
+	"sort"
+
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/simulator/util"
+)
+
+func (s *Simulator) loadGroups() {
+
+	s.Groups = make(map[string]fleet.Group)
+
+	path, err := util.GetPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path += "/groups.json"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return
+	}
+
+	err = util.RecoverConfigFile(path, &s.Groups)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+}
+
+func (s *Simulator) saveGroups() {
+
+	pathDir, err := util.GetPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.saveComponent(pathDir+"/groups.json", &s.Groups)
+}
+
+func (s *Simulator) GetGroups() []fleet.Group {
+
+	groups := []fleet.Group{}
+
+	for _, g := range s.Groups {
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	return groups
+}
+
+func (s *Simulator) SaveGroup(group fleet.Group) error {
+
+	if err := group.Validate(); err != nil {
+		return err
+	}
+
+	group.Devices.Tags = fleet.NormalizeTags(group.Devices.Tags)
+	group.Gateways.Tags = fleet.NormalizeTags(group.Gateways.Tags)
+
+	s.Groups[group.Name] = group
+	s.saveGroups()
+
+	s.Print("Group "+group.Name+" saved", nil, util.PrintOnlyConsole)
+
+	return nil
+}
+
+func (s *Simulator) DeleteGroup(Name string) bool {
+
+	if _, ok := s.Groups[Name]; !ok {
+		return false
+	}
+
+	delete(s.Groups, Name)
+	s.saveGroups()
+
+	s.Print("Group "+Name+" deleted", nil, util.PrintOnlyConsole)
+
+	return true
+}
+
+func (s *Simulator) selectGateways(selection fleet.Selection) []int {
+
+	var ids []int
+
+	for id := range s.Gateways {
+		if s.gatewaySelected(id, selection) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+// gatewaySelected is true if the gateway matches the selection or its group
+func (s *Simulator) gatewaySelected(Id int, selection fleet.Selection) bool {
+
+	g := s.Gateways[Id]
+	if selection.Match(Id, g.Info.Name, g.Info.Tags) {
+		return true
+	}
+
+	group, ok := s.Groups[selection.Group]
+
+	return ok && group.Gateways.Match(Id, g.Info.Name, g.Info.Tags)
+}
@@ -0,0 +1,254 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/arslab/lwnsimulator/codes"
+	gw "github.com/arslab/lwnsimulator/simulator/components/gateway"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/simulator/util"
+	"github.com/brocaar/lorawan"
+)
+
+// ListDevices returns the page of the devices matching the query
+func (s *Simulator) ListDevices(query fleet.Query) (fleet.Page, error) {
+
+	if err := query.Validate(); err != nil {
+		return fleet.Page{}, err
+	}
+
+	var entries []fleet.Entry
+
+	for id, d := range s.Devices {
+
+		if !query.Empty() && !s.deviceSelected(id, query.Selection) {
+			continue
+		}
+
+		if query.Filtered(d.Info.Status.Active) {
+			continue
+		}
+
+		entries = append(entries, fleet.Entry{Id: id, Name: d.Info.Name, Address: d.Info.DevEUI})
+	}
+
+	page := s.newPage(query, len(entries))
+
+	for _, id := range query.Paginate(entries) {
+
+		bytes, err := json.Marshal(s.Devices[id])
+		if err != nil {
+			return fleet.Page{}, err
+		}
+
+		page.Items = append(page.Items, bytes)
+	}
+
+	return page, nil
+}
+
+// ListGateways returns the page of the gateways matching the query
+func (s *Simulator) ListGateways(query fleet.Query) (fleet.Page, error) {
+
+	if err := query.Validate(); err != nil {
+		return fleet.Page{}, err
+	}
+
+	var entries []fleet.Entry
+
+	for id, g := range s.Gateways {
+
+		if !query.Empty() && !s.gatewaySelected(id, query.Selection) {
+			continue
+		}
+
+		if query.Filtered(g.Info.Active) {
+			continue
+		}
+
+		entries = append(entries, fleet.Entry{Id: id, Name: g.Info.Name, Address: g.Info.MACAddress})
+	}
+
+	page := s.newPage(query, len(entries))
+
+	for _, id := range query.Paginate(entries) {
+
+		bytes, err := json.Marshal(s.Gateways[id])
+		if err != nil {
+			return fleet.Page{}, err
+		}
+
+		page.Items = append(page.Items, bytes)
+	}
+
+	return page, nil
+}
+
+func (s *Simulator) newPage(query fleet.Query, total int) fleet.Page {
+
+	return fleet.Page{
+		Items:  []json.RawMessage{},
+		Total:  total,
+		Offset: query.Offset,
+		Limit:  query.Limit,
+	}
+}
+
+// GetDevice returns the JSON of the device, its last snapshot if running
+func (s *Simulator) GetDevice(Id int) (json.RawMessage, int, error) {
+
+	d, ok := s.Devices[Id]
+	if !ok {
+		return nil, codes.CodeErrorNotFound, errors.New("Device not found")
+	}
+
+	bytes, err := json.Marshal(d)
+	if err != nil {
+		return nil, codes.CodeErrorInternal, err
+	}
+
+	return bytes, codes.CodeOK, nil
+}
+
+// DeviceByEUI returns the JSON of the device with the DevEUI
+func (s *Simulator) DeviceByEUI(DevEUI lorawan.EUI64) (json.RawMessage, int, error) {
+
+	for id, d := range s.Devices {
+		if d.Info.DevEUI == DevEUI {
+			return s.GetDevice(id)
+		}
+	}
+
+	return nil, codes.CodeErrorNotFound, errors.New("Device not found")
+}
+
+// MergeDevice merges the patch over the device, a running device isn't updated
+func (s *Simulator) MergeDevice(Id int, patch json.RawMessage) (int, error) {
+
+	d, ok := s.Devices[Id]
+	if !ok {
+		return codes.CodeErrorNotFound, errors.New("Device not found")
+	}
+
+	if d.IsOn() {
+		return codes.CodeErrorDeviceActive, errors.New("Device is running, unable update")
+	}
+
+	device, err := s.patchDevice(Id, patch)
+	if err != nil {
+		return codes.CodeErrorPatch, err
+	}
+
+	code, _, err := s.SetDevice(device, true)
+
+	return code, err
+}
+
+// RemoveDevice deletes the device, a running device isn't deleted
+func (s *Simulator) RemoveDevice(Id int) (int, error) {
+
+	if _, ok := s.Devices[Id]; !ok {
+		return codes.CodeErrorNotFound, errors.New("Device not found")
+	}
+
+	if !s.deleteDevice(Id) {
+		return codes.CodeErrorDeviceActive, errors.New("Device is running, unable delete")
+	}
+
+	s.saveDevices()
+	s.Print("Device Deleted", nil, util.PrintOnlyConsole)
+
+	return codes.CodeOK, nil
+}
+
+// GetGateway returns the JSON of the gateway
+func (s *Simulator) GetGateway(Id int) (json.RawMessage, int, error) {
+
+	g, ok := s.Gateways[Id]
+	if !ok {
+		return nil, codes.CodeErrorNotFound, errors.New("Gateway not found")
+	}
+
+	bytes, err := json.Marshal(g)
+	if err != nil {
+		return nil, codes.CodeErrorInternal, err
+	}
+
+	return bytes, codes.CodeOK, nil
+}
+
+// GatewayByMAC returns the JSON of the gateway with the MAC address
+func (s *Simulator) GatewayByMAC(MACAddress lorawan.EUI64) (json.RawMessage, int, error) {
+
+	for id, g := range s.Gateways {
+		if g.Info.MACAddress == MACAddress {
+			return s.GetGateway(id)
+		}
+	}
+
+	return nil, codes.CodeErrorNotFound, errors.New("Gateway not found")
+}
+
+// MergeGateway merges the patch over the gateway, a running gateway isn't updated
+func (s *Simulator) MergeGateway(Id int, patch json.RawMessage) (int, error) {
+
+	g, ok := s.Gateways[Id]
+	if !ok {
+		return codes.CodeErrorNotFound, errors.New("Gateway not found")
+	}
+
+	if g.IsOn() {
+		return codes.CodeErrorGatewayActive, errors.New("Gateway is running, unable update")
+	}
+
+	current, err := json.Marshal(g)
+	if err != nil {
+		return codes.CodeErrorInternal, err
+	}
+
+	merged, err := fleet.Merge(current, patch)
+	if err != nil {
+		return codes.CodeErrorPatch, err
+	}
+
+	gateway := gw.Gateway{}
+	if err := json.Unmarshal(merged, &gateway); err != nil {
+		return codes.CodeErrorPatch, err
+	}
+
+	gateway.Id = Id
+
+	code, _, err := s.SetGateway(&gateway, true)
+
+	return code, err
+}
+
+// RemoveGateway deletes the gateway, a running gateway isn't deleted
+func (s *Simulator) RemoveGateway(Id int) (int, error) {
+
+	g, ok := s.Gateways[Id]
+	if !ok {
+		return codes.CodeErrorNotFound, errors.New("Gateway not found")
+	}
+
+	if g.IsOn() {
+		return codes.CodeErrorGatewayActive, errors.New("Gateway is running, unable delete")
+	}
+
+	delete(s.Gateways, Id)
+	delete(s.ActiveGateways, Id)
+
+	pathDir, err := util.GetPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path := pathDir + "/gateways.json"
+	s.saveComponent(path, &s.Gateways)
+
+	s.Print("Gateway Deleted", nil, util.PrintOnlyConsole)
+
+	return codes.CodeOK, nil
+}
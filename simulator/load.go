@@ -0,0 +1,299 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/clock"
+	"github.com/arslab/lwnsimulator/simulator/load"
+	res "github.com/arslab/lwnsimulator/simulator/resources"
+	"github.com/arslab/lwnsimulator/simulator/util"
+	"github.com/arslab/lwnsimulator/socket"
+)
+
+type loadController struct {
+	profile load.Profile
+	pool    []int
+	saved   map[int]savedDevice // devices before the profile, restored at the end
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// savedDevice is the state of a device of the pool changed by the profile
+type savedDevice struct {
+	interval time.Duration
+	on       bool // turned on
+	started  bool // in the devices turned on at the start of the simulation
+	active   bool // Status.Active
+}
+
+// StartLoadProfile starts the simulator if it's stopped and drives the selected devices
+// to the target uplink rate of the profile
+func (s *Simulator) StartLoadProfile(profile load.Profile) error {
+
+	if err := profile.Setup(); err != nil {
+		return err
+	}
+
+	s.loadMutex.Lock()
+	running := s.load != nil
+	s.loadMutex.Unlock()
+
+	if running {
+		return errors.New("A load profile is already running")
+	}
+
+	pool := s.selectDevices(profile.Devices)
+	if len(pool) == 0 {
+		return errors.New("Load profile without devices")
+	}
+
+	if s.State != util.Running {
+
+		if err := s.CheckClock(s.Clock); err != nil {
+			return err
+		}
+
+		s.Run()
+	}
+
+	l := &loadController{
+		profile: profile,
+		pool:    pool,
+		saved:   make(map[int]savedDevice),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	for _, id := range pool {
+
+		d := s.Devices[id]
+		_, started := s.ActiveDevices[id]
+
+		l.saved[id] = savedDevice{
+			interval: d.Info.Configuration.SendInterval,
+			on:       d.IsOn(),
+			started:  started,
+			active:   d.Info.Status.Active,
+		}
+	}
+
+	s.loadMutex.Lock()
+	s.load = l
+	s.loadStatus = load.Status{
+		Running: true,
+		Profile: &l.profile,
+		Started: s.Resources.Clock.Now(),
+	}
+	s.loadMutex.Unlock()
+
+	s.Resources.Clock.Go(func() { s.runLoad(l) }) // a virtual clock waits the ticks of the profile
+
+	s.Print(fmt.Sprintf("Load profile started: %v stages on %v devices, %v", len(profile.Stages), len(pool), profile.Duration()), nil, util.PrintBoth)
+
+	return nil
+}
+
+// StopLoadProfile stops the running profile, the simulator keeps running
+func (s *Simulator) StopLoadProfile() bool {
+	return s.stopLoad()
+}
+
+// GetLoadStatus returns the running profile and its last report
+func (s *Simulator) GetLoadStatus() load.Status {
+
+	s.loadMutex.Lock()
+	defer s.loadMutex.Unlock()
+
+	return s.loadStatus
+}
+
+func (s *Simulator) stopLoad() bool {
+
+	s.loadMutex.Lock()
+	l := s.load
+	s.load = nil
+	s.loadMutex.Unlock()
+
+	if l == nil {
+		return false
+	}
+
+	close(l.stop)
+	<-l.done
+
+	s.applyLoad(l, 0, 0, true)
+
+	s.loadMutex.Lock()
+	s.loadStatus.Running = false
+	s.loadMutex.Unlock()
+
+	s.Print("Load profile stopped", nil, util.PrintBoth)
+
+	return true
+}
+
+// runLoad drives the devices at every tick of the profile, the ticks are commands of the loop
+// paced by the clock of the simulation
+func (s *Simulator) runLoad(l *loadController) {
+
+	clk := s.Resources.Clock
+
+	started := clk.Now()
+	first := s.Resources.Stats.Get()
+	previous, last := first, started
+
+	finished := false
+
+	for n := 1; !finished; n++ {
+
+		tick := func() {
+
+			now := clk.Now()
+			stage, target, ok := l.profile.Target(now.Sub(started))
+			finished = !ok
+
+			active, interval := l.profile.Plan(target, len(l.pool))
+			s.applyLoad(l, active, interval, finished) // at the end the devices are restored
+
+			counters := s.Resources.Stats.Get()
+
+			report := load.NewReport(now.Sub(last), counters.Sub(previous), now)
+			report.Elapsed = now.Sub(started).Seconds()
+			report.Stage = stage
+			report.Target = target
+			report.Active = s.activeLoad(l)
+			report.Interval = interval.Seconds()
+
+			previous, last = counters, now
+
+			s.reportLoad(report, counters.Sub(first), finished)
+		}
+
+		if !s.doUnless(l.stop, tick) { // stopLoad restores the devices
+			close(l.done)
+			return
+		}
+
+		if finished {
+			break
+		}
+
+		next := started.Add(time.Duration(n) * l.profile.Tick)
+
+		if !clk.Sleep(clock.Until(clk, next), l.stop) {
+			close(l.done)
+			return
+		}
+
+	}
+
+	s.loadMutex.Lock()
+	owner := s.load == l
+	if owner {
+		s.load = nil
+	}
+	s.loadMutex.Unlock()
+
+	close(l.done)
+
+	if !owner { // stopped meanwhile
+		return
+	}
+
+	s.Print("Load profile completed", nil, util.PrintBoth)
+
+	if l.profile.StopAtEnd {
+		s.Do(func() {
+			if s.State == util.Running {
+				s.Stop()
+			}
+		})
+	}
+}
+
+// applyLoad turns on the first active devices of the pool with the send interval and
+// turns off the others; restore sets back the devices as they were before the profile
+func (s *Simulator) applyLoad(l *loadController, active int, interval time.Duration, restore bool) {
+
+	for i, id := range l.pool {
+
+		d, ok := s.Devices[id]
+		if !ok { // deleted meanwhile
+			continue
+		}
+
+		if restore {
+			s.restoreLoad(id, l.saved[id])
+			continue
+		}
+
+		if i >= active {
+
+			if d.IsOn() {
+				s.turnOFFDevice(id)
+			}
+
+			continue
+		}
+
+		d.SetSendInterval(interval)
+
+		if s.State == util.Running && !d.IsOn() {
+			s.turnONDevice(id)
+		}
+	}
+
+}
+
+// restoreLoad sets back a device of the pool, it isn't turned on if the simulator is stopping
+func (s *Simulator) restoreLoad(Id int, saved savedDevice) {
+
+	d := s.Devices[Id]
+
+	d.SetSendInterval(saved.interval)
+	d.SetActive(saved.active)
+
+	if d.IsOn() && !saved.on {
+		s.turnOFFDevice(Id)
+	} else if !d.IsOn() && saved.on && s.State == util.Running {
+		s.turnONDevice(Id)
+	}
+
+	if saved.started {
+		s.ActiveDevices[Id] = Id
+	} else {
+		delete(s.ActiveDevices, Id)
+	}
+}
+
+// activeLoad returns the devices of the pool turned on
+func (s *Simulator) activeLoad(l *loadController) int {
+
+	active := 0
+
+	for _, id := range l.pool {
+		if d, ok := s.Devices[id]; ok && d.IsOn() {
+			active++
+		}
+	}
+
+	return active
+}
+
+func (s *Simulator) reportLoad(report load.Report, total res.Counters, finished bool) {
+
+	s.loadMutex.Lock()
+	s.loadStatus.Last = &report
+	s.loadStatus.Total = total
+	s.loadStatus.Running = !finished
+	s.loadMutex.Unlock()
+
+	s.Console.PrintSocket(socket.EventLoadReport, report)
+
+	s.Print(fmt.Sprintf("Load: target %.1f up/s, achieved %.1f up/s, %v devices every %v, join success %.0f%%, downlink hit rate %.0f%%",
+		report.Target, report.Achieved, report.Active, time.Duration(report.Interval*float64(time.Second)),
+		report.JoinSuccess*100, report.DownlinkHitRate*100), nil, util.PrintOnlyConsole)
+}
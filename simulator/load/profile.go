@@ -0,0 +1,323 @@
+package load
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	res "github.com/arslab/lwnsimulator/simulator/resources"
+)
+
+const (
+	ShapeRamp  = "ramp"  // from rate to to, linearly
+	ShapeSoak  = "soak"  // rate for the whole stage
+	ShapeSpike = "spike" // to for hold (the whole stage if 0), then back to rate
+	ShapeStep  = "step"  // from rate to to in steps equal steps
+	ShapeSine  = "sine"  // rate ± amplitude with the given period
+
+	DefaultTick        = 5 * time.Second
+	DefaultMinInterval = 1 * time.Second
+	DefaultMaxInterval = 10 * time.Minute
+)
+
+// Stage is a segment of the profile, the rates are uplinks per second
+type Stage struct {
+	Shape     string        `json:"shape"`
+	Duration  time.Duration `json:"duration"`
+	Rate      float64       `json:"rate"`
+	To        float64       `json:"to"`
+	Steps     int           `json:"steps"`
+	Hold      time.Duration `json:"hold"`
+	Amplitude float64       `json:"amplitude"`
+	Period    time.Duration `json:"period"`
+}
+
+// Profile drives the devices of the selection to the target uplink rate
+type Profile struct {
+	Devices     fleet.Selection `json:"devices"`
+	Stages      []Stage         `json:"stages"`
+	Tick        time.Duration   `json:"tick"`        // adjustment and report interval
+	MinInterval time.Duration   `json:"minInterval"` // bounds of the devices' send interval
+	MaxInterval time.Duration   `json:"maxInterval"`
+	StopAtEnd   bool            `json:"stopAtEnd"` // stop the simulator at the end of the profile
+}
+
+// Report compares the achieved throughput with the target over the last tick
+type Report struct {
+	Time     time.Time `json:"time"`
+	Elapsed  float64   `json:"elapsed"` // seconds since the start
+	Stage    int       `json:"stage"`
+	Target   float64   `json:"target"`   // uplinks per second
+	Achieved float64   `json:"achieved"` // uplinks per second
+	Active   int       `json:"activeDevices"`
+	Interval float64   `json:"interval"` // seconds
+
+	Counters        res.Counters `json:"counters"`
+	JoinSuccess     float64      `json:"joinSuccess"`     // join accepts / join requests
+	DownlinkHitRate float64      `json:"downlinkHitRate"` // downlinks / uplinks
+}
+
+// Status of the load controller
+type Status struct {
+	Running bool         `json:"running"`
+	Profile *Profile     `json:"profile,omitempty"`
+	Started time.Time    `json:"started"`
+	Last    *Report      `json:"last,omitempty"`
+	Total   res.Counters `json:"total"` // since the start of the profile
+}
+
+// Setup validates the profile and sets the defaults
+func (p *Profile) Setup() error {
+
+	if p.Devices.Empty() {
+		return errors.New("Load profile without devices")
+	}
+
+	if len(p.Stages) == 0 {
+		return errors.New("Load profile without stages")
+	}
+
+	if p.Tick <= 0 {
+		p.Tick = DefaultTick
+	}
+
+	if p.MinInterval <= 0 {
+		p.MinInterval = DefaultMinInterval
+	}
+
+	if p.MaxInterval <= 0 {
+		p.MaxInterval = DefaultMaxInterval
+	}
+
+	if p.MaxInterval < p.MinInterval {
+		return errors.New("maxInterval must be greater than minInterval")
+	}
+
+	for i, s := range p.Stages {
+
+		if s.Duration <= 0 {
+			return fmt.Errorf("Stage %v: duration must be greater than 0", i)
+		}
+
+		if s.Rate < 0 || s.To < 0 || s.Amplitude < 0 {
+			return fmt.Errorf("Stage %v: rates must be positive", i)
+		}
+
+		switch s.Shape {
+
+		case ShapeRamp, ShapeSoak, ShapeSpike:
+
+		case ShapeStep:
+			if s.Steps <= 0 {
+				return fmt.Errorf("Stage %v: steps must be greater than 0", i)
+			}
+
+		case ShapeSine:
+			if s.Period <= 0 {
+				return fmt.Errorf("Stage %v: period must be greater than 0", i)
+			}
+
+		default:
+			return fmt.Errorf("Stage %v: shape %v not supported", i, s.Shape)
+
+		}
+	}
+
+	return nil
+}
+
+// Duration is the length of the whole profile
+func (p *Profile) Duration() time.Duration {
+
+	var total time.Duration
+	for _, s := range p.Stages {
+		total += s.Duration
+	}
+
+	return total
+}
+
+// Target returns the stage and the uplink rate at elapsed, false after the last stage
+func (p *Profile) Target(elapsed time.Duration) (int, float64, bool) {
+
+	for i, s := range p.Stages {
+
+		if elapsed < s.Duration {
+			return i, s.Target(elapsed), true
+		}
+
+		elapsed -= s.Duration
+	}
+
+	return len(p.Stages) - 1, 0, false
+}
+
+// Target returns the uplink rate at elapsed since the start of the stage
+func (s *Stage) Target(elapsed time.Duration) float64 {
+
+	progress := float64(elapsed) / float64(s.Duration)
+
+	switch s.Shape {
+
+	case ShapeRamp:
+		return s.Rate + (s.To-s.Rate)*progress
+
+	case ShapeSpike:
+		if s.Hold <= 0 || elapsed < s.Hold {
+			return s.To
+		}
+		return s.Rate
+
+	case ShapeStep:
+		step := math.Floor(progress * float64(s.Steps+1))
+		return s.Rate + (s.To-s.Rate)*step/float64(s.Steps)
+
+	case ShapeSine:
+		rate := s.Rate + s.Amplitude*math.Sin(2*math.Pi*float64(elapsed)/float64(s.Period))
+		return math.Max(rate, 0)
+
+	}
+
+	return s.Rate
+}
+
+// Plan returns the active devices and their send interval to reach rate with pool devices:
+// the interval is as long as possible, to keep the devices joined
+func (p *Profile) Plan(rate float64, pool int) (int, time.Duration) {
+
+	if rate <= 0 || pool == 0 {
+		return 0, p.MaxInterval
+	}
+
+	interval := time.Duration(float64(pool) / rate * float64(time.Second))
+	if interval < p.MinInterval {
+		interval = p.MinInterval
+	}
+	if interval > p.MaxInterval {
+		interval = p.MaxInterval
+	}
+
+	active := int(math.Ceil(rate * interval.Seconds()))
+	if active > pool {
+		active = pool
+	}
+
+	return active, interval
+}
+
+// NewReport computes the rates of the counters increased in the window ending at now,
+// the time of the simulation
+func NewReport(window time.Duration, counters res.Counters, now time.Time) Report {
+
+	r := Report{
+		Time:     now,
+		Counters: counters,
+	}
+
+	if window > 0 {
+		r.Achieved = float64(counters.Uplinks) / window.Seconds()
+	}
+
+	if counters.JoinRequests > 0 {
+		r.JoinSuccess = float64(counters.JoinAccepts) / float64(counters.JoinRequests)
+	}
+
+	if counters.Uplinks > 0 {
+		r.DownlinkHitRate = float64(counters.Downlinks) / float64(counters.Uplinks)
+	}
+
+	return r
+}
+
+// MarshalJSON of the stage, durations in seconds
+func (s *Stage) MarshalJSON() ([]byte, error) {
+
+	type Alias Stage
+
+	return json.Marshal(&struct {
+		Duration float64 `json:"duration"`
+		Hold     float64 `json:"hold"`
+		Period   float64 `json:"period"`
+		*Alias
+	}{
+		Duration: s.Duration.Seconds(),
+		Hold:     s.Hold.Seconds(),
+		Period:   s.Period.Seconds(),
+		Alias:    (*Alias)(s),
+	})
+}
+
+// UnmarshalJSON of the stage, durations in seconds
+func (s *Stage) UnmarshalJSON(data []byte) error {
+
+	type Alias Stage
+
+	aux := &struct {
+		Duration float64 `json:"duration"`
+		Hold     float64 `json:"hold"`
+		Period   float64 `json:"period"`
+		*Alias
+	}{
+		Alias: (*Alias)(s),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	s.Duration = seconds(aux.Duration)
+	s.Hold = seconds(aux.Hold)
+	s.Period = seconds(aux.Period)
+
+	return nil
+}
+
+// MarshalJSON of the profile, durations in seconds
+func (p *Profile) MarshalJSON() ([]byte, error) {
+
+	type Alias Profile
+
+	return json.Marshal(&struct {
+		Tick        float64 `json:"tick"`
+		MinInterval float64 `json:"minInterval"`
+		MaxInterval float64 `json:"maxInterval"`
+		*Alias
+	}{
+		Tick:        p.Tick.Seconds(),
+		MinInterval: p.MinInterval.Seconds(),
+		MaxInterval: p.MaxInterval.Seconds(),
+		Alias:       (*Alias)(p),
+	})
+}
+
+// UnmarshalJSON of the profile, durations in seconds
+func (p *Profile) UnmarshalJSON(data []byte) error {
+
+	type Alias Profile
+
+	aux := &struct {
+		Tick        float64 `json:"tick"`
+		MinInterval float64 `json:"minInterval"`
+		MaxInterval float64 `json:"maxInterval"`
+		*Alias
+	}{
+		Alias: (*Alias)(p),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	p.Tick = seconds(aux.Tick)
+	p.MinInterval = seconds(aux.MinInterval)
+	p.MaxInterval = seconds(aux.MaxInterval)
+
+	return nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
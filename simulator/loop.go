@@ -0,0 +1,54 @@
+package simulator
+
+// The state of the simulator has a single writer: the requests of the API, the scheduled
+// actions and the background jobs of the run are commands executed one at a time by the
+// loop. A running device owns its own state, the commands change it through Device.Apply
+// and the API reads the snapshot the device takes after each event
+
+// loop executes the commands in order of arrival
+func (s *Simulator) loop() {
+
+	for command := range s.commands {
+		command()
+	}
+
+}
+
+// Do executes f on the loop and waits for it, a panic of f is raised again to the caller.
+// The exported methods of the simulator must be called through Do and never call it
+func (s *Simulator) Do(f func()) {
+	s.doUnless(nil, f)
+}
+
+// doUnless executes f on the loop unless stop is closed first, it reports if f has been
+// executed. A goroutine waited by a command uses it: the command closes stop before waiting
+func (s *Simulator) doUnless(stop <-chan struct{}, f func()) bool {
+
+	done := make(chan struct{})
+	var recovered interface{}
+
+	command := func() {
+
+		defer func() {
+			recovered = recover()
+			close(done)
+		}()
+
+		f()
+	}
+
+	select {
+
+	case s.commands <- command:
+		<-done
+
+	case <-stop:
+		return false
+	}
+
+	if recovered != nil {
+		panic(recovered)
+	}
+
+	return true
+}
@@ -0,0 +1,105 @@
+// Package metrics exports the counters of the frames published on the bus to Prometheus
+package metrics
+
+import (
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	uplinkCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "device_uplink_sent_total",
+		Help: "The total number of uplinks sent",
+	})
+	downlinkCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "device_downlink_received_total",
+		Help: "The total number of downlinks received",
+	})
+	ackTimeoutCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "device_ack_timetou_total",
+		Help: "The total number of ACK timeouts",
+	})
+	joinRequestCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "device_join_request_sent_total",
+		Help: "The total number of JOIN REQUEST sent",
+	})
+	joinAcceptCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "device_join_accept_received_total",
+		Help: "The total number of JOIN ACCEPT accepted",
+	})
+	retransmissionCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "device_retransmission_total",
+		Help: "The total number of confirmed uplinks sent again",
+	})
+	preambleMissedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "device_downlink_preamble_missed_total",
+		Help: "The total number of downlinks missed because the RX window didn't contain enough preamble",
+	})
+	pushDataCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_data_sent_total",
+		Help: "The total number of gateway PUSH DATA",
+	})
+	pullDataCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_pull_data_total",
+		Help: "The total number of gateway PULL DATA",
+	})
+	pushAckCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_push_ack_total",
+		Help: "The total number of gateway PUSH ACK",
+	})
+	pullAckCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_pull_ack_total",
+		Help: "The total number of gateway PULL ACK",
+	})
+	pullRespCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_pull_resp_total",
+		Help: "The total number of gateway PULL RESP",
+	})
+)
+
+// Consume counts the event
+func Consume(e events.Event) {
+
+	switch e.Type {
+
+	case events.TypeUplink:
+		uplinkCounter.Inc()
+
+	case events.TypeDownlink:
+		downlinkCounter.Inc()
+
+	case events.TypeAckTimeout:
+		ackTimeoutCounter.Inc()
+
+	case events.TypeJoinRequest:
+		joinRequestCounter.Inc()
+
+	case events.TypeJoinAccept:
+		joinAcceptCounter.Inc()
+
+	case events.TypeRetransmission:
+		retransmissionCounter.Inc()
+
+	case events.TypeDownlinkMissed:
+		preambleMissedCounter.Inc()
+
+	case events.TypePushData:
+		if data, ok := e.Data.(*events.PushData); ok && data.Forwarded {
+			pushDataCounter.Inc()
+		}
+
+	case events.TypePullData:
+		pullDataCounter.Inc()
+
+	case events.TypePushAck:
+		pushAckCounter.Inc()
+
+	case events.TypePullAck:
+		pullAckCounter.Inc()
+
+	case events.TypePullResp:
+		pullRespCounter.Inc()
+
+	}
+}
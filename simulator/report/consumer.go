@@ -0,0 +1,58 @@
+package report
+
+import (
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/brocaar/lorawan"
+)
+
+// Consume records the frames of the devices and the gateways published on the bus
+func (r *Recorder) Consume(e events.Event) {
+
+	switch data := e.Data.(type) {
+
+	case *events.Uplink:
+		r.Uplink(e.Id, data.DataRate, data.MType == lorawan.ConfirmedDataUp.String(), data.Delivered)
+
+	case *events.Downlink:
+		r.Downlink(e.Id, window(data.Window), data.Latency)
+
+		if data.ACK {
+			r.ConfirmedAck(e.Id)
+		}
+
+	case *events.JoinRequest:
+		r.JoinRequest(e.Id)
+
+	case *events.JoinAccept:
+		r.JoinAccept(e.Id, data.Latency)
+
+	case *events.AckTimeout:
+		r.AckTimeout(e.Id)
+
+	case *events.MACCommand:
+		if data.Direction == events.DirectionDownlink {
+			r.MACCommands(e.Id, 1)
+		}
+
+	case *events.PushData:
+		r.GatewayUplink(e.Id, data.Forwarded)
+
+	case *events.PullResp:
+		r.GatewayDownlink(e.Id)
+
+	}
+}
+
+func window(name string) int {
+
+	switch name {
+	case events.WindowRX1:
+		return WindowRX1
+	case events.WindowRX2:
+		return WindowRX2
+	case events.WindowPing:
+		return WindowPing
+	}
+
+	return WindowClassC
+}
@@ -0,0 +1,176 @@
+package report
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	WindowRX1    = iota // class A/B RX1 and class C RX1
+	WindowRX2           // class A/B RX2
+	WindowPing          // class B ping slots
+	WindowClassC        // class C continuous RX2
+)
+
+// Latency sums the delays of the events
+type Latency struct {
+	Count uint64        `json:"count"`
+	Total time.Duration `json:"total"`
+	Max   time.Duration `json:"max"`
+}
+
+// Windows counts the downlinks received in each receive window
+type Windows struct {
+	RX1    uint64 `json:"rx1"`
+	RX2    uint64 `json:"rx2"`
+	Ping   uint64 `json:"ping"`
+	ClassC uint64 `json:"classC"`
+}
+
+// Device counts the traffic of a device
+type Device struct {
+	UplinksSent      uint64           `json:"uplinksSent"`
+	UplinksDelivered uint64           `json:"uplinksDelivered"` // received by at least a gateway
+	ConfirmedSent    uint64           `json:"confirmedSent"`
+	ConfirmedAcked   uint64           `json:"confirmedAcked"`
+	JoinRequests     uint64           `json:"joinRequests"`
+	JoinAccepts      uint64           `json:"joinAccepts"`
+	Downlinks        Windows          `json:"downlinks"`
+	AckTimeouts      uint64           `json:"ackTimeouts"`
+	MACCommands      uint64           `json:"macCommands"`
+	DataRates        map[uint8]uint64 `json:"dataRates"` // uplinks sent for each data rate
+	DownlinkLatency  Latency          `json:"downlinkLatency"`
+	JoinLatency      Latency          `json:"joinLatency"` // from the first JOIN REQUEST to the JOIN ACCEPT
+}
+
+// Gateway counts the traffic of a gateway
+type Gateway struct {
+	UplinksReceived  uint64 `json:"uplinksReceived"`
+	UplinksForwarded uint64 `json:"uplinksForwarded"` // PUSH DATA sent to the bridge
+	DownlinksSent    uint64 `json:"downlinksSent"`    // PULL RESP received from the bridge
+}
+
+func (l *Latency) Add(delay time.Duration) {
+
+	l.Count++
+	l.Total += delay
+
+	if delay > l.Max {
+		l.Max = delay
+	}
+}
+
+func (l *Latency) Merge(other Latency) {
+
+	l.Count += other.Count
+	l.Total += other.Total
+
+	if other.Max > l.Max {
+		l.Max = other.Max
+	}
+}
+
+// Average returns the average delay, 0 without events
+func (l Latency) Average() time.Duration {
+
+	if l.Count == 0 {
+		return 0
+	}
+
+	return l.Total / time.Duration(l.Count)
+}
+
+func (w *Windows) Add(window int) {
+
+	switch window {
+	case WindowRX1:
+		w.RX1++
+	case WindowRX2:
+		w.RX2++
+	case WindowPing:
+		w.Ping++
+	case WindowClassC:
+		w.ClassC++
+	}
+}
+
+// Total returns the downlinks received in all the windows
+func (w *Windows) Total() uint64 {
+	return w.RX1 + w.RX2 + w.Ping + w.ClassC
+}
+
+func (w *Windows) Merge(other Windows) {
+	w.RX1 += other.RX1
+	w.RX2 += other.RX2
+	w.Ping += other.Ping
+	w.ClassC += other.ClassC
+}
+
+func (d *Device) Merge(other *Device) {
+
+	d.UplinksSent += other.UplinksSent
+	d.UplinksDelivered += other.UplinksDelivered
+	d.ConfirmedSent += other.ConfirmedSent
+	d.ConfirmedAcked += other.ConfirmedAcked
+	d.JoinRequests += other.JoinRequests
+	d.JoinAccepts += other.JoinAccepts
+	d.Downlinks.Merge(other.Downlinks)
+	d.AckTimeouts += other.AckTimeouts
+	d.MACCommands += other.MACCommands
+	d.DownlinkLatency.Merge(other.DownlinkLatency)
+	d.JoinLatency.Merge(other.JoinLatency)
+
+	if d.DataRates == nil {
+		d.DataRates = make(map[uint8]uint64)
+	}
+
+	for dr, n := range other.DataRates {
+		d.DataRates[dr] += n
+	}
+}
+
+func (g *Gateway) Merge(other *Gateway) {
+	g.UplinksReceived += other.UplinksReceived
+	g.UplinksForwarded += other.UplinksForwarded
+	g.DownlinksSent += other.DownlinksSent
+}
+
+// MarshalJSON of the latency, durations in seconds
+func (l Latency) MarshalJSON() ([]byte, error) {
+
+	return json.Marshal(&struct {
+		Count   uint64  `json:"count"`
+		Total   float64 `json:"total"`
+		Average float64 `json:"average"`
+		Max     float64 `json:"max"`
+	}{
+		Count:   l.Count,
+		Total:   l.Total.Seconds(),
+		Average: l.Average().Seconds(),
+		Max:     l.Max.Seconds(),
+	})
+}
+
+// UnmarshalJSON of the latency, durations in seconds
+func (l *Latency) UnmarshalJSON(data []byte) error {
+
+	aux := struct {
+		Count uint64  `json:"count"`
+		Total float64 `json:"total"`
+		Max   float64 `json:"max"`
+	}{}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	l.Count = aux.Count
+	l.Total = seconds(aux.Total)
+	l.Max = seconds(aux.Max)
+
+	return nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
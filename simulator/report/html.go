@@ -0,0 +1,98 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"time"
+)
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent":   percent,
+	"seconds":   func(d time.Duration) string { return fmt.Sprintf("%.3f s", d.Seconds()) },
+	"dataRates": dataRates,
+	"date":      func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>LWN Simulator - Run {{.Run}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th { background: #eee; }
+td:first-child, th:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>Run {{.Run}}</h1>
+<p>From {{date .From}} to {{date .To}}</p>
+<h2>Devices</h2>
+<table>
+<tr>
+<th>Device</th><th>Uplinks</th><th>Delivered</th><th>PDR</th><th>Confirmed</th><th>ACK ratio</th>
+<th>Join requests</th><th>Joins</th><th>Join success</th><th>Join latency</th>
+<th>RX1</th><th>RX2</th><th>Ping</th><th>Class C</th><th>Downlink hit rate</th><th>Downlink latency</th>
+<th>ACK timeouts</th><th>MAC commands</th><th>Data rates</th>
+</tr>
+{{range .Devices}}{{template "device" .}}{{end}}
+{{template "device" .Total}}
+</table>
+<h2>Gateways</h2>
+<table>
+<tr><th>Gateway</th><th>Uplinks received</th><th>Uplinks forwarded</th><th>Downlinks sent</th></tr>
+{{range .Gateways}}<tr><td>{{.Name}}</td><td>{{.UplinksReceived}}</td><td>{{.UplinksForwarded}}</td><td>{{.DownlinksSent}}</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+{{define "device"}}<tr>
+<td>{{.Name}}</td><td>{{.UplinksSent}}</td><td>{{.UplinksDelivered}}</td><td>{{percent .PDR}}</td>
+<td>{{.ConfirmedSent}}</td><td>{{percent .AckRatio}}</td>
+<td>{{.JoinRequests}}</td><td>{{.JoinAccepts}}</td><td>{{percent .JoinSuccess}}</td><td>{{seconds .JoinLatency.Average}}</td>
+<td>{{.Downlinks.RX1}}</td><td>{{.Downlinks.RX2}}</td><td>{{.Downlinks.Ping}}</td><td>{{.Downlinks.ClassC}}</td>
+<td>{{percent .DownlinkHitRate}}</td><td>{{seconds .DownlinkLatency.Average}}</td>
+<td>{{.AckTimeouts}}</td><td>{{.MACCommands}}</td><td>{{dataRates .DataRates}}</td>
+</tr>
+{{end}}`))
+
+// HTML renders the report as a page with a table of the devices and one of the gateways
+func (r *Report) HTML() ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	if err := page.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func percent(ratio float64) string {
+	return fmt.Sprintf("%.1f%%", ratio*100)
+}
+
+// dataRates returns the uplinks of each data rate, "DR0: 10, DR5: 3"
+func dataRates(rates map[uint8]uint64) string {
+
+	var drs []int
+	for dr := range rates {
+		drs = append(drs, int(dr))
+	}
+
+	sort.Ints(drs)
+
+	s := ""
+	for i, dr := range drs {
+
+		if i > 0 {
+			s += ", "
+		}
+
+		s += fmt.Sprintf("DR%v: %v", dr, rates[uint8(dr)])
+	}
+
+	return s
+}
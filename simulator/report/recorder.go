@@ -0,0 +1,418 @@
+package report
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/clock"
+)
+
+const (
+	// DefaultResolution is the width of the time buckets of a run
+	DefaultResolution = time.Minute
+
+	// MaxBuckets bounds the time series of a run: beyond it two buckets are merged in
+	// one, the resolution doubles
+	MaxBuckets = 10080
+)
+
+// Bucket holds the counters of the events of all the devices and of all the gateways
+// in a time slot
+type Bucket struct {
+	Start    time.Time `json:"start"`
+	Devices  Device    `json:"devices"`
+	Gateways Gateway   `json:"gateways"`
+}
+
+// Run is the recording of a simulation, from the start to the stop: the counters of
+// each component over the whole run and the traffic of the fleet over time
+type Run struct {
+	Id           string           `json:"id"`
+	Started      time.Time        `json:"started"`
+	Stopped      time.Time        `json:"stopped"` // zero while running
+	Resolution   time.Duration    `json:"resolution"`
+	DeviceNames  map[int]string   `json:"deviceNames"`
+	GatewayNames map[int]string   `json:"gatewayNames"`
+	Devices      map[int]*Device  `json:"devices"`
+	Gateways     map[int]*Gateway `json:"gateways"`
+	Buckets      []*Bucket        `json:"buckets"`
+}
+
+type deviceCounters struct {
+	Device
+	mutex sync.Mutex
+}
+
+type gatewayCounters struct {
+	Gateway
+	mutex sync.Mutex
+}
+
+// Recorder collects the events of the devices and the gateways of the running simulation.
+// The counters of a component have their own lock, the recorder's one is held only to
+// look them up. A nil recorder discards the events
+type Recorder struct {
+	run      Run // without the counters of the components, they are in devices and gateways
+	devices  map[int]*deviceCounters
+	gateways map[int]*gatewayCounters
+	clock    clock.Clock
+	mutex    sync.Mutex
+
+	seriesMutex sync.Mutex // Buckets and Resolution of run
+}
+
+// NewRecorder starts the recording of a run in the time of the clock
+func NewRecorder(resolution time.Duration, c clock.Clock) *Recorder {
+
+	if resolution <= 0 {
+		resolution = DefaultResolution
+	}
+
+	now := c.Now()
+
+	return &Recorder{
+		clock:    c,
+		devices:  make(map[int]*deviceCounters),
+		gateways: make(map[int]*gatewayCounters),
+		run: Run{
+			Id:           now.Format("20060102-150405"),
+			Started:      now,
+			Resolution:   resolution,
+			DeviceNames:  make(map[int]string),
+			GatewayNames: make(map[int]string),
+		},
+	}
+}
+
+// Id returns the id of the run, the time of the start
+func (r *Recorder) Id() string {
+	return r.run.Id
+}
+
+// Uplink records an uplink sent by the device
+func (r *Recorder) Uplink(Id int, dataRate uint8, confirmed bool, delivered bool) {
+
+	r.device(Id, func(d *Device) {
+
+		d.UplinksSent++
+		d.DataRates[dataRate]++
+
+		if delivered {
+			d.UplinksDelivered++
+		}
+
+		if confirmed {
+			d.ConfirmedSent++
+		}
+	})
+}
+
+// ConfirmedAck records the ACK of a confirmed uplink
+func (r *Recorder) ConfirmedAck(Id int) {
+	r.device(Id, func(d *Device) { d.ConfirmedAcked++ })
+}
+
+func (r *Recorder) JoinRequest(Id int) {
+	r.device(Id, func(d *Device) { d.JoinRequests++ })
+}
+
+// JoinAccept records a join, latency is the time since the first JOIN REQUEST
+func (r *Recorder) JoinAccept(Id int, latency time.Duration) {
+
+	r.device(Id, func(d *Device) {
+		d.JoinAccepts++
+		d.JoinLatency.Add(latency)
+	})
+}
+
+// Downlink records a downlink received in the window, latency is the time since the uplink.
+// The downlinks of the ping slots and of class C have no uplink, nor latency
+func (r *Recorder) Downlink(Id int, window int, latency time.Duration) {
+
+	r.device(Id, func(d *Device) {
+
+		d.Downlinks.Add(window)
+
+		if window != WindowPing && window != WindowClassC {
+			d.DownlinkLatency.Add(latency)
+		}
+	})
+}
+
+func (r *Recorder) AckTimeout(Id int) {
+	r.device(Id, func(d *Device) { d.AckTimeouts++ })
+}
+
+// MACCommands records the MAC commands executed by the device
+func (r *Recorder) MACCommands(Id int, n int) {
+
+	if n == 0 {
+		return
+	}
+
+	r.device(Id, func(d *Device) { d.MACCommands += uint64(n) })
+}
+
+// GatewayUplink records an uplink received by the gateway, forwarded if sent to the bridge
+func (r *Recorder) GatewayUplink(Id int, forwarded bool) {
+
+	r.gateway(Id, func(g *Gateway) {
+
+		g.UplinksReceived++
+
+		if forwarded {
+			g.UplinksForwarded++
+		}
+	})
+}
+
+func (r *Recorder) GatewayDownlink(Id int) {
+	r.gateway(Id, func(g *Gateway) { g.DownlinksSent++ })
+}
+
+// Stop ends the recording, names are the ones of the components at the stop
+func (r *Recorder) Stop(devices map[int]string, gateways map[int]string) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.run.Stopped = r.clock.Now()
+	r.run.DeviceNames = devices
+	r.run.GatewayNames = gateways
+}
+
+// SetNames sets the names of the components, a running recording has only the ids
+func (r *Recorder) SetNames(devices map[int]string, gateways map[int]string) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.run.DeviceNames = devices
+	r.run.GatewayNames = gateways
+}
+
+// Run returns a copy of the recording. The recorder's lock is held only to collect the
+// counters of the components, each one is copied under its own lock
+func (r *Recorder) Run() Run {
+
+	r.mutex.Lock()
+
+	run := Run{
+		Id:           r.run.Id,
+		Started:      r.run.Started,
+		Stopped:      r.run.Stopped,
+		DeviceNames:  r.run.DeviceNames,
+		GatewayNames: r.run.GatewayNames,
+	}
+
+	devices := make(map[int]*deviceCounters, len(r.devices))
+	for id, d := range r.devices {
+		devices[id] = d
+	}
+
+	gateways := make(map[int]*gatewayCounters, len(r.gateways))
+	for id, g := range r.gateways {
+		gateways[id] = g
+	}
+
+	r.mutex.Unlock()
+
+	run.Devices = make(map[int]*Device, len(devices))
+	for id, d := range devices {
+
+		counters := &Device{}
+
+		d.mutex.Lock()
+		counters.Merge(&d.Device)
+		d.mutex.Unlock()
+
+		run.Devices[id] = counters
+	}
+
+	run.Gateways = make(map[int]*Gateway, len(gateways))
+	for id, g := range gateways {
+
+		counters := &Gateway{}
+
+		g.mutex.Lock()
+		counters.Merge(&g.Gateway)
+		g.mutex.Unlock()
+
+		run.Gateways[id] = counters
+	}
+
+	r.seriesMutex.Lock()
+
+	run.Resolution = r.run.Resolution
+	run.Buckets = make([]*Bucket, len(r.run.Buckets))
+	for i, b := range r.run.Buckets {
+		run.Buckets[i] = b.copy()
+	}
+
+	r.seriesMutex.Unlock()
+
+	return run
+}
+
+// Report returns the report of the events in [from, to), zero values are the bounds of the run
+func (r *Recorder) Report(from time.Time, to time.Time) Report {
+
+	run := r.Run() // a running recording ends at the time of the clock
+
+	return run.Report(from, to, r.clock.Now())
+}
+
+func (r *Recorder) device(Id int, update func(d *Device)) {
+
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+
+	d, ok := r.devices[Id]
+	if !ok {
+		d = &deviceCounters{Device: Device{DataRates: make(map[uint8]uint64)}}
+		r.devices[Id] = d
+	}
+
+	r.mutex.Unlock()
+
+	d.mutex.Lock()
+	update(&d.Device)
+	d.mutex.Unlock()
+
+	r.seriesMutex.Lock()
+	update(&r.bucket().Devices)
+	r.seriesMutex.Unlock()
+}
+
+func (r *Recorder) gateway(Id int, update func(g *Gateway)) {
+
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+
+	g, ok := r.gateways[Id]
+	if !ok {
+		g = &gatewayCounters{}
+		r.gateways[Id] = g
+	}
+
+	r.mutex.Unlock()
+
+	g.mutex.Lock()
+	update(&g.Gateway)
+	g.mutex.Unlock()
+
+	r.seriesMutex.Lock()
+	update(&r.bucket().Gateways)
+	r.seriesMutex.Unlock()
+}
+
+// bucket returns the bucket of the current time slot, the slots without events have none.
+// Called with seriesMutex locked
+func (r *Recorder) bucket() *Bucket {
+
+	slot := clock.Since(r.clock, r.run.Started) / r.run.Resolution
+	start := r.run.Started.Add(slot * r.run.Resolution)
+
+	n := len(r.run.Buckets)
+	if n > 0 && !r.run.Buckets[n-1].Start.Before(start) {
+		return r.run.Buckets[n-1]
+	}
+
+	if n >= MaxBuckets {
+		r.coarsen()
+		return r.bucket()
+	}
+
+	b := &Bucket{
+		Start:   start,
+		Devices: Device{DataRates: make(map[uint8]uint64)},
+	}
+
+	r.run.Buckets = append(r.run.Buckets, b)
+
+	return b
+}
+
+// coarsen doubles the resolution of the time series, the buckets in the same slot are merged
+func (r *Recorder) coarsen() {
+
+	r.run.Resolution *= 2
+
+	buckets := r.run.Buckets[:0]
+
+	for _, b := range r.run.Buckets {
+
+		slot := b.Start.Sub(r.run.Started) / r.run.Resolution
+		start := r.run.Started.Add(slot * r.run.Resolution)
+
+		n := len(buckets)
+		if n > 0 && buckets[n-1].Start.Equal(start) {
+			buckets[n-1].Devices.Merge(&b.Devices)
+			buckets[n-1].Gateways.Merge(&b.Gateways)
+			continue
+		}
+
+		b.Start = start
+		buckets = append(buckets, b)
+	}
+
+	for i := len(buckets); i < len(r.run.Buckets); i++ {
+		r.run.Buckets[i] = nil
+	}
+
+	r.run.Buckets = buckets
+}
+
+func (b *Bucket) copy() *Bucket {
+
+	c := &Bucket{
+		Start:    b.Start,
+		Gateways: b.Gateways,
+	}
+
+	c.Devices.Merge(&b.Devices)
+
+	return c
+}
+
+// MarshalJSON of the run, resolution in seconds
+func (r *Run) MarshalJSON() ([]byte, error) {
+
+	type Alias Run
+
+	return json.Marshal(&struct {
+		Resolution float64 `json:"resolution"`
+		*Alias
+	}{
+		Resolution: r.Resolution.Seconds(),
+		Alias:      (*Alias)(r),
+	})
+}
+
+// UnmarshalJSON of the run, resolution in seconds
+func (r *Run) UnmarshalJSON(data []byte) error {
+
+	type Alias Run
+
+	aux := &struct {
+		Resolution float64 `json:"resolution"`
+		*Alias
+	}{
+		Alias: (*Alias)(r),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.Resolution = seconds(aux.Resolution)
+
+	return nil
+}
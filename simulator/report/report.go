@@ -0,0 +1,121 @@
+package report
+
+import (
+	"sort"
+	"time"
+)
+
+// DeviceReport is the traffic of a device with the derived rates
+type DeviceReport struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Device
+
+	PDR             float64 `json:"pdr"`             // uplinks delivered / uplinks sent
+	AckRatio        float64 `json:"ackRatio"`        // confirmed acked / confirmed sent
+	JoinSuccess     float64 `json:"joinSuccess"`     // join accepts / join requests
+	DownlinkHitRate float64 `json:"downlinkHitRate"` // downlinks / uplinks sent
+}
+
+// GatewayReport is the traffic of a gateway
+type GatewayReport struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Gateway
+}
+
+// Report of a run over a time range
+type Report struct {
+	Run      string          `json:"run"`
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Total    DeviceReport    `json:"total"` // all the devices
+	Devices  []DeviceReport  `json:"devices"`
+	Gateways []GatewayReport `json:"gateways"`
+}
+
+// Report returns the report of [from, to), zero values are the bounds of the run, a running
+// one ends at now, the time of the simulation. The devices and the gateways are detailed
+// over the whole run, a shorter range has only the total of the fleet, from the buckets
+// overlapping it
+func (r *Run) Report(from time.Time, to time.Time, now time.Time) Report {
+
+	end := r.Stopped
+	if end.IsZero() {
+		end = now
+	}
+
+	if from.IsZero() || from.Before(r.Started) {
+		from = r.Started
+	}
+
+	if to.IsZero() || to.After(end) {
+		to = end
+	}
+
+	report := Report{
+		Run:      r.Id,
+		From:     from,
+		To:       to,
+		Devices:  []DeviceReport{},
+		Gateways: []GatewayReport{},
+	}
+
+	total := Device{DataRates: make(map[uint8]uint64)}
+
+	if from.Equal(r.Started) && to.Equal(end) {
+
+		for id, counters := range r.Devices {
+			total.Merge(counters)
+			report.Devices = append(report.Devices, newDeviceReport(id, r.DeviceNames[id], *counters))
+		}
+
+		for id, counters := range r.Gateways {
+			report.Gateways = append(report.Gateways, GatewayReport{
+				Id:      id,
+				Name:    r.GatewayNames[id],
+				Gateway: *counters,
+			})
+		}
+
+	} else {
+
+		for _, b := range r.Buckets {
+			if b.Start.Before(to) && b.Start.Add(r.Resolution).After(from) {
+				total.Merge(&b.Devices)
+			}
+		}
+
+	}
+
+	report.Total = newDeviceReport(-1, "Total", total)
+
+	sort.Slice(report.Devices, func(i, j int) bool { return report.Devices[i].Id < report.Devices[j].Id })
+	sort.Slice(report.Gateways, func(i, j int) bool { return report.Gateways[i].Id < report.Gateways[j].Id })
+
+	return report
+}
+
+func newDeviceReport(Id int, Name string, counters Device) DeviceReport {
+
+	d := DeviceReport{
+		Id:     Id,
+		Name:   Name,
+		Device: counters,
+	}
+
+	if d.UplinksSent > 0 {
+		d.PDR = float64(d.UplinksDelivered) / float64(d.UplinksSent)
+		d.DownlinkHitRate = float64(d.Downlinks.Total()) / float64(d.UplinksSent)
+	}
+
+	if d.ConfirmedSent > 0 {
+		d.AckRatio = float64(d.ConfirmedAcked) / float64(d.ConfirmedSent)
+	}
+
+	if d.JoinRequests > 0 {
+		d.JoinSuccess = float64(d.JoinAccepts) / float64(d.JoinRequests)
+	}
+
+	return d
+}
@@ -0,0 +1,164 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/report"
+	"github.com/arslab/lwnsimulator/simulator/util"
+)
+
+const runSuffix = ".run.json"
+
+// startReport starts the recording of the run
+func (s *Simulator) startReport() {
+	s.Resources.Report = report.NewRecorder(time.Duration(s.ReportResolution)*time.Second, s.Resources.Clock)
+	s.Resources.Events.Attach("report", s.Resources.Report)
+}
+
+// saveReport stops the recording and saves the report of the whole run as JSON and HTML,
+// the recording is saved too for the reports over a time range
+func (s *Simulator) saveReport() {
+
+	recorder := s.Resources.Report
+	if recorder == nil {
+		return
+	}
+
+	s.Resources.Events.Detach("report")
+
+	devices, gateways := s.componentNames()
+	recorder.Stop(devices, gateways)
+
+	run := recorder.Run()
+	rep := run.Report(time.Time{}, time.Time{}, s.Resources.Clock.Now())
+
+	pathDir, err := reportsPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.saveComponent(pathDir+"/"+run.Id+runSuffix, &run)
+	s.saveComponent(pathDir+"/"+run.Id+".json", &rep)
+
+	html, err := rep.HTML()
+	if err != nil {
+		s.Print("", err, util.PrintBoth)
+		return
+	}
+
+	if err := util.WriteConfigFile(pathDir+"/"+run.Id+".html", html); err != nil {
+		s.Print("", err, util.PrintBoth)
+		return
+	}
+
+	s.Print("Report of run "+run.Id+" saved", nil, util.PrintBoth)
+}
+
+// GetReport returns the report of the last run over [from, to), zero values are its bounds
+func (s *Simulator) GetReport(from time.Time, to time.Time) (report.Report, error) {
+
+	recorder := s.Resources.Report
+	if recorder == nil {
+		return report.Report{}, errors.New("No run recorded")
+	}
+
+	if s.State == util.Running {
+		recorder.SetNames(s.componentNames())
+	}
+
+	return recorder.Report(from, to), nil
+}
+
+// GetReports returns the ids of the saved runs, the oldest first
+func (s *Simulator) GetReports() []string {
+
+	ids := []string{}
+
+	pathDir, err := reportsPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(pathDir)
+	if err != nil {
+		s.Print("", err, util.PrintOnlyConsole)
+		return ids
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), runSuffix) {
+			ids = append(ids, strings.TrimSuffix(file.Name(), runSuffix))
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// GetRunReport returns the report of a saved run over [from, to), zero values are its bounds
+func (s *Simulator) GetRunReport(Id string, from time.Time, to time.Time) (report.Report, error) {
+
+	if recorder := s.Resources.Report; recorder != nil && s.State == util.Running {
+		if recorder.Id() == Id { // not saved yet
+			return s.GetReport(from, to)
+		}
+	}
+
+	if Id == "" || filepath.Base(Id) != Id {
+		return report.Report{}, errors.New("Invalid run")
+	}
+
+	pathDir, err := reportsPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bytes, err := ioutil.ReadFile(pathDir + "/" + Id + runSuffix)
+	if err != nil {
+		return report.Report{}, errors.New("Run " + Id + " doesn't exist")
+	}
+
+	var run report.Run
+	if err := json.Unmarshal(bytes, &run); err != nil {
+		return report.Report{}, err
+	}
+
+	return run.Report(from, to, s.Resources.Clock.Now()), nil
+}
+
+// componentNames returns the names of the devices and the gateways by id
+func (s *Simulator) componentNames() (map[int]string, map[int]string) {
+
+	devices := make(map[int]string)
+	for id, d := range s.Devices {
+		devices[id] = d.Info.Name
+	}
+
+	gateways := make(map[int]string)
+	for id, g := range s.Gateways {
+		gateways[id] = g.Info.Name
+	}
+
+	return devices, gateways
+}
+
+func reportsPath() (string, error) {
+
+	pathDir, err := util.GetPath()
+	if err != nil {
+		return "", err
+	}
+
+	pathDir += "/reports"
+
+	return pathDir, os.MkdirAll(pathDir, os.ModePerm)
+}
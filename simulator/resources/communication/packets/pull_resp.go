@@ -59,6 +59,22 @@ func GetInfoPullResp(pullResp []byte) (*lorawan.PHYPayload, *uint32, error) {
 
 }
 
+//GetTmstPullResp returns the timestamp requested for the transmission, nil if immediate
+func GetTmstPullResp(pullResp []byte) *uint32 {
+
+	var packet PullRespPacket
+
+	if err := packet.UnmarshalBinary(pullResp); err != nil {
+		return nil
+	}
+
+	if packet.Payload.TXPK.Imme {
+		return nil
+	}
+
+	return packet.Payload.TXPK.Tmst
+}
+
 func (p *PullRespPacket) UnmarshalBinary(data []byte) error {
 
 	if len(data) < MinLenPullResp {
@@ -1,16 +1,20 @@
 package resources
 
 import (
-	"sync"
+	"context"
 
-	socketio "github.com/googollee/go-socket.io"
+	"github.com/arslab/lwnsimulator/simulator/clock"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/arslab/lwnsimulator/simulator/report"
+	"github.com/arslab/lwnsimulator/simulator/scheduler"
 )
 
 type Resources struct {
-	ExitGroup sync.WaitGroup `json:"-"`
-	WebSocket socketio.Conn  `json:"-"`
-}
-
-func (r *Resources) AddWebSocket(WebSocket *socketio.Conn) {
-	r.WebSocket = *WebSocket
+	Context   context.Context      `json:"-"` // lifetime of the run, the components derive theirs from it
+	Events    *events.Bus          `json:"-"` // events of the devices and the gateways
+	Triggers  Triggers             `json:"-"`
+	Stats     Stats                `json:"-"`
+	Report    *report.Recorder     `json:"-"` // recording of the current run, nil before the first
+	Scheduler *scheduler.Scheduler `json:"-"` // timed events of the devices
+	Clock     clock.Clock          `json:"-"` // time of the simulation, wall or virtual
 }
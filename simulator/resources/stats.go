@@ -0,0 +1,76 @@
+package resources
+
+import (
+	"sync/atomic"
+
+	"github.com/arslab/lwnsimulator/simulator/events"
+)
+
+// Stats counts the traffic of all the devices since the start
+type Stats struct {
+	uplinks      uint64
+	downlinks    uint64
+	joinRequests uint64
+	joinAccepts  uint64
+}
+
+// Counters is a snapshot of the stats
+type Counters struct {
+	Uplinks      uint64 `json:"uplinks"`
+	Downlinks    uint64 `json:"downlinks"`
+	JoinRequests uint64 `json:"joinRequests"`
+	JoinAccepts  uint64 `json:"joinAccepts"`
+}
+
+func (s *Stats) Uplink() {
+	atomic.AddUint64(&s.uplinks, 1)
+}
+
+func (s *Stats) Downlink() {
+	atomic.AddUint64(&s.downlinks, 1)
+}
+
+func (s *Stats) JoinRequest() {
+	atomic.AddUint64(&s.joinRequests, 1)
+}
+
+func (s *Stats) JoinAccept() {
+	atomic.AddUint64(&s.joinAccepts, 1)
+}
+
+// Get returns the current counters
+func (s *Stats) Get() Counters {
+
+	return Counters{
+		Uplinks:      atomic.LoadUint64(&s.uplinks),
+		Downlinks:    atomic.LoadUint64(&s.downlinks),
+		JoinRequests: atomic.LoadUint64(&s.joinRequests),
+		JoinAccepts:  atomic.LoadUint64(&s.joinAccepts),
+	}
+}
+
+// Sub returns the counters increased since previous
+func (c Counters) Sub(previous Counters) Counters {
+
+	return Counters{
+		Uplinks:      c.Uplinks - previous.Uplinks,
+		Downlinks:    c.Downlinks - previous.Downlinks,
+		JoinRequests: c.JoinRequests - previous.JoinRequests,
+		JoinAccepts:  c.JoinAccepts - previous.JoinAccepts,
+	}
+}
+
+// Consume counts the frames published on the bus
+func (s *Stats) Consume(e events.Event) {
+
+	switch e.Type {
+	case events.TypeUplink:
+		s.Uplink()
+	case events.TypeDownlink:
+		s.Downlink()
+	case events.TypeJoinRequest:
+		s.JoinRequest()
+	case events.TypeJoinAccept:
+		s.JoinAccept()
+	}
+}
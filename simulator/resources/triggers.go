@@ -0,0 +1,48 @@
+package resources
+
+import "sync"
+
+// Triggers routes the uplinks of a device to the devices that react to them
+type Triggers struct {
+	mutex       sync.Mutex
+	subscribers map[string]map[int]func() // device's name -> subscriber's id
+}
+
+// Subscribe calls trigger whenever the device Name sends an uplink
+func (t *Triggers) Subscribe(Name string, Id int, trigger func()) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.subscribers == nil {
+		t.subscribers = make(map[string]map[int]func())
+	}
+
+	if t.subscribers[Name] == nil {
+		t.subscribers[Name] = make(map[int]func())
+	}
+
+	t.subscribers[Name][Id] = trigger
+}
+
+// Unsubscribe removes all subscriptions of the device Id
+func (t *Triggers) Unsubscribe(Id int) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for name := range t.subscribers {
+		delete(t.subscribers[name], Id)
+	}
+}
+
+// Notify signals the uplink of the device Name
+func (t *Triggers) Notify(Name string) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, trigger := range t.subscribers[Name] {
+		trigger()
+	}
+}
@@ -0,0 +1,231 @@
+package scheduler
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/arslab/lwnsimulator/simulator/clock"
+)
+
+// Scheduler fires the timed events of all the components with a single goroutine and a
+// single timer: a component waiting its next event has no goroutine and no timer of its own
+type Scheduler struct {
+	queue queue
+	seq   uint64 // events at the same time fire in order of scheduling
+	wake  chan struct{}
+	mutex sync.Mutex
+
+	virtual clock.Clock // fires the tasks in its own time, nil for the wall clock
+}
+
+// Task is an event in the queue
+type Task struct {
+	at    time.Time
+	seq   uint64
+	run   func()
+	index int // position in the queue, -1 once fired or cancelled
+
+	timer clock.Timer // task of the virtual clock
+}
+
+// Setup returns a running scheduler
+func Setup() *Scheduler {
+
+	s := &Scheduler{
+		wake: make(chan struct{}, 1),
+	}
+
+	go s.loop()
+
+	return s
+}
+
+// SetClock sets the time of the tasks: a virtual clock fires them in its own time.
+// Only the tasks scheduled later are affected
+func (s *Scheduler) SetClock(c clock.Clock) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.virtual = nil
+	if c.Virtual() {
+		s.virtual = c
+	}
+}
+
+// At schedules run at the given time, run is called on the scheduler's goroutine and must not block
+func (s *Scheduler) At(at time.Time, run func()) *Task {
+
+	s.mutex.Lock()
+
+	if s.virtual != nil {
+
+		t := &Task{index: -1}
+		t.timer = s.virtual.AfterFunc(clock.Until(s.virtual, at), run)
+
+		s.mutex.Unlock()
+
+		return t
+	}
+
+	s.seq++
+	t := &Task{
+		at:  at,
+		seq: s.seq,
+		run: run,
+	}
+
+	heap.Push(&s.queue, t)
+	first := t.index == 0
+
+	s.mutex.Unlock()
+
+	if first { // the timer must be brought forward
+		s.signal()
+	}
+
+	return t
+}
+
+// After schedules run after delay
+func (s *Scheduler) After(delay time.Duration, run func()) *Task {
+
+	s.mutex.Lock()
+	virtual := s.virtual
+	s.mutex.Unlock()
+
+	if virtual != nil {
+		return s.At(virtual.Now().Add(delay), run)
+	}
+
+	return s.At(time.Now().Add(delay), run)
+}
+
+// Cancel removes the task, it returns false if it has already fired. A nil task is ignored
+func (s *Scheduler) Cancel(t *Task) bool {
+
+	if t == nil {
+		return false
+	}
+
+	if t.timer != nil {
+		return t.timer.Stop()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if t.index < 0 {
+		return false
+	}
+
+	heap.Remove(&s.queue, t.index)
+
+	return true
+}
+
+// Len returns the tasks waiting
+func (s *Scheduler) Len() int {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.queue)
+}
+
+func (s *Scheduler) signal() {
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+}
+
+func (s *Scheduler) loop() {
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	for {
+
+		s.mutex.Lock()
+
+		now := time.Now()
+
+		var due []*Task
+		for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+			due = append(due, heap.Pop(&s.queue).(*Task))
+		}
+
+		wait := time.Duration(-1) // empty queue, only a new task wakes up the loop
+		if len(s.queue) > 0 {
+			wait = s.queue[0].at.Sub(now)
+		}
+
+		s.mutex.Unlock()
+
+		for _, t := range due {
+			t.run()
+		}
+
+		if wait >= 0 {
+			timer.Reset(wait)
+		}
+
+		select {
+
+		case <-timer.C:
+			break
+
+		case <-s.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// queue is a min-heap of the tasks by time
+type queue []*Task
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+
+	return q[i].at.Before(q[j].at)
+}
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x interface{}) {
+	t := x.(*Task)
+	t.index = len(*q)
+	*q = append(*q, t)
+}
+
+func (q *queue) Pop() interface{} {
+
+	old := *q
+	n := len(old)
+
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+
+	*q = old[:n-1]
+
+	return t
+}
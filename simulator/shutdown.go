@@ -0,0 +1,104 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arslab/lwnsimulator/models"
+	"github.com/arslab/lwnsimulator/simulator/util"
+)
+
+// DefaultStopTimeout is the time the components have to exit when they are turned off
+const DefaultStopTimeout = 10 * time.Second
+
+// exiting is a component turned off and not yet exited
+type exiting struct {
+	name    string
+	gateway bool
+	done    <-chan struct{}
+}
+
+// startContext creates the context of the run, cancelled at the stop
+func (s *Simulator) startContext() {
+	s.Resources.Context, s.cancelRun = context.WithCancel(context.Background())
+}
+
+// cancelContext interrupts the waits of all the components at once
+func (s *Simulator) cancelContext() {
+
+	if s.cancelRun != nil {
+		s.cancelRun()
+		s.cancelRun = nil
+	}
+}
+
+func (s *Simulator) stopTimeout() time.Duration {
+
+	if s.StopTimeout <= 0 {
+		return DefaultStopTimeout
+	}
+
+	return time.Duration(s.StopTimeout) * time.Second
+}
+
+// waitExit waits the components until the stop timeout and reports the ones still running
+func (s *Simulator) waitExit(components []exiting) models.StopReport {
+
+	start := time.Now()
+
+	report := models.StopReport{
+		Time:     start,
+		Devices:  []string{},
+		Gateways: []string{},
+	}
+
+	timer := time.NewTimer(s.stopTimeout())
+	defer timer.Stop()
+
+	for i, c := range components {
+
+		select {
+
+		case <-c.done:
+			continue
+
+		case <-timer.C:
+		}
+
+		report.TimedOut = true
+
+		for _, c := range components[i:] {
+
+			select {
+
+			case <-c.done:
+
+			default:
+				if c.gateway {
+					report.Gateways = append(report.Gateways, c.name)
+				} else {
+					report.Devices = append(report.Devices, c.name)
+				}
+			}
+		}
+
+		break
+	}
+
+	report.Duration = time.Since(start).Seconds()
+
+	if report.TimedOut {
+		names := append(append([]string{}, report.Devices...), report.Gateways...)
+		err := fmt.Errorf("%v components didn't exit within %v: %v", len(names), s.stopTimeout(), strings.Join(names, ", "))
+		s.Print("", err, util.PrintBoth)
+	}
+
+	return report
+}
+
+// GetStopReport returns the outcome of the last stop
+func (s *Simulator) GetStopReport() models.StopReport {
+	return s.stopReport
+}
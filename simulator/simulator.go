@@ -1,18 +1,26 @@
 package simulator
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/arslab/lwnsimulator/codes"
+	"github.com/arslab/lwnsimulator/models"
+	"github.com/arslab/lwnsimulator/simulator/clock"
 	dev "github.com/arslab/lwnsimulator/simulator/components/device"
 	f "github.com/arslab/lwnsimulator/simulator/components/forwarder"
 	mfw "github.com/arslab/lwnsimulator/simulator/components/forwarder/models"
 	gw "github.com/arslab/lwnsimulator/simulator/components/gateway"
 	c "github.com/arslab/lwnsimulator/simulator/console"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/simulator/load"
 	res "github.com/arslab/lwnsimulator/simulator/resources"
 	"github.com/arslab/lwnsimulator/simulator/util"
 	"github.com/arslab/lwnsimulator/socket"
@@ -21,20 +29,50 @@ import (
 
 // Simulator is a model
 type Simulator struct {
-	State                 uint8               `json:"-"`
-	Devices               map[int]*dev.Device `json:"-"`
-	ActiveDevices         map[int]int         `json:"-"`
-	ActiveGateways        map[int]int         `json:"-"`
-	ComponentsInactiveTmp int                 `json:"-"`
-	Gateways              map[int]*gw.Gateway `json:"-"`
-	Forwarder             f.Forwarder         `json:"-"`
-	NextIDDev             int                 `json:"nextIDDev"`
-	NextIDGw              int                 `json:"nextIDGw"`
-	BridgeAddress         string              `json:"bridgeAddress"`
-	Resources             res.Resources       `json:"-"`
-	Console               c.Console           `json:"-"`
+	State            uint8                     `json:"-"`
+	Devices          map[int]*dev.Device       `json:"-"`
+	ActiveDevices    map[int]int               `json:"-"`
+	ActiveGateways   map[int]int               `json:"-"`
+	Gateways         map[int]*gw.Gateway       `json:"-"`
+	Forwarder        f.Forwarder               `json:"-"`
+	NextIDDev        int                       `json:"nextIDDev"`
+	NextIDGw         int                       `json:"nextIDGw"`
+	BridgeAddress    string                    `json:"bridgeAddress"`
+	RampUp           float64                   `json:"rampUp"`           // devices turned on per second, 0 all together
+	Checkpoint       int                       `json:"checkpoint"`       // seconds between saves while running, 0 default, negative disabled
+	ReportResolution int                       `json:"reportResolution"` // seconds of the time buckets of the run reports, 0 default
+	Clock            clock.Config              `json:"clock"`
+	StopTimeout      int                       `json:"stopTimeout"` // seconds the components have to exit, 0 default
+	Resources        res.Resources             `json:"-"`
+	Console          c.Console                 `json:"-"`
+	Templates        map[string]fleet.Template `json:"-"`
+	Groups           map[string]fleet.Group    `json:"-"`
+
+	stopRampUp chan struct{}
+	rampUpDone chan struct{}
+
+	cancelRun  context.CancelFunc // context of the run
+	stopReport models.StopReport
+
+	stopCheckpoint chan struct{}
+	checkpointDone chan struct{}
+
+	actions      map[int]*scheduledAction // lifecycle actions not yet applied
+	nextActionId int
+	actionsMutex sync.Mutex
+
+	load       *loadController
+	loadStatus load.Status
+	loadMutex  sync.Mutex
+
+	commands chan func() // executed by the loop, the only writer of the state
 }
 
+const (
+	// DefaultCheckpoint is the interval between the periodic saves
+	DefaultCheckpoint = 60 * time.Second
+)
+
 func (s *Simulator) setup() {
 	s.setupGateways()
 	s.setupDevices()
@@ -102,6 +140,9 @@ func (s *Simulator) loadData() {
 		log.Fatal(err)
 	}
 
+	s.loadTemplates()
+	s.loadGroups()
+
 }
 
 func (s *Simulator) searchName(Name string, Id int, gwFlag bool) (int, error) {
@@ -194,6 +235,21 @@ func (s *Simulator) saveStatus() {
 	s.Print("Status saved", nil, util.PrintOnlyConsole)
 }
 
+// saveDevices writes the devices and the next id
+func (s *Simulator) saveDevices() {
+
+	pathDir, err := util.GetPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path := pathDir + "/devices.json"
+	s.saveComponent(path, &s.Devices)
+	path = pathDir + "/simulator.json"
+	s.saveComponent(path, &s)
+
+}
+
 func (s *Simulator) turnONDevice(Id int) {
 
 	infoDev := mfw.InfoDevice{
@@ -203,6 +259,7 @@ func (s *Simulator) turnONDevice(Id int) {
 	}
 	s.Forwarder.AddDevice(infoDev)
 
+	s.Devices[Id].SetConsole(&s.Console)
 	s.Devices[Id].Setup(&s.Resources, &s.Forwarder)
 	s.Devices[Id].TurnON()
 	s.ActiveDevices[Id] = Id
@@ -210,19 +267,142 @@ func (s *Simulator) turnONDevice(Id int) {
 	s.Console.PrintSocket(socket.EventResponseCommand, s.Devices[Id].Info.Name+" Turn ON")
 }
 
-func (s *Simulator) turnOFFDevice(Id int) {
+func (s *Simulator) turnONDevices() {
+
+	ids := make([]int, 0, len(s.ActiveDevices))
+	for _, id := range s.ActiveDevices {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	if s.RampUp <= 0 || s.Resources.Clock.Virtual() { // a virtual run has no wall time to spread
+
+		for _, id := range ids {
+			s.turnONDevice(id)
+		}
+
+		return
+	}
+
+	s.stopRampUp = make(chan struct{})
+	s.rampUpDone = make(chan struct{})
+
+	stop, done := s.stopRampUp, s.rampUpDone
+	s.Resources.Clock.Go(func() { s.rampUp(ids, time.Duration(float64(time.Second)/s.RampUp), stop, done) })
+
+	s.Print(fmt.Sprintf("Ramp-up of %v devices, %v per second", len(ids), s.RampUp), nil, util.PrintBoth)
+}
+
+func (s *Simulator) rampUp(ids []int, interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+
+	defer close(done)
+
+	clk := s.Resources.Clock
+	started := clk.Now()
+
+	for i, id := range ids {
+
+		next := started.Add(time.Duration(i) * interval)
+
+		if i > 0 && !clk.Sleep(clock.Until(clk, next), stop) {
+			return
+		}
+
+		turnON := func() {
+			if _, ok := s.ActiveDevices[id]; ok && !s.Devices[id].IsOn() {
+				s.turnONDevice(id)
+			}
+		}
+
+		if !s.doUnless(stop, turnON) {
+			return
+		}
+
+	}
+
+	s.Print("Ramp-up completed", nil, util.PrintBoth)
+}
+
+func (s *Simulator) stopRamp() {
+
+	if s.stopRampUp == nil {
+		return
+	}
+
+	close(s.stopRampUp)
+	<-s.rampUpDone
+
+	s.stopRampUp = nil
+}
+
+// startCheckpoints saves the status periodically, the sessions survive a crash
+func (s *Simulator) startCheckpoints() {
+
+	interval := time.Duration(s.Checkpoint) * time.Second
+	if s.Checkpoint == 0 {
+		interval = DefaultCheckpoint
+	}
+
+	if interval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	s.stopCheckpoint = stop
+	s.checkpointDone = done
+
+	// wall time: on a virtual clock the saves would hold the run at every
+	// interval, the status is saved when it stops anyway
+	clk := clock.Real{}
+
+	clk.Go(func() {
+
+		defer close(done)
+
+		started := clk.Now()
+
+		for n := 1; ; n++ {
+
+			next := started.Add(time.Duration(n) * interval)
+
+			if !clk.Sleep(clock.Until(clk, next), stop) {
+				return
+			}
+
+			if !s.doUnless(stop, s.saveDevices) {
+				return
+			}
+
+			s.Print("Checkpoint saved", nil, util.PrintOnlyConsole)
+		}
+
+	})
+}
+
+func (s *Simulator) stopCheckpoints() {
 
-	s.ComponentsInactiveTmp++
-	s.Resources.ExitGroup.Add(1)
+	if s.stopCheckpoint == nil {
+		return
+	}
+
+	close(s.stopCheckpoint)
+	<-s.checkpointDone
+
+	s.stopCheckpoint = nil
+}
+
+// turnOFFDevice stops the device, it returns false if the device didn't exit within the stop timeout
+func (s *Simulator) turnOFFDevice(Id int) bool {
 
 	s.Devices[Id].TurnOFF()
 
 	s.Forwarder.DeleteDevice(s.Devices[Id].Info.DevEUI)
 
-	s.Resources.ExitGroup.Wait()
+	report := s.waitExit([]exiting{{name: s.Devices[Id].Info.Name, done: s.Devices[Id].Done()}})
 
 	delete(s.ActiveDevices, Id)
-	s.ComponentsInactiveTmp--
 
 	status := socket.NewStatusDev{
 		DevEUI:   s.Devices[Id].Info.DevEUI,
@@ -236,6 +416,8 @@ func (s *Simulator) turnOFFDevice(Id int) {
 	s.Console.PrintSocket(socket.EventSaveStatus, status)
 
 	s.Console.PrintSocket(socket.EventResponseCommand, s.Devices[Id].Info.Name+" Turn OFF")
+
+	return !report.TimedOut
 }
 
 func (s *Simulator) turnONGateway(Id int) {
@@ -247,6 +429,7 @@ func (s *Simulator) turnONGateway(Id int) {
 
 	s.Forwarder.AddGateway(infoGw)
 
+	s.Gateways[Id].SetConsole(&s.Console)
 	s.Gateways[Id].Setup(&s.BridgeAddress, &s.Resources, &s.Forwarder)
 	s.Gateways[Id].TurnON()
 
@@ -257,19 +440,16 @@ func (s *Simulator) turnONGateway(Id int) {
 
 func (s *Simulator) turnOFFGateway(Id int) {
 
-	s.ComponentsInactiveTmp++
-	s.Resources.ExitGroup.Add(1)
-
 	s.Gateways[Id].TurnOFF()
 
-	s.Resources.ExitGroup.Wait()
+	s.waitExit([]exiting{{name: s.Gateways[Id].Info.Name, gateway: true, done: s.Gateways[Id].Done()}})
 
 	delete(s.ActiveGateways, Id)
-	s.ComponentsInactiveTmp--
 
 	infoGw := mfw.InfoGateway{
-		Buffer:   &s.Gateways[Id].BufferUplink,
-		Location: s.Gateways[Id].Info.Location,
+		MACAddress: s.Gateways[Id].Info.MACAddress,
+		Buffer:     &s.Gateways[Id].BufferUplink,
+		Location:   s.Gateways[Id].Info.Location,
 	}
 
 	s.Forwarder.DeleteGateway(infoGw)
@@ -297,6 +477,10 @@ func (s *Simulator) reset() {
 func (s *Simulator) Print(content string, err error, printType int) {
 
 	now := time.Now()
+	if s.Resources.Clock != nil {
+		now = s.Resources.Clock.Now()
+	}
+
 	message := ""
 	messageLog := ""
 	event := socket.EventLog
@@ -308,6 +492,8 @@ func (s *Simulator) Print(content string, err error, printType int) {
 		message = fmt.Sprintf("[ %s ] [SIM] [ERROR]: %s", now.Format(time.Stamp), err)
 		messageLog = fmt.Sprintf("[SIM] [ERROR]: %s", err)
 		event = socket.EventError
+
+		s.publish(events.TypeError, &events.Error{Message: err.Error()})
 	}
 
 	data := socket.ConsoleLog{
@@ -325,3 +511,20 @@ func (s *Simulator) Print(content string, err error, printType int) {
 		s.Console.PrintLog(messageLog)
 	}
 }
+
+// publish sends an event of the simulator on the bus
+func (s *Simulator) publish(eventType string, data interface{}) {
+
+	now := time.Now()
+	if s.Resources.Clock != nil {
+		now = s.Resources.Clock.Now()
+	}
+
+	s.Resources.Events.Publish(events.Event{
+		Type:   eventType,
+		Time:   now,
+		Source: events.SourceSimulator,
+		Name:   "SIM",
+		Data:   data,
+	})
+}
@@ -88,5 +88,11 @@ func WriteConfigFile(path string, data []byte) error {
 
 	}
 
-	return ioutil.WriteFile(path, data, os.ModePerm)
+	// write a temporary file and rename it: a crash never leaves a truncated file
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
 }
@@ -1,17 +1,23 @@
 package socket
 
 const (
-	EventLog                = "console-sim"
-	EventError              = "console-error"
-	EventDev                = "log-dev"
-	EventGw                 = "log-gw"
-	EventToggleStateDevice  = "toggleState-dev"
-	EventToggleStateGateway = "toggleState-gw"
-	EventSaveStatus         = "save-status"
-	EventMacCommand         = "send-MACCommand"
-	EventResponseCommand    = "response-command"
-	EventChangePayload      = "change-payload"
-	EventSendUplink         = "send-uplink"
-	EventChangeLocation     = "change-location"
-	EventGetParameters      = "get-regional-parameters"
+	EventLog                 = "console-sim"
+	EventError               = "console-error"
+	EventDev                 = "log-dev"
+	EventGw                  = "log-gw"
+	EventToggleStateDevice   = "toggleState-dev"
+	EventToggleStateGateway  = "toggleState-gw"
+	EventSaveStatus          = "save-status"
+	EventMacCommand          = "send-MACCommand"
+	EventResponseCommand     = "response-command"
+	EventChangePayload       = "change-payload"
+	EventSendUplink          = "send-uplink"
+	EventTriggerUplink       = "trigger-uplink"
+	EventDeviceAction        = "device-action"
+	EventBulkDevicesCommand  = "bulk-devices-command"
+	EventBulkGatewaysCommand = "bulk-gateways-command"
+	EventLoadReport          = "load-report"
+	EventChangeLocation      = "change-location"
+	EventGetParameters       = "get-regional-parameters"
+	EventReceivedDownlink    = "received-downlink"
 )
@@ -19,6 +19,7 @@ type NewStatusDev struct {
 type NewPayload struct {
 	Id      int    `json:"id"`
 	MType   string `json:"mtype"`
+	FPort   uint8  `json:"fport"` // 0 device's FPort
 	Payload string `json:"payload"`
 }
 
@@ -34,3 +35,11 @@ type MacCommand struct {
 	CID         string `json:"cid"`
 	Periodicity uint8  `json:"periodicity"`
 }
+
+type ReceivedDownlink struct {
+	Id        int           `json:"id"`
+	DevEUI    lorawan.EUI64 `json:"devEUI"`
+	FPort     uint8         `json:"fport"`
+	Payload   string        `json:"payload"` //hex
+	Confirmed bool          `json:"confirmed"`
+}
@@ -0,0 +1,154 @@
+package webserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/arslab/lwnsimulator/codes"
+	"github.com/arslab/lwnsimulator/models"
+	"github.com/arslab/lwnsimulator/socket"
+	"github.com/gin-gonic/gin"
+	socketio "github.com/googollee/go-socket.io"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	roleKey = "role" // role of the request in the gin context
+
+	// unknownUserHash is checked when the username doesn't exist, as long as a real one
+	unknownUserHash = "$2a$10$9B.TlyCEhqddgy/MXI6c3OBVHh8BAykm//oUMp1etadPuQiTBtg8W"
+)
+
+// every authenticated account is at least a viewer
+var (
+	operator = requireRole(models.RoleOperator)
+	admin    = requireRole(models.RoleAdmin)
+)
+
+// authenticate resolves the role of the request, everyone is admin if no account is configured
+func authenticate(c *gin.Context) {
+
+	var query url.Values
+	if path := c.Request.URL.Path; strings.HasPrefix(path, "/socket.io/") || strings.HasPrefix(path, "/api/v2/events") {
+		query = c.Request.URL.Query()
+	}
+
+	role, ok := resolveRole(c.Request.Header, query)
+	if !ok {
+
+		c.Header("WWW-Authenticate", `Basic realm="LWN Simulator"`)
+		abortError(c, codes.CodeErrorUnauthorized, errors.New("Authentication required"))
+
+		return
+	}
+
+	c.Set(roleKey, role)
+}
+
+// requireRole rejects the requests of the accounts without the role
+func requireRole(role string) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		if !models.HasRole(c.GetString(roleKey), role) {
+			abortError(c, codes.CodeErrorForbidden, errors.New("The "+role+" role is required"))
+		}
+	}
+}
+
+// resolveRole checks the basic auth or the bearer token, access_token in the query is accepted
+// for the sockets and the event streams, where the browsers can't set the header
+func resolveRole(header http.Header, query url.Values) (string, bool) {
+
+	auth := configuration.Auth
+	if !auth.Enabled() {
+		return models.RoleAdmin, true
+	}
+
+	req := http.Request{Header: header}
+
+	if username, password, ok := req.BasicAuth(); ok {
+
+		// every account is compared and a hash is always checked: the time of the answer
+		// doesn't tell if the username exists
+		var user *models.User
+		for i := range auth.Users {
+			if subtle.ConstantTimeCompare([]byte(auth.Users[i].Username), []byte(username)) == 1 && user == nil {
+				user = &auth.Users[i]
+			}
+		}
+
+		hash := unknownUserHash
+		if user != nil {
+			hash = user.Password
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil && user != nil {
+			return user.Role, true
+		}
+
+		return "", false
+	}
+
+	token := query.Get("access_token")
+	if bearer := header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		token = strings.TrimPrefix(bearer, "Bearer ")
+	}
+
+	if token == "" {
+		return "", false
+	}
+
+	for _, t := range auth.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return t.Role, true
+		}
+	}
+
+	return "", false
+}
+
+// sameOrigin rejects the cross-origin requests when no origin is allowed by CORS
+func sameOrigin(c *gin.Context) {
+
+	if !allowedOrigin(c.Request) {
+		abortError(c, codes.CodeErrorForbidden, errors.New("Origin not allowed"))
+	}
+}
+
+// allowedOrigin is true for the requests of the same host and of the configured origins
+func allowedOrigin(r *http.Request) bool {
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+
+	for _, o := range configuration.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// socketAllowed is true if the account of the socket has the role, otherwise the client
+// receives the error as response-command
+func socketAllowed(s socketio.Conn, role string) bool {
+
+	granted, _ := s.Context().(string)
+	if models.HasRole(granted, role) {
+		return true
+	}
+
+	s.Emit(socket.EventResponseCommand, "The "+role+" role is required")
+
+	return false
+}
@@ -0,0 +1,170 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arslab/lwnsimulator/codes"
+	cnt "github.com/arslab/lwnsimulator/controllers"
+	"github.com/arslab/lwnsimulator/models"
+	dev "github.com/arslab/lwnsimulator/simulator/components/device"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeController answers the routes of the test, the others aren't called
+type fakeController struct {
+	cnt.SimulatorController
+}
+
+func (fakeController) Run() bool {
+	return true
+}
+
+func (fakeController) AddDevice(*dev.Device) (int, int, error) {
+	return codes.CodeOK, 1, nil
+}
+
+func (fakeController) GetDevice(int) (json.RawMessage, int, error) {
+	return json.RawMessage(`{"id": 1}`), codes.CodeOK, nil
+}
+
+func newTestRouter(t *testing.T, config models.ServerConfig) *gin.Engine {
+
+	t.Helper()
+
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration = &config
+	simulatorController = fakeController{}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+
+	router.Use(sameOrigin, authenticate)
+
+	registerV1(router)
+	registerV2(router)
+
+	return router
+}
+
+func hash(t *testing.T, password string) string {
+
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(h)
+}
+
+func TestRoles(t *testing.T) {
+
+	router := newTestRouter(t, models.ServerConfig{
+		Auth: models.AuthConfig{
+			Users: []models.User{
+				{Username: "viewer", Password: hash(t, "viewer-secret"), Role: models.RoleViewer},
+				{Username: "operator", Password: hash(t, "operator-secret"), Role: models.RoleOperator},
+				{Username: "admin", Password: hash(t, "admin-secret"), Role: models.RoleAdmin},
+			},
+			Tokens: []models.Token{{Name: "ci", Token: "operator-token", Role: models.RoleOperator}},
+		},
+	})
+
+	routes := []struct {
+		method string
+		path   string
+		role   string
+	}{
+		{http.MethodGet, "/api/v2/openapi.json", models.RoleViewer},
+		{http.MethodGet, "/api/start", models.RoleOperator},
+		{http.MethodPost, "/api/v2/devices", models.RoleAdmin},
+	}
+
+	accounts := []struct {
+		name     string
+		auth     func(r *http.Request)
+		role     string // "" unauthenticated
+		rejected int
+	}{
+		{"anonymous", func(r *http.Request) {}, "", http.StatusUnauthorized},
+		{"viewer", func(r *http.Request) { r.SetBasicAuth("viewer", "viewer-secret") }, models.RoleViewer, http.StatusForbidden},
+		{"operator", func(r *http.Request) { r.SetBasicAuth("operator", "operator-secret") }, models.RoleOperator, http.StatusForbidden},
+		{"admin", func(r *http.Request) { r.SetBasicAuth("admin", "admin-secret") }, models.RoleAdmin, http.StatusForbidden},
+		{"token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer operator-token") }, models.RoleOperator, http.StatusForbidden},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("admin", "viewer-secret") }, "", http.StatusUnauthorized},
+		{"unknown user", func(r *http.Request) { r.SetBasicAuth("root", "admin-secret") }, "", http.StatusUnauthorized},
+		{"wrong token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer operator") }, "", http.StatusUnauthorized},
+	}
+
+	for _, route := range routes {
+		for _, account := range accounts {
+
+			r := httptest.NewRequest(route.method, route.path, strings.NewReader("{}"))
+			account.auth(r)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			want := account.rejected
+			if account.role != "" && models.HasRole(account.role, route.role) {
+				want = http.StatusOK
+			}
+
+			if got := w.Code; (want == http.StatusOK && got >= 300) || (want != http.StatusOK && got != want) {
+				t.Errorf("%v %v as %v = %v, want %v", route.method, route.path, account.name, got, want)
+			}
+		}
+	}
+}
+
+func TestPlaintextPassword(t *testing.T) {
+
+	config := models.ServerConfig{
+		Auth: models.AuthConfig{Users: []models.User{{Username: "admin", Password: "admin-secret", Role: models.RoleAdmin}}},
+	}
+
+	if err := config.Validate(); err == nil {
+		t.Errorf("plaintext password accepted")
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+
+	tests := []struct {
+		allowed []string
+		origin  string
+		status  int
+	}{
+		{nil, "", http.StatusOK}, // not a browser
+		{nil, "http://simulator.local:8000", http.StatusOK},
+		{nil, "http://evil.example", http.StatusForbidden},
+		{nil, "http://simulator.local:9000", http.StatusForbidden},
+		{[]string{"http://dashboard.example"}, "http://dashboard.example", http.StatusOK},
+		{[]string{"http://dashboard.example"}, "http://evil.example", http.StatusForbidden},
+		{[]string{"*"}, "http://evil.example", http.StatusOK},
+	}
+
+	for _, test := range tests {
+
+		router := newTestRouter(t, models.ServerConfig{AllowedOrigins: test.allowed})
+
+		r := httptest.NewRequest(http.MethodGet, "http://simulator.local:8000/api/v2/openapi.json", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("origin %q allowed %v = %v, want %v", test.origin, test.allowed, w.Code, test.status)
+		}
+	}
+}
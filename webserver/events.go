@@ -0,0 +1,157 @@
+package webserver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arslab/lwnsimulator/codes"
+	"github.com/arslab/lwnsimulator/simulator/events"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	keepAlive    = 15 * time.Second // comment of the stream, ping of the socket
+	writeTimeout = 10 * time.Second
+)
+
+var eventsUpgrader = websocket.Upgrader{
+	CheckOrigin: allowedOrigin,
+}
+
+// eventFilter reads ?device=&gateway=&type=, each repeatable
+func eventFilter(c *gin.Context) (events.Filter, error) {
+
+	filter := events.Filter{
+		Types: c.QueryArray("type"),
+	}
+
+	for _, value := range c.QueryArray("device") {
+
+		Id, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("Invalid device " + value)
+		}
+
+		filter.Devices = append(filter.Devices, Id)
+	}
+
+	for _, value := range c.QueryArray("gateway") {
+
+		Id, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("Invalid gateway " + value)
+		}
+
+		filter.Gateways = append(filter.Gateways, Id)
+	}
+
+	return filter, nil
+}
+
+// streamEvents sends the events as server-sent events, the name of each one is its type
+func streamEvents(c *gin.Context) {
+
+	filter, err := eventFilter(c)
+	if err != nil {
+		abortError(c, codes.CodeErrorRequest, err)
+		return
+	}
+
+	sub := simulatorController.Subscribe(filter)
+	defer simulatorController.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // no buffering behind nginx
+
+	c.Status(http.StatusOK)
+	c.Writer.Flush() // the clients connect before the first event
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+
+		select {
+
+		case e, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+
+			c.SSEvent(e.Type, e)
+
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return false
+			}
+
+		case <-c.Request.Context().Done():
+			return false
+		}
+
+		return true
+	})
+}
+
+// socketEvents sends the events as JSON messages of a plain WebSocket
+func socketEvents(c *gin.Context) {
+
+	filter, err := eventFilter(c)
+	if err != nil {
+		abortError(c, codes.CodeErrorRequest, err)
+		return
+	}
+
+	conn, err := eventsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // the upgrader has already replied
+	}
+	defer conn.Close()
+
+	sub := simulatorController.Subscribe(filter)
+	defer simulatorController.Unsubscribe(sub)
+
+	closed := make(chan struct{})
+
+	go func() { // the client doesn't send messages, reading detects the close
+		defer close(closed)
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package webserver
+
+import (
+	_ "embed" // openapi.json
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPI is the OpenAPI 3 document of the HTTP API, keep it aligned with the routes
+//
+//go:embed openapi.json
+var openAPI []byte
+
+func getOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPI)
+}
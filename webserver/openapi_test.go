@@ -0,0 +1,53 @@
+package webserver
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// param is a parameter of a gin path, {name} in the OpenAPI document
+var param = regexp.MustCompile(`:(\w+)`)
+
+// TestOpenAPIRoutes checks that the document describes every route of the API and nothing else
+func TestOpenAPIRoutes(t *testing.T) {
+
+	doc := struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+
+	if err := json.Unmarshal(openAPI, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+
+	registerV1(router)
+	registerV2(router)
+
+	registered := make(map[string]bool)
+
+	for _, route := range router.Routes() {
+
+		path := param.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+
+		registered[method+" "+path] = true
+
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("%v %v missing in openapi.json", route.Method, path)
+		}
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method != "parameters" && !registered[method+" "+path] {
+				t.Errorf("%v %v in openapi.json isn't a route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package webserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/arslab/lwnsimulator/codes"
+	dev "github.com/arslab/lwnsimulator/simulator/components/device"
+	gw "github.com/arslab/lwnsimulator/simulator/components/gateway"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/brocaar/lorawan"
+	"github.com/gin-gonic/gin"
+)
+
+// registerV2 adds the resource-oriented API, the v1 routes stay for the dashboard
+func registerV2(router *gin.Engine) {
+
+	v2 := router.Group("/api/v2")
+	{
+		v2.GET("/devices", listDevices)
+		v2.POST("/devices", admin, createDevice)
+		v2.GET("/devices/:id", getDevice)
+		v2.PATCH("/devices/:id", admin, patchDevice)
+		v2.DELETE("/devices/:id", admin, removeDevice)
+		v2.GET("/devices/eui/:devEUI", getDeviceByEUI)
+		v2.GET("/gateways", listGateways)
+		v2.POST("/gateways", admin, createGateway)
+		v2.GET("/gateways/:id", getGateway)
+		v2.PATCH("/gateways/:id", admin, patchGateway)
+		v2.DELETE("/gateways/:id", admin, removeGateway)
+		v2.GET("/gateways/mac/:mac", getGatewayByMAC)
+		v2.GET("/events", streamEvents)
+		v2.GET("/events/ws", socketEvents)
+		v2.GET("/openapi.json", getOpenAPI)
+	}
+}
+
+// httpStatus maps the code of the simulator to the HTTP status
+func httpStatus(code int) int {
+
+	switch code {
+
+	case codes.CodeOK:
+		return http.StatusOK
+
+	case codes.CodeErrorNotFound:
+		return http.StatusNotFound
+
+	case codes.CodeErrorRequest:
+		return http.StatusBadRequest
+
+	case codes.CodeErrorUnauthorized:
+		return http.StatusUnauthorized
+
+	case codes.CodeErrorForbidden:
+		return http.StatusForbidden
+
+	case codes.CodeErrorName, codes.CodeErrorAddress, codes.CodeErrorDeviceActive,
+		codes.CodeErrorGatewayActive, codes.CodeNoBridge, codes.CodeErrorDeviceOff:
+		return http.StatusConflict
+
+	case codes.CodeSaving, codes.CodeErrorInternal:
+		return http.StatusInternalServerError
+
+	}
+
+	return http.StatusUnprocessableEntity
+}
+
+// abortError writes the error body {"error": {"code": N, "message": "..."}}
+func abortError(c *gin.Context, code int, err error) {
+
+	c.AbortWithStatusJSON(httpStatus(code), gin.H{
+		"error": gin.H{
+			"code":    code,
+			"message": err.Error(),
+		},
+	})
+}
+
+// listQuery reads ?offset=&limit=&sort=&active= and the selection filter
+func listQuery(c *gin.Context) (fleet.Query, error) {
+
+	query := fleet.Query{
+		Selection: selectionQuery(c),
+		Sort:      c.Query("sort"),
+	}
+
+	var err error
+
+	if value := c.Query("offset"); value != "" {
+		if query.Offset, err = strconv.Atoi(value); err != nil {
+			return query, errors.New("Invalid offset " + value)
+		}
+	}
+
+	if value := c.Query("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil {
+			return query, errors.New("Invalid limit " + value)
+		}
+	}
+
+	if value := c.Query("active"); value != "" {
+
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, errors.New("Invalid active " + value)
+		}
+
+		query.Active = &active
+	}
+
+	return query, query.Validate()
+}
+
+func idParam(c *gin.Context) (int, bool) {
+
+	Id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortError(c, codes.CodeErrorRequest, errors.New("Invalid id "+c.Param("id")))
+		return 0, false
+	}
+
+	return Id, true
+}
+
+func euiParam(c *gin.Context, name string) (lorawan.EUI64, bool) {
+
+	var eui lorawan.EUI64
+	if err := eui.UnmarshalText([]byte(c.Param(name))); err != nil {
+		abortError(c, codes.CodeErrorRequest, errors.New("Invalid "+name+" "+c.Param(name)))
+		return eui, false
+	}
+
+	return eui, true
+}
+
+// patchBody reads the JSON merge patch of the request
+func patchBody(c *gin.Context) (json.RawMessage, bool) {
+
+	patch, err := c.GetRawData()
+	if err == nil && !json.Valid(patch) {
+		err = errors.New("Invalid JSON patch")
+	}
+
+	if err != nil {
+		abortError(c, codes.CodeErrorRequest, err)
+		return nil, false
+	}
+
+	return patch, true
+}
+
+func writeResource(c *gin.Context, status int, resource json.RawMessage, code int, err error) {
+
+	if err != nil {
+		abortError(c, code, err)
+		return
+	}
+
+	c.Data(status, "application/json; charset=utf-8", resource)
+}
+
+func listDevices(c *gin.Context) {
+
+	query, err := listQuery(c)
+	if err != nil {
+		abortError(c, codes.CodeErrorRequest, err)
+		return
+	}
+
+	page, err := simulatorController.ListDevices(query)
+	if err != nil {
+		abortError(c, codes.CodeErrorInternal, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func createDevice(c *gin.Context) {
+
+	var device dev.Device
+	if err := c.ShouldBindJSON(&device); err != nil {
+		abortError(c, codes.CodeErrorRequest, err)
+		return
+	}
+
+	code, id, err := simulatorController.AddDevice(&device)
+	if err != nil {
+		abortError(c, code, err)
+		return
+	}
+
+	c.Header("Location", "/api/v2/devices/"+strconv.Itoa(id))
+
+	resource, code, err := simulatorController.GetDevice(id)
+	writeResource(c, http.StatusCreated, resource, code, err)
+}
+
+func getDevice(c *gin.Context) {
+
+	Id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	resource, code, err := simulatorController.GetDevice(Id)
+	writeResource(c, http.StatusOK, resource, code, err)
+}
+
+func getDeviceByEUI(c *gin.Context) {
+
+	DevEUI, ok := euiParam(c, "devEUI")
+	if !ok {
+		return
+	}
+
+	resource, code, err := simulatorController.DeviceByEUI(DevEUI)
+	writeResource(c, http.StatusOK, resource, code, err)
+}
+
+func patchDevice(c *gin.Context) {
+
+	Id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	patch, ok := patchBody(c)
+	if !ok {
+		return
+	}
+
+	code, err := simulatorController.MergeDevice(Id, patch)
+	if err != nil {
+		abortError(c, code, err)
+		return
+	}
+
+	resource, code, err := simulatorController.GetDevice(Id)
+	writeResource(c, http.StatusOK, resource, code, err)
+}
+
+func removeDevice(c *gin.Context) {
+
+	Id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	code, err := simulatorController.RemoveDevice(Id)
+	if err != nil {
+		abortError(c, code, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func listGateways(c *gin.Context) {
+
+	query, err := listQuery(c)
+	if err != nil {
+		abortError(c, codes.CodeErrorRequest, err)
+		return
+	}
+
+	page, err := simulatorController.ListGateways(query)
+	if err != nil {
+		abortError(c, codes.CodeErrorInternal, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func createGateway(c *gin.Context) {
+
+	var g gw.Gateway
+	if err := c.ShouldBindJSON(&g); err != nil {
+		abortError(c, codes.CodeErrorRequest, err)
+		return
+	}
+
+	code, id, err := simulatorController.AddGateway(&g)
+	if err != nil {
+		abortError(c, code, err)
+		return
+	}
+
+	c.Header("Location", "/api/v2/gateways/"+strconv.Itoa(id))
+
+	resource, code, err := simulatorController.GetGateway(id)
+	writeResource(c, http.StatusCreated, resource, code, err)
+}
+
+func getGateway(c *gin.Context) {
+
+	Id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	resource, code, err := simulatorController.GetGateway(Id)
+	writeResource(c, http.StatusOK, resource, code, err)
+}
+
+func getGatewayByMAC(c *gin.Context) {
+
+	MACAddress, ok := euiParam(c, "mac")
+	if !ok {
+		return
+	}
+
+	resource, code, err := simulatorController.GatewayByMAC(MACAddress)
+	writeResource(c, http.StatusOK, resource, code, err)
+}
+
+func patchGateway(c *gin.Context) {
+
+	Id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	patch, ok := patchBody(c)
+	if !ok {
+		return
+	}
+
+	code, err := simulatorController.MergeGateway(Id, patch)
+	if err != nil {
+		abortError(c, code, err)
+		return
+	}
+
+	resource, code, err := simulatorController.GetGateway(Id)
+	writeResource(c, http.StatusOK, resource, code, err)
+}
+
+func removeGateway(c *gin.Context) {
+
+	Id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	code, err := simulatorController.RemoveGateway(Id)
+	if err != nil {
+		abortError(c, code, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
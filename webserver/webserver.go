@@ -1,23 +1,33 @@
 package webserver
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	cnt "github.com/arslab/lwnsimulator/controllers"
 	"github.com/arslab/lwnsimulator/models"
+	"github.com/arslab/lwnsimulator/simulator/clock"
 	dev "github.com/arslab/lwnsimulator/simulator/components/device"
 	rp "github.com/arslab/lwnsimulator/simulator/components/device/regional_parameters"
 	mrp "github.com/arslab/lwnsimulator/simulator/components/device/regional_parameters/models_rp"
 	gw "github.com/arslab/lwnsimulator/simulator/components/gateway"
+	"github.com/arslab/lwnsimulator/simulator/fleet"
+	"github.com/arslab/lwnsimulator/simulator/load"
+	"github.com/arslab/lwnsimulator/simulator/report"
 	"github.com/arslab/lwnsimulator/socket"
 	_ "github.com/arslab/lwnsimulator/webserver/statik"
 	"github.com/brocaar/lorawan"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	socketio "github.com/googollee/go-socket.io"
+	"github.com/googollee/go-socket.io/engineio"
+	"github.com/googollee/go-socket.io/engineio/transport"
+	"github.com/googollee/go-socket.io/engineio/transport/polling"
+	"github.com/googollee/go-socket.io/engineio/transport/websocket"
 	"github.com/rakyll/statik/fs"
 )
 
@@ -36,11 +46,15 @@ var (
 
 func NewWebServer(config *models.ServerConfig, controller cnt.SimulatorController) *WebServer {
 
-	serverSocket := newServerSocket()
-
 	configuration = config
 	simulatorController = controller
 
+	if err := configuration.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	serverSocket := newServerSocket()
+
 	go func() {
 
 		err := serverSocket.Serve()
@@ -54,15 +68,22 @@ func NewWebServer(config *models.ServerConfig, controller cnt.SimulatorControlle
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 
-	configCors := cors.DefaultConfig()
-	configCors.AllowAllOrigins = true
-	configCors.AllowHeaders = []string{"Origin", "Access-Control-Allow-Origin",
-		"Access-Control-Allow-Headers", "Content-type"}
-	configCors.AllowMethods = []string{"GET", "POST", "DELETE", "OPTIONS"}
-	configCors.AllowCredentials = true
-	router.Use(cors.New(configCors))
+	if len(configuration.AllowedOrigins) > 0 { // "*" allows every origin
+
+		configCors := cors.DefaultConfig()
+		configCors.AllowOrigins = configuration.AllowedOrigins
+		configCors.AllowHeaders = []string{"Origin", "Access-Control-Allow-Origin",
+			"Access-Control-Allow-Headers", "Content-type", "Authorization"}
+		configCors.AllowMethods = []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"}
+		configCors.AllowCredentials = true
+		router.Use(cors.New(configCors))
+
+	} else {
+		router.Use(sameOrigin) // only the dashboard served by the simulator
+	}
 
 	router.Use(gin.Recovery())
+	router.Use(authenticate)
 
 	ws := WebServer{
 		Address:      configuration.Address,
@@ -80,22 +101,8 @@ func NewWebServer(config *models.ServerConfig, controller cnt.SimulatorControlle
 	staticGroup.StaticFS("/", staticFS)
 	//router.Use(static.Serve("/", staticFS))
 
-	apiRoutes := router.Group("/api")
-	{
-		apiRoutes.GET("/start", startSimulator)
-		apiRoutes.GET("/stop", stopSimulator)
-		apiRoutes.GET("/status", simulatorStatus)
-		apiRoutes.GET("/bridge", getRemoteAddress)
-		apiRoutes.GET("/gateways", getGateways)
-		apiRoutes.GET("/devices", getDevices)
-		apiRoutes.POST("/add-device", addDevice)
-		apiRoutes.POST("/up-device", updateDevice)
-		apiRoutes.POST("/del-device", deleteDevice)
-		apiRoutes.POST("/del-gateway", deleteGateway)
-		apiRoutes.POST("/add-gateway", addGateway)
-		apiRoutes.POST("/up-gateway", updateGateway)
-		apiRoutes.POST("/bridge/save", saveInfoBridge)
-	}
+	registerV1(router)
+	registerV2(router)
 
 	router.GET("/socket.io/*any", gin.WrapH(serverSocket))
 	router.POST("/socket.io/*any", gin.WrapH(serverSocket))
@@ -105,6 +112,55 @@ func NewWebServer(config *models.ServerConfig, controller cnt.SimulatorControlle
 	return &ws
 }
 
+// registerV1 adds the routes of the dashboard
+func registerV1(router *gin.Engine) {
+
+	apiRoutes := router.Group("/api")
+	{
+		apiRoutes.GET("/start", operator, startSimulator)
+		apiRoutes.GET("/stop", operator, stopSimulator)
+		apiRoutes.GET("/stop-report", getStopReport)
+		apiRoutes.GET("/status", simulatorStatus)
+		apiRoutes.GET("/bridge", getRemoteAddress)
+		apiRoutes.GET("/gateways", getGateways)
+		apiRoutes.GET("/devices", getDevices)
+		apiRoutes.POST("/add-device", admin, addDevice)
+		apiRoutes.POST("/up-device", admin, updateDevice)
+		apiRoutes.POST("/del-device", admin, deleteDevice)
+		apiRoutes.POST("/del-gateway", admin, deleteGateway)
+		apiRoutes.POST("/add-gateway", admin, addGateway)
+		apiRoutes.POST("/up-gateway", admin, updateGateway)
+		apiRoutes.POST("/bridge/save", admin, saveInfoBridge)
+		apiRoutes.GET("/ramp-up", getRampUp)
+		apiRoutes.POST("/ramp-up/save", admin, saveRampUp)
+		apiRoutes.GET("/clock", getClock)
+		apiRoutes.POST("/clock/save", admin, saveClock)
+		apiRoutes.GET("/downlinks/:id", getDownlinks)
+		apiRoutes.POST("/trigger-uplink", operator, triggerUplink)
+		apiRoutes.GET("/templates", getTemplates)
+		apiRoutes.POST("/templates/save", admin, saveTemplate)
+		apiRoutes.POST("/templates/del", admin, deleteTemplate)
+		apiRoutes.POST("/bulk/add-devices", admin, bulkAddDevices)
+		apiRoutes.POST("/bulk/up-devices", admin, bulkUpdateDevices)
+		apiRoutes.POST("/bulk/del-devices", admin, bulkDeleteDevices)
+		apiRoutes.POST("/bulk/devices-command", operator, devicesCommand)
+		apiRoutes.POST("/bulk/gateways-command", operator, gatewaysCommand)
+		apiRoutes.GET("/groups", getGroups)
+		apiRoutes.POST("/groups/save", admin, saveGroup)
+		apiRoutes.POST("/groups/del", admin, deleteGroup)
+		apiRoutes.POST("/rejoin", operator, forceRejoin)
+		apiRoutes.GET("/load-profile", getLoadStatus)
+		apiRoutes.POST("/load-profile/start", operator, startLoadProfile)
+		apiRoutes.GET("/load-profile/stop", operator, stopLoadProfile)
+		apiRoutes.GET("/report", getReport)
+		apiRoutes.GET("/reports", getReports)
+		apiRoutes.GET("/reports/:id", getRunReport)
+		apiRoutes.POST("/device-action", operator, deviceAction)
+		apiRoutes.GET("/device-actions", getDeviceActions)
+		apiRoutes.POST("/device-actions/del", operator, cancelDeviceAction)
+	}
+}
+
 func startSimulator(c *gin.Context) {
 	c.JSON(http.StatusOK, simulatorController.Run())
 }
@@ -113,6 +169,10 @@ func stopSimulator(c *gin.Context) {
 	c.JSON(http.StatusOK, simulatorController.Stop())
 }
 
+func getStopReport(c *gin.Context) {
+	c.JSON(http.StatusOK, simulatorController.GetStopReport())
+}
+
 func simulatorStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, simulatorController.Status())
 }
@@ -129,9 +189,39 @@ func getRemoteAddress(c *gin.Context) {
 	c.JSON(http.StatusOK, simulatorController.GetBridgeAddress())
 }
 
+func saveRampUp(c *gin.Context) {
+
+	var rampUp models.RampUp
+	c.BindJSON(&rampUp)
+
+	err := simulatorController.SaveRampUp(rampUp)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString})
+}
+
+func getRampUp(c *gin.Context) {
+	c.JSON(http.StatusOK, simulatorController.GetRampUp())
+}
+
+func saveClock(c *gin.Context) {
+
+	var config clock.Config
+	c.BindJSON(&config)
+
+	err := simulatorController.SaveClock(config)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString})
+}
+
+func getClock(c *gin.Context) {
+	c.JSON(http.StatusOK, simulatorController.GetClock())
+}
+
 func getGateways(c *gin.Context) {
 
-	gws := simulatorController.GetGateways()
+	gws := simulatorController.GetGateways(selectionQuery(c))
 	c.JSON(http.StatusOK, gws)
 }
 
@@ -172,7 +262,17 @@ func deleteGateway(c *gin.Context) {
 }
 
 func getDevices(c *gin.Context) {
-	c.JSON(http.StatusOK, simulatorController.GetDevices())
+	c.JSON(http.StatusOK, simulatorController.GetDevices(selectionQuery(c)))
+}
+
+// selectionQuery reads the filter ?tag=a&tag=b&group=name&prefix=p
+func selectionQuery(c *gin.Context) fleet.Selection {
+
+	return fleet.Selection{
+		Prefix: c.Query("prefix"),
+		Tags:   c.QueryArray("tag"),
+		Group:  c.Query("group"),
+	}
 }
 
 func addDevice(c *gin.Context) {
@@ -210,15 +310,288 @@ func deleteDevice(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": simulatorController.DeleteDevice(Identifier.Id)})
 }
 
+func getDownlinks(c *gin.Context) {
+
+	Id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": err.Error()})
+		return
+	}
+
+	downlinks, err := simulatorController.GetDownlinks(Id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, downlinks)
+}
+
+func triggerUplink(c *gin.Context) {
+
+	var pl socket.NewPayload
+	c.BindJSON(&pl)
+
+	c.JSON(http.StatusOK, gin.H{"status": simulatorController.TriggerUplink(pl)})
+}
+
+func getTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, simulatorController.GetTemplates())
+}
+
+func saveTemplate(c *gin.Context) {
+
+	var template fleet.Template
+	c.BindJSON(&template)
+
+	err := simulatorController.SaveTemplate(template)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString})
+}
+
+func deleteTemplate(c *gin.Context) {
+
+	Identifier := struct {
+		Name string `json:"name"`
+	}{}
+
+	c.BindJSON(&Identifier)
+
+	c.JSON(http.StatusOK, gin.H{"status": simulatorController.DeleteTemplate(Identifier.Name)})
+}
+
+func bulkAddDevices(c *gin.Context) {
+
+	var req fleet.Create
+	c.BindJSON(&req)
+
+	results, err := simulatorController.BulkAddDevices(req)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString, "results": results})
+}
+
+func bulkUpdateDevices(c *gin.Context) {
+
+	var req fleet.Update
+	c.BindJSON(&req)
+
+	results, err := simulatorController.BulkUpdateDevices(req)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString, "results": results})
+}
+
+func bulkDeleteDevices(c *gin.Context) {
+
+	var req fleet.Delete
+	c.BindJSON(&req)
+
+	c.JSON(http.StatusOK, gin.H{"results": simulatorController.BulkDeleteDevices(req)})
+}
+
+func devicesCommand(c *gin.Context) {
+
+	var cmd fleet.Command
+	c.BindJSON(&cmd)
+
+	results, err := simulatorController.DevicesCommand(cmd)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString, "results": results})
+}
+
+func gatewaysCommand(c *gin.Context) {
+
+	var cmd fleet.Command
+	c.BindJSON(&cmd)
+
+	results, err := simulatorController.GatewaysCommand(cmd)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString, "results": results})
+}
+
+func getGroups(c *gin.Context) {
+	c.JSON(http.StatusOK, simulatorController.GetGroups())
+}
+
+func saveGroup(c *gin.Context) {
+
+	var group fleet.Group
+	c.BindJSON(&group)
+
+	err := simulatorController.SaveGroup(group)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString})
+}
+
+func deleteGroup(c *gin.Context) {
+
+	Identifier := struct {
+		Name string `json:"name"`
+	}{}
+
+	c.BindJSON(&Identifier)
+
+	c.JSON(http.StatusOK, gin.H{"status": simulatorController.DeleteGroup(Identifier.Name)})
+}
+
+func forceRejoin(c *gin.Context) {
+
+	var selection fleet.Selection
+	c.BindJSON(&selection)
+
+	c.JSON(http.StatusOK, gin.H{"results": simulatorController.ForceRejoin(selection)})
+}
+
+func deviceAction(c *gin.Context) {
+
+	var req fleet.Action
+	c.BindJSON(&req)
+
+	action, results, err := simulatorController.DeviceAction(req)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString, "action": action, "results": results})
+}
+
+func getDeviceActions(c *gin.Context) {
+	c.JSON(http.StatusOK, simulatorController.GetDeviceActions())
+}
+
+func cancelDeviceAction(c *gin.Context) {
+
+	Identifier := struct {
+		Id int `json:"id"`
+	}{}
+
+	c.BindJSON(&Identifier)
+
+	c.JSON(http.StatusOK, gin.H{"status": simulatorController.CancelDeviceAction(Identifier.Id)})
+}
+
+func getLoadStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, simulatorController.GetLoadStatus())
+}
+
+func startLoadProfile(c *gin.Context) {
+
+	var profile load.Profile
+	c.BindJSON(&profile)
+
+	err := simulatorController.StartLoadProfile(profile)
+	errString := fmt.Sprintf("%v", err)
+
+	c.JSON(http.StatusOK, gin.H{"status": errString})
+}
+
+func stopLoadProfile(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": simulatorController.StopLoadProfile()})
+}
+
+func getReport(c *gin.Context) {
+
+	from, to, err := reportRange(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"status": err.Error()})
+		return
+	}
+
+	rep, err := simulatorController.GetReport(from, to)
+	writeReport(c, rep, err)
+}
+
+func getReports(c *gin.Context) {
+	c.JSON(http.StatusOK, simulatorController.GetReports())
+}
+
+func getRunReport(c *gin.Context) {
+
+	from, to, err := reportRange(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"status": err.Error()})
+		return
+	}
+
+	rep, err := simulatorController.GetRunReport(c.Param("id"), from, to)
+	writeReport(c, rep, err)
+}
+
+// reportRange reads the time range of a report, ?from=&to= in RFC 3339, missing are the bounds of the run
+func reportRange(c *gin.Context) (time.Time, time.Time, error) {
+
+	var bounds [2]time.Time
+
+	for i, key := range []string{"from", "to"} {
+
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("Invalid " + key + ": " + value)
+		}
+
+		bounds[i] = t
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+// writeReport writes the report as JSON or, with ?format=html, as a page
+func writeReport(c *gin.Context, rep report.Report, err error) {
+
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"status": err.Error()})
+		return
+	}
+
+	if c.Query("format") != "html" {
+		c.JSON(http.StatusOK, rep)
+		return
+	}
+
+	html, err := rep.HTML()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"status": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+}
+
 func newServerSocket() *socketio.Server {
 
-	serverSocket := socketio.NewServer(nil)
+	var options *engineio.Options
+
+	if len(configuration.AllowedOrigins) > 0 { // the polling transport is under the CORS of the router
+		options = &engineio.Options{
+			Transports: []transport.Transport{
+				polling.Default,
+				&websocket.Transport{CheckOrigin: allowedOrigin},
+			},
+		}
+	}
+
+	serverSocket := socketio.NewServer(options)
 
 	serverSocket.OnConnect("/", func(s socketio.Conn) error {
 
 		log.Println("[WS]: Socket connected")
 
-		s.SetContext("")
+		u := s.URL()
+
+		role, ok := resolveRole(s.RemoteHeader(), u.Query())
+		if !ok {
+			return errors.New("Authentication required")
+		}
+
+		s.SetContext(role)
 		simulatorController.AddWebSocket(&s)
 
 		return nil
@@ -226,19 +599,34 @@ func newServerSocket() *socketio.Server {
 	})
 
 	serverSocket.OnDisconnect("/", func(s socketio.Conn, reason string) {
+		simulatorController.RemoveWebSocket(&s)
 		s.Close()
 	})
 
 	serverSocket.OnEvent("/", socket.EventToggleStateDevice, func(s socketio.Conn, Id int) {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return
+		}
+
 		simulatorController.ToggleStateDevice(Id)
 	})
 
 	serverSocket.OnEvent("/", socket.EventToggleStateGateway, func(s socketio.Conn, Id int) {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return
+		}
+
 		simulatorController.ToggleStateGateway(Id)
 	})
 
 	serverSocket.OnEvent("/", socket.EventMacCommand, func(s socketio.Conn, data socket.MacCommand) {
 
+		if !socketAllowed(s, models.RoleOperator) {
+			return
+		}
+
 		switch data.CID {
 		case "DeviceTimeReq":
 			simulatorController.SendMACCommand(lorawan.DeviceTimeReq, data)
@@ -252,18 +640,84 @@ func newServerSocket() *socketio.Server {
 	})
 
 	serverSocket.OnEvent("/", socket.EventChangePayload, func(s socketio.Conn, data socket.NewPayload) (string, bool) {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return "", false
+		}
+
 		return simulatorController.ChangePayload(data)
 	})
 
 	serverSocket.OnEvent("/", socket.EventSendUplink, func(s socketio.Conn, data socket.NewPayload) {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return
+		}
+
 		simulatorController.SendUplink(data)
 	})
 
+	serverSocket.OnEvent("/", socket.EventTriggerUplink, func(s socketio.Conn, data socket.NewPayload) bool {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return false
+		}
+
+		return simulatorController.TriggerUplink(data)
+	})
+
+	serverSocket.OnEvent("/", socket.EventDeviceAction, func(s socketio.Conn, data fleet.Action) []fleet.Result {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return nil
+		}
+
+		_, results, err := simulatorController.DeviceAction(data)
+		if err != nil {
+			s.Emit(socket.EventResponseCommand, err.Error())
+		}
+
+		return results
+	})
+
+	serverSocket.OnEvent("/", socket.EventBulkDevicesCommand, func(s socketio.Conn, data fleet.Command) []fleet.Result {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return nil
+		}
+
+		results, err := simulatorController.DevicesCommand(data)
+		if err != nil {
+			s.Emit(socket.EventResponseCommand, err.Error())
+		}
+
+		return results
+	})
+
+	serverSocket.OnEvent("/", socket.EventBulkGatewaysCommand, func(s socketio.Conn, data fleet.Command) []fleet.Result {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return nil
+		}
+
+		results, err := simulatorController.GatewaysCommand(data)
+		if err != nil {
+			s.Emit(socket.EventResponseCommand, err.Error())
+		}
+
+		return results
+	})
+
 	serverSocket.OnEvent("/", socket.EventGetParameters, func(s socketio.Conn, code int) mrp.Informations {
 		return rp.GetInfo(code)
 	})
 
 	serverSocket.OnEvent("/", socket.EventChangeLocation, func(s socketio.Conn, info socket.NewLocation) bool {
+
+		if !socketAllowed(s, models.RoleOperator) {
+			return false
+		}
+
 		return simulatorController.ChangeLocation(info)
 	})
 
@@ -274,7 +728,13 @@ func (ws *WebServer) Run() {
 
 	log.Println("[WS]: Listen [", ws.Address+":"+strconv.Itoa(ws.Port), "]")
 
-	err := ws.Router.Run(ws.Address + ":" + strconv.Itoa(ws.Port))
+	var err error
+
+	if configuration.TLS.Enabled() {
+		err = ws.Router.RunTLS(ws.Address+":"+strconv.Itoa(ws.Port), configuration.TLS.CertFile, configuration.TLS.KeyFile)
+	} else {
+		err = ws.Router.Run(ws.Address + ":" + strconv.Itoa(ws.Port))
+	}
 	if err != nil {
 		log.Println("[WS] [ERROR]:", err.Error())
 	}